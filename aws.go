@@ -0,0 +1,272 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AWSQueryOptions configures the AWS Query protocol profile. Zero values select
+// the conventions of services such as IAM and SNS.
+type AWSQueryOptions struct {
+	// Flattened writes list members and map entries directly below the name of
+	// the list or map, as in "Item.1", instead of below "member" or "entry",
+	// as in "Item.member.1". The EC2 protocol always uses flattened lists.
+	Flattened bool
+
+	// KeyName and ValueName name the two parts of each map entry. They default
+	// to "key" and "value"; SQS, for example, uses "Name" and "Value".
+	KeyName   string
+	ValueName string
+}
+
+// WithAWSQueryCompat configures a [Codec] to encode and decode the AWS Query
+// protocol, as used by the EC2, IAM, SNS and SQS APIs.
+//
+// Nested struct fields are joined with dots. Lists are written with one-based
+// indices, as in "Item.member.1=a", and maps as numbered key and value pairs,
+// as in "Attribute.entry.1.key=k&Attribute.entry.1.value=v". Because the same
+// syntax addresses both lists and maps, keys are interpreted according to the
+// type being decoded into. List members are decoded in the order of their
+// indices, with gaps between them closed.
+func WithAWSQueryCompat(opts AWSQueryOptions) Option {
+	return func(c *Codec) error {
+		if opts.KeyName == "" {
+			opts.KeyName = "key"
+		}
+		if opts.ValueName == "" {
+			opts.ValueName = "value"
+		}
+		if opts.KeyName == opts.ValueName {
+			return fmt.Errorf("form: AWS query map key and value names must differ")
+		}
+		if err := c.setSyntax("WithAWSQueryCompat", true, true); err != nil {
+			return err
+		}
+		c.parser = awsParser{opts: opts}
+		c.renderer = awsRenderer{opts: opts}
+		return nil
+	}
+}
+
+// awsRenderer produces keys such as "Tags.member.1.Key".
+type awsRenderer struct {
+	opts AWSQueryOptions
+}
+
+func (r awsRenderer) render(path []pathSegment) string {
+	return r.renderEntry(path, r.opts.ValueName)
+}
+
+func (r awsRenderer) renderEntryKey(path []pathSegment) string {
+	return r.renderEntry(path, r.opts.KeyName)
+}
+
+// renderEntry renders path, naming the final map entry part, if any, with
+// part. Map entries earlier in the path always refer to their value.
+func (r awsRenderer) renderEntry(path []pathSegment, part string) string {
+	var b strings.Builder
+	b.WriteString(path[0].Key)
+	for i, seg := range path[1:] {
+		switch {
+		case seg.Index:
+			if !r.opts.Flattened {
+				b.WriteString(".member")
+			}
+			b.WriteString(".")
+			b.WriteString(strconv.Itoa(seg.Pos + 1))
+		case seg.Map:
+			if !r.opts.Flattened {
+				b.WriteString(".entry")
+			}
+			b.WriteString(".")
+			b.WriteString(strconv.Itoa(seg.Pos + 1))
+			b.WriteString(".")
+			if i == len(path)-2 {
+				b.WriteString(part)
+			} else {
+				b.WriteString(r.opts.ValueName)
+			}
+		default:
+			b.WriteString(".")
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
+
+// awsParser builds a tree from dotted keys and then interprets it against the
+// type being decoded into, turning numbered members into array positions and
+// numbered entries into map keys.
+type awsParser struct {
+	opts AWSQueryOptions
+}
+
+// parse interprets the query without a target type, so every segment is
+// treated as a key.
+func (p awsParser) parse(query string) ([]entry, error) {
+	return dotParser{}.parse(query)
+}
+
+func (p awsParser) parseType(c *Codec, query string, t reflect.Type) ([]entry, error) {
+	pairs, err := splitPairs(query)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+	}
+
+	tree := make(map[string]interface{})
+	for _, pair := range pairs {
+		if err := awsInsert(tree, pair.key, pair.value); err != nil {
+			return nil, err
+		}
+	}
+	return p.resolve(c, nil, tree, t, nil)
+}
+
+// awsInsert stores value in tree at the location named by the dotted key.
+func awsInsert(tree map[string]interface{}, key, value string) error {
+	parts := strings.Split(key, ".")
+	node := tree
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			if _, ok := node[part].(map[string]interface{}); ok {
+				return fmt.Errorf("form: %w", &SyntaxError{Msg: "conflicting values", Key: key})
+			}
+			node[part] = value
+			return nil
+		}
+
+		switch child := node[part].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			node[part] = next
+			node = next
+		case map[string]interface{}:
+			node = child
+		default:
+			return fmt.Errorf("form: %w", &SyntaxError{Msg: "conflicting values", Key: key})
+		}
+	}
+	return nil
+}
+
+// resolve converts node into entries, using t to decide whether numbered keys
+// address list members or map entries. A nil t treats every segment as a key.
+func (p awsParser) resolve(c *Codec, entries []entry, node interface{}, t reflect.Type, path []pathSegment) ([]entry, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		value, _ := node.(string)
+		return append(entries, entry{key: renderSegments(path), path: path, value: value}), nil
+	}
+
+	kind := reflect.Invalid
+	if t != nil {
+		kind = t.Kind()
+	}
+
+	switch {
+	case kind == reflect.Map && len(path) == 0:
+		// The keys of a top-level map are parameter names.
+		for _, k := range awsNumbered(m) {
+			var err error
+			if entries, err = p.resolve(c, entries, m[k], t.Elem(), []pathSegment{{Key: k, Map: true}}); err != nil {
+				return nil, err
+			}
+		}
+
+	case kind == reflect.Slice, kind == reflect.Array:
+		members, err := p.container(m, "member", path)
+		if err != nil {
+			return nil, err
+		}
+		// Members are decoded in the order of their numbers, which are
+		// compacted, so that a sparse or huge number cannot grow the list
+		// beyond the members present.
+		for i, n := range awsNumbered(members) {
+			pos, err := strconv.Atoi(n)
+			if err != nil || pos < 1 {
+				return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid list index " + strconv.Quote(n), Key: renderSegments(path)})
+			}
+			seg := pathSegment{Index: true, Pos: i}
+			if entries, err = p.resolve(c, entries, members[n], t.Elem(), appendSegment(path, seg)); err != nil {
+				return nil, err
+			}
+		}
+
+	case kind == reflect.Map:
+		items, err := p.container(m, "entry", path)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range awsNumbered(items) {
+			item, _ := items[n].(map[string]interface{})
+			key, ok := item[p.opts.KeyName].(string)
+			if !ok {
+				return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "missing " + p.opts.KeyName + " for map entry " + n, Key: renderSegments(path)})
+			}
+			seg := pathSegment{Key: key, Map: true}
+			if entries, err = p.resolve(c, entries, item[p.opts.ValueName], t.Elem(), appendSegment(path, seg)); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		var sv reflect.Value
+		if kind == reflect.Struct {
+			sv = reflect.New(t).Elem()
+		}
+		for _, k := range awsNumbered(m) {
+			var ft reflect.Type
+			if sv.IsValid() {
+				if fv, _ := c.findStructField(sv, k); fv.IsValid() {
+					ft = fv.Type()
+				}
+			}
+			var err error
+			if entries, err = p.resolve(c, entries, m[k], ft, appendSegment(path, pathSegment{Key: k})); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// container returns the members or entries of a list or map, which are nested
+// below name unless the profile is flattened.
+func (p awsParser) container(m map[string]interface{}, name string, path []pathSegment) (map[string]interface{}, error) {
+	if p.opts.Flattened {
+		return m, nil
+	}
+	if len(m) != 1 {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "expected " + name, Key: renderSegments(path)})
+	}
+	inner, ok := m[name].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "expected " + name, Key: renderSegments(path)})
+	}
+	return inner, nil
+}
+
+// awsNumbered returns the keys of m sorted numerically where possible, so
+// members and entries are visited in their numbered order.
+func awsNumbered(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,185 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type AWSTag struct {
+	Key   string `form:"Key"`
+	Value string `form:"Value"`
+}
+
+type AWSRequest struct {
+	Action     string            `form:"Action"`
+	Version    string            `form:"Version"`
+	Tags       []AWSTag          `form:"Tags,omitempty"`
+	Names      []string          `form:"Names,omitempty"`
+	Attributes map[string]string `form:"Attributes,omitempty"`
+}
+
+func TestCodec_AWSQueryCompat(t *testing.T) {
+	t.Parallel()
+
+	request := AWSRequest{
+		Action:     "CreateTopic",
+		Version:    "2010-03-31",
+		Tags:       []AWSTag{{Key: "env", Value: "prod"}, {Key: "team", Value: "core"}},
+		Names:      []string{"a", "b"},
+		Attributes: map[string]string{"DisplayName": "alerts", "Policy": "{}"},
+	}
+
+	tests := map[string]struct {
+		opts formenc.AWSQueryOptions
+		want string
+	}{
+		"members and entries": {
+			want: "Action=CreateTopic" +
+				"&Attributes.entry.1.key=DisplayName&Attributes.entry.1.value=alerts" +
+				"&Attributes.entry.2.key=Policy&Attributes.entry.2.value=%7B%7D" +
+				"&Names.member.1=a&Names.member.2=b" +
+				"&Tags.member.1.Key=env&Tags.member.1.Value=prod" +
+				"&Tags.member.2.Key=team&Tags.member.2.Value=core" +
+				"&Version=2010-03-31",
+		},
+		"flattened": {
+			opts: formenc.AWSQueryOptions{Flattened: true, KeyName: "Name", ValueName: "Value"},
+			want: "Action=CreateTopic" +
+				"&Attributes.1.Name=DisplayName&Attributes.1.Value=alerts" +
+				"&Attributes.2.Name=Policy&Attributes.2.Value=%7B%7D" +
+				"&Names.1=a&Names.2=b" +
+				"&Tags.1.Key=env&Tags.1.Value=prod" +
+				"&Tags.2.Key=team&Tags.2.Value=core" +
+				"&Version=2010-03-31",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithAWSQueryCompat(tt.opts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := codec.Marshal(request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+
+			var decoded AWSRequest
+			if err := codec.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(request, decoded); diff != "" {
+				t.Errorf("round trip (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodec_AWSQueryCompat_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts    formenc.AWSQueryOptions
+		input   string
+		want    AWSRequest
+		wantErr bool
+	}{
+		"members out of order": {
+			input: "Names.member.2=b&Names.member.1=a",
+			want:  AWSRequest{Names: []string{"a", "b"}},
+		},
+		"sparse members": {
+			input: "Names.member.1=a&Names.member.3=c",
+			want:  AWSRequest{Names: []string{"a", "c"}},
+		},
+		"huge member number": {
+			input: "Names.member.50000000=a&Names.member.2=b",
+			want:  AWSRequest{Names: []string{"b", "a"}},
+		},
+		"map entries with struct-like names": {
+			opts:  formenc.AWSQueryOptions{Flattened: true, KeyName: "Name", ValueName: "Value"},
+			input: "Attributes.1.Name=Policy&Attributes.1.Value=x&Tags.1.Key=k",
+			want: AWSRequest{
+				Attributes: map[string]string{"Policy": "x"},
+				Tags:       []AWSTag{{Key: "k"}},
+			},
+		},
+		"zero index": {
+			input:   "Names.member.0=a",
+			wantErr: true,
+		},
+		"missing member": {
+			input:   "Names.1=a",
+			wantErr: true,
+		},
+		"missing entry key": {
+			input:   "Attributes.entry.1.value=x",
+			wantErr: true,
+		},
+		"conflicting values": {
+			input:   "Names=a&Names.member.1=b",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithAWSQueryCompat(tt.opts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got AWSRequest
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodec_AWSQueryCompat_Map(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithAWSQueryCompat(formenc.AWSQueryOptions{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	input := "Action=SetAttributes&Attributes.entry.1.key=a&Attributes.entry.1.value=b"
+	var got map[string]interface{}
+	if err := codec.Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"Action": "SetAttributes",
+		"Attributes": map[string]interface{}{
+			"entry": map[string]interface{}{
+				"1": map[string]interface{}{"key": "a", "value": "b"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithAWSQueryCompat(formenc.AWSQueryOptions{KeyName: "value"})); err == nil {
+		t.Error("expected error for identical key and value names, got nil")
+	}
+}
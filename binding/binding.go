@@ -0,0 +1,135 @@
+// Package binding adapts formenc to the request binders of web frameworks, so
+// that a framework's default form binder can be replaced in one line.
+//
+// A [Binding] satisfies the binding.Binding and binding.BindingBody interfaces
+// of gin, and can be used with any router through [Binding.Bind]:
+//
+//	b, err := binding.New(formenc.WithIgnoreUnknownKeys())
+//	...
+//	if err := c.ShouldBindWith(&signup, b); err != nil { // gin
+//		...
+//	}
+//
+// An [EchoBinder] satisfies echo.Binder when instantiated with echo.Context:
+//
+//	e.Binder = binding.NewEchoBinder[echo.Context](b)
+//
+// Neither framework is imported, as their interfaces are satisfied through
+// their method sets alone.
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+// maxBodySize is the limit on the bytes read from an urlencoded body, matching
+// [net/http.Request.ParseForm].
+const maxBodySize = 10 << 20
+
+// Binding binds the form data of HTTP requests to Go values.
+type Binding struct {
+	codec *formenc.Codec
+	opts  []formenc.Option
+}
+
+// New returns a [Binding] decoding with a [formenc.Codec] configured by opts.
+func New(opts ...formenc.Option) (*Binding, error) {
+	codec, err := formenc.NewCodec(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Binding{codec: codec, opts: opts}, nil
+}
+
+// Name returns the name of the binding, "formenc".
+func (b *Binding) Name() string {
+	return "formenc"
+}
+
+// Bind decodes the form data of r into the value pointed to by v. The query
+// string is always decoded. The body is decoded too when its content type is
+// application/x-www-form-urlencoded, its pairs following those of the query
+// string, or multipart/form-data, as read by a [multipart.Reader] with the
+// query string set by [multipart.Reader.SetQuery]. An urlencoded body is read
+// up to 10 MB, as [net/http.Request.ParseForm] reads it, a larger one
+// returning a [formenc.LimitExceededError]. A request without form data
+// leaves v untouched. The context of r is passed to decode functions and
+// validation, as [formenc.Codec.UnmarshalContext] does.
+func (b *Binding) Bind(r *http.Request, v interface{}) error {
+	data := r.URL.RawQuery
+	if r.Body != nil && r.Body != http.NoBody {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					return fmt.Errorf("form: %w", &formenc.LimitExceededError{Limit: "byte size", Max: int(maxErr.Limit), Err: err})
+				}
+				return fmt.Errorf("form: failed to read body: %w", err)
+			}
+			data = join(data, string(body))
+		case "multipart/form-data":
+			mr, err := multipart.NewRequestReader(r, b.opts...)
+			if err != nil {
+				return err
+			}
+			mr.SetQuery(data)
+			return mr.DecodeContext(r.Context(), v)
+		}
+	}
+	if data == "" {
+		return nil
+	}
+	return b.codec.UnmarshalContext(r.Context(), []byte(data), v)
+}
+
+// BindBody decodes body, urlencoded form data, into the value pointed to by v.
+func (b *Binding) BindBody(body []byte, v interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+	return b.codec.Unmarshal(body, v)
+}
+
+// Context is the part of a framework's request context used by an
+// [EchoBinder].
+type Context interface {
+	Request() *http.Request
+}
+
+// EchoBinder binds requests through the context of the echo framework. An
+// EchoBinder[echo.Context] satisfies echo.Binder.
+type EchoBinder[C Context] struct {
+	b *Binding
+}
+
+// NewEchoBinder returns an [EchoBinder] binding requests with b.
+func NewEchoBinder[C Context](b *Binding) *EchoBinder[C] {
+	return &EchoBinder[C]{b: b}
+}
+
+// Bind decodes the form data of the request of c into the value pointed to by
+// v, as [Binding.Bind] does.
+func (e *EchoBinder[C]) Bind(v interface{}, c C) error {
+	return e.b.Bind(c.Request(), v)
+}
+
+// join joins two lists of urlencoded pairs.
+func join(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "&" + b
+}
@@ -0,0 +1,176 @@
+package binding_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/binding"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+type Signup struct {
+	Email string   `form:"email"`
+	Plan  string   `form:"plan"`
+	Tags  []string `form:"tags"`
+}
+
+// ginBinding is the shape of binding.BindingBody in gin.
+type ginBinding interface {
+	Name() string
+	Bind(*http.Request, interface{}) error
+	BindBody([]byte, interface{}) error
+}
+
+var _ ginBinding = (*binding.Binding)(nil)
+
+func TestBinding_Bind(t *testing.T) {
+	t.Parallel()
+
+	multipartRequest := func() *http.Request {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		form := struct {
+			Email string   `form:"email"`
+			Tags  []string `form:"tags"`
+		}{Email: "alice@example.com", Tags: []string{"beta"}}
+		if err := w.Encode(form); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/signup?plan=pro", &buf)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+		return r
+	}
+
+	tests := map[string]struct {
+		request func() *http.Request
+		want    Signup
+		wantErr bool
+	}{
+		"query string": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/signup?email=alice%40example.com&plan=pro", nil)
+			},
+			want: Signup{Email: "alice@example.com", Plan: "pro"},
+		},
+		"urlencoded body": {
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/signup?plan=pro", strings.NewReader("email=alice%40example.com&tags[]=beta"))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			want: Signup{Email: "alice@example.com", Plan: "pro", Tags: []string{"beta"}},
+		},
+		"multipart body": {
+			request: multipartRequest,
+			want:    Signup{Email: "alice@example.com", Plan: "pro", Tags: []string{"beta"}},
+		},
+		"multipart body with a cancelled context": {
+			request: func() *http.Request {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return multipartRequest().WithContext(ctx)
+			},
+			wantErr: true,
+		},
+		"oversized urlencoded body": {
+			request: func() *http.Request {
+				body := "email=" + strings.Repeat("a", 10<<20)
+				r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			wantErr: true,
+		},
+		"unknown key": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/signup?colour=red", nil)
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := binding.New()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Signup
+			err = b.Bind(tt.request(), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBinding_BindBody(t *testing.T) {
+	t.Parallel()
+
+	b, err := binding.New(formenc.WithIgnoreUnknownKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Signup
+	if err := b.BindBody([]byte("email=bob%40example.com&colour=red"), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Signup{Email: "bob@example.com"}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// echoContext stands in for echo.Context, of which an EchoBinder uses only
+// the Request method.
+type echoContext interface {
+	Request() *http.Request
+	Param(name string) string
+}
+
+type fakeContext struct {
+	r *http.Request
+}
+
+func (c fakeContext) Request() *http.Request { return c.r }
+func (c fakeContext) Param(string) string    { return "" }
+
+// echoBinder is the shape of echo.Binder.
+type echoBinder interface {
+	Bind(i interface{}, c echoContext) error
+}
+
+func TestEchoBinder(t *testing.T) {
+	t.Parallel()
+
+	b, err := binding.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var binder echoBinder = binding.NewEchoBinder[echoContext](b)
+
+	var got Signup
+	r := httptest.NewRequest(http.MethodGet, "/signup?email=carol%40example.com", nil)
+	if err := binder.Bind(&got, fakeContext{r: r}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Signup{Email: "carol@example.com"}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
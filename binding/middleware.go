@@ -0,0 +1,64 @@
+package binding
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+// Responder writes the response to a request whose form data could not be
+// bound, such as a problem+json document or a plain 400 Bad Request. err is
+// the error returned by [Binding.Bind], including validation failures.
+type Responder func(w http.ResponseWriter, r *http.Request, err error)
+
+// ctxKey is the context key under which [Middleware] stores values of type T.
+type ctxKey[T any] struct{}
+
+// Middleware returns HTTP middleware binding the form data of each request
+// into a new value of type T with b, as [Binding.Bind] does. On success the
+// value is stored on the request's context, from which handlers retrieve it
+// with [FromContext], and the next handler is called. On failure respond
+// writes the response instead; a nil respond uses [DefaultResponder].
+//
+// Register the middleware on each route with the type that route decodes:
+//
+//	r.With(binding.Middleware[Signup](b, nil)).Post("/signup", signup)
+func Middleware[T any](b *Binding, respond Responder) func(http.Handler) http.Handler {
+	if respond == nil {
+		respond = DefaultResponder
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v := new(T)
+			if err := b.Bind(r, v); err != nil {
+				respond(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey[T]{}, v)))
+		})
+	}
+}
+
+// FromContext returns the value of type T bound by [Middleware], reporting
+// false if there is none.
+func FromContext[T any](ctx context.Context) (*T, bool) {
+	v, ok := ctx.Value(ctxKey[T]{}).(*T)
+	return v, ok
+}
+
+// DefaultResponder writes err as plain text, with the status 413 Request
+// Entity Too Large if a limit was exceeded, and 400 Bad Request otherwise. A
+// limit is exceeded if err is a [formenc.LimitExceededError], or matches
+// [formenc.ErrTooLarge] or [multipart.ErrTooLarge], as a [multipart.SizeError]
+// does.
+func DefaultResponder(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadRequest
+	var limitErr *formenc.LimitExceededError
+	if errors.As(err, &limitErr) || errors.Is(err, formenc.ErrTooLarge) || errors.Is(err, multipart.ErrTooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, err.Error(), status)
+}
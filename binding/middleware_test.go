@@ -0,0 +1,135 @@
+package binding_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/binding"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	teapot := func(w http.ResponseWriter, _ *http.Request, err error) {
+		http.Error(w, "custom: "+err.Error(), http.StatusTeapot)
+	}
+
+	tests := map[string]struct {
+		opts       []formenc.Option
+		respond    binding.Responder
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		"bound": {
+			url:        "/signup?email=alice%40example.com&plan=pro",
+			wantStatus: http.StatusOK,
+			wantBody:   "alice@example.com pro",
+		},
+		"default responder": {
+			url:        "/signup?colour=red",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "form: cannot unmarshal \"red\" into key \"colour\": unknown field \"colour\" in struct binding_test.Signup\n",
+		},
+		"limit exceeded": {
+			opts:       []formenc.Option{formenc.WithLimits(formenc.Limits{MaxKeys: 1})},
+			url:        "/signup?email=a&plan=b",
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   "form: exceeded the key count limit of 1\n",
+		},
+		"validation failure": {
+			opts: []formenc.Option{formenc.WithValidation(func(v interface{}) error {
+				return errors.New("plan is required")
+			})},
+			respond:    teapot,
+			url:        "/signup?email=a",
+			wantStatus: http.StatusTeapot,
+			wantBody:   "custom: form: validation failed: plan is required\n",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := binding.New(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			handler := binding.Middleware[Signup](b, tt.respond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signup, ok := binding.FromContext[Signup](r.Context())
+				if !ok {
+					t.Fatal("expected a bound value on the context")
+				}
+				w.Write([]byte(signup.Email + " " + signup.Plan))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if diff := cmp.Diff(tt.wantBody, rec.Body.String()); diff != "" {
+				t.Errorf("body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromContext_Missing(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := binding.FromContext[Signup](r.Context()); ok {
+		t.Error("expected no bound value")
+	}
+}
+
+func TestDefaultResponder(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err        error
+		wantStatus int
+	}{
+		"bad request": {
+			err:        errors.New("form: invalid"),
+			wantStatus: http.StatusBadRequest,
+		},
+		"limit exceeded": {
+			err:        fmt.Errorf("form: %w", &formenc.LimitExceededError{Limit: "depth", Max: 1}),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		"decoder limit": {
+			err:        formenc.ErrTooLarge,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		"multipart memory": {
+			err:        multipart.ErrTooLarge,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		"multipart size": {
+			err:        &multipart.SizeError{Limit: "file size", Max: 4, Field: "avatar"},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			binding.DefaultResponder(rec, httptest.NewRequest(http.MethodPost, "/", nil), tt.err)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
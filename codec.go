@@ -0,0 +1,237 @@
+package formenc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/url"
+	"reflect"
+)
+
+// defaultCodec backs the package-level functions such as [Marshal] and
+// [Unmarshal].
+var defaultCodec = &Codec{
+	parser:   bracketParser{},
+	renderer: bracketRenderer{},
+	escape:   url.QueryEscape,
+	tagName:  defaultTagName,
+
+	escapedKeys: &keyCache{},
+}
+
+// Codec encodes and decodes form data according to a fixed set of options. A
+// Codec is safe for concurrent use once constructed.
+type Codec struct {
+	parser   parser
+	renderer renderer
+
+	// parserOption and rendererOption name the options that selected the
+	// parser and renderer, if any, so that options selecting different key
+	// syntaxes are rejected.
+	parserOption   string
+	rendererOption string
+
+	// escape escapes keys and values in encoded output. escapedKeys, if set,
+	// caches the escaped static keys of c, so must be replaced whenever the
+	// escaping or rendering of keys changes.
+	escape      func(string) string
+	escapedKeys *keyCache
+
+	// groups selects the fields encoded by their groups tag flag.
+	groups []string
+
+	// preserveOrder writes encoded pairs in the order they are produced rather
+	// than sorting them by key.
+	preserveOrder bool
+
+	// tagName is the struct tag key naming fields.
+	tagName string
+
+	// ignoreUnknownKeys skips keys that do not match a struct field.
+	ignoreUnknownKeys bool
+
+	// skipUnexported skips keys that match unexported struct fields.
+	skipUnexported bool
+
+	// aggregateErrors continues decoding after field-level errors, returning
+	// them together as a MultiError.
+	aggregateErrors bool
+
+	// validation runs Validate methods and validator, if set, after
+	// decoding.
+	validation bool
+	validator  func(context.Context, interface{}) error
+
+	// overflow determines how numbers too large for their target are decoded.
+	overflow OverflowPolicy
+
+	// merge determines how decoding treats data already held by the target.
+	merge MergePolicy
+
+	// emptyAsNil decodes empty values into pointer fields as nil pointers.
+	emptyAsNil bool
+
+	// notation determines how keys mixing array and map notation are
+	// decoded into interface values.
+	notation NotationPolicy
+
+	// strict rejects ambiguous input, such as two keys assigning the same
+	// field. While decoding, assigned maps each field to the key that
+	// assigned it, and key holds the key being assigned.
+	strict   bool
+	assigned map[fieldAddr]string
+	key      string
+
+	// required rejects input omitting fields tagged required. While
+	// decoding, present records the part of the value being assigned.
+	required bool
+	present  *presence
+
+	// roundTrip verifies that encoded and decoded values survive the reverse
+	// operation unchanged.
+	roundTrip bool
+
+	// trimNoise removes a byte order mark, quotes and a leading '?' from
+	// input before parsing it.
+	trimNoise bool
+
+	// lenient accepts invalid form data where its meaning is clear. While
+	// decoding, metadata, if set, collects the issues found.
+	lenient  bool
+	metadata *Metadata
+
+	// positionalKeys decodes numeric keys below a slice, as in "items[0]",
+	// as the position of an element rather than rejecting them.
+	positionalKeys bool
+
+	// separators determines how stray separators are decoded.
+	separators SeparatorPolicy
+
+	// literalPlusKeys and literalPlusValues decode '+' as a plus sign rather
+	// than a space, and encode spaces as "%20".
+	literalPlusKeys   bool
+	literalPlusValues bool
+
+	// keyNormalizer, if set, rewrites each key before it is parsed.
+	keyNormalizer func(string) string
+
+	// nfcKeys and nfcValues convert decoded keys and values to Unicode
+	// Normalization Form C.
+	nfcKeys   bool
+	nfcValues bool
+
+	// hooks are called as values are encoded and decoded. While decoding,
+	// decodeStats, if set, collects statistics for the OnDecodeDone hook.
+	hooks       Hooks
+	decodeStats *DecodeStats
+
+	// plan, if set, records how a key is decoded for Plan.
+	plan *planState
+
+	// ctx, if set, is the context of the current call, passed to decode
+	// functions, validation and hooks.
+	ctx context.Context
+
+	// limits bounds the resources used to decode form data.
+	limits Limits
+
+	// sizes, if set, holds the number of elements expected for each slice
+	// and map decoded, and path the path of the entry being decoded.
+	sizes *sizeHints
+	path  []pathSegment
+
+	// useNumber decodes numeric values into interface values as json.Number
+	// rather than string.
+	useNumber bool
+
+	// decoders and encoders hold functions registered for specific types.
+	// Options copy these maps before modifying them, so they can be shared
+	// between codecs.
+	decoders map[reflect.Type]func(context.Context, string) (interface{}, error)
+	encoders map[reflect.Type]func(interface{}) (string, error)
+
+	// style and explode select the OpenAPI serialisation of struct fields
+	// without a style of their own.
+	style   ParameterStyle
+	explode bool
+
+	// discriminator names the key selecting the type decoded by
+	// UnmarshalDiscriminated from variants.
+	discriminator string
+	variants      map[string]reflect.Type
+}
+
+// Option configures a [Codec], returning an error if the option cannot be
+// applied.
+type Option func(*Codec) error
+
+// NewCodec returns a [Codec] configured with opts. Options are applied in
+// order, so later options override earlier ones. An error is returned if an
+// option is invalid, or if options cannot be honoured together, such as two
+// compatibility modes decoding keys differently.
+func NewCodec(opts ...Option) (*Codec, error) {
+	c := *defaultCodec
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkOptions(); err != nil {
+		return nil, err
+	}
+	c.escapedKeys = &keyCache{}
+	return &c, nil
+}
+
+// With returns a new [Codec] configured as c with opts applied after its own
+// options, such as a strict variant of a codec for some routes. c is not
+// modified. The struct tags parsed by c, and by [Codec.Prepare], are shared
+// with the new codec rather than parsed again.
+func (c *Codec) With(opts ...Option) (*Codec, error) {
+	d := *c
+	for _, opt := range opts {
+		if err := opt(&d); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.checkOptions(); err != nil {
+		return nil, err
+	}
+	d.escapedKeys = &keyCache{}
+	return &d, nil
+}
+
+// Marshal returns the form encoding of v.
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	return c.marshal(v)
+}
+
+// AppendMarshal appends the form encoding of v to dst and returns the extended
+// buffer. On error dst is returned unchanged.
+func (c *Codec) AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	return c.appendMarshal(dst, v)
+}
+
+// Unmarshal parses the form data and stores the result in the value pointed to
+// by v.
+func (c *Codec) Unmarshal(data []byte, v interface{}) error {
+	return c.unmarshal(data, v)
+}
+
+// NewEncoder returns an [Encoder] that writes to w using the options of c.
+func (c *Codec) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, codec: c, separator: "&"}
+}
+
+// NewDecoder returns a [Decoder] that reads from r using the options of c.
+func (c *Codec) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), codec: c}
+}
+
+// NewDecoderLimit returns a [Decoder] that reads from r using the options of
+// c, returning [ErrTooLarge] if r holds more than n bytes. Unlike the
+// MaxBytes limit of [WithLimits], which bounds each record, n bounds the whole
+// of the input.
+func (c *Codec) NewDecoderLimit(r io.Reader, n int64) *Decoder {
+	return c.NewDecoder(&limitReader{r: r, n: n})
+}
@@ -0,0 +1,69 @@
+package formenc
+
+import (
+	"context"
+)
+
+// ContextValidator is the interface implemented by types that validate
+// themselves using a context, for example to look values up with a deadline.
+// When a value implements both ContextValidator and [Validator] only
+// ValidateContext is called. See [WithValidation].
+type ContextValidator interface {
+	ValidateContext(ctx context.Context) error
+}
+
+// UnmarshalContext parses the form data and stores the result in the value
+// pointed to by v, as [Unmarshal] does. See [Codec.UnmarshalContext].
+func UnmarshalContext(ctx context.Context, data []byte, v interface{}) error {
+	return defaultCodec.UnmarshalContext(ctx, data, v)
+}
+
+// MarshalContext returns the form encoding of v, as [Marshal] does. See
+// [Codec.MarshalContext].
+func MarshalContext(ctx context.Context, v interface{}) ([]byte, error) {
+	return defaultCodec.MarshalContext(ctx, v)
+}
+
+// UnmarshalContext parses the form data and stores the result in the value
+// pointed to by v, as [Codec.Unmarshal] does. ctx is passed to the decode
+// functions registered with [WithDecodeFuncContext], to validation and to
+// hooks. Decoding stops with ctx's error if ctx is done before every key has
+// been decoded.
+func (c *Codec) UnmarshalContext(ctx context.Context, data []byte, v interface{}) error {
+	return c.withContext(ctx).unmarshal(data, v)
+}
+
+// MarshalContext returns the form encoding of v, as [Codec.Marshal] does,
+// passing ctx to hooks.
+func (c *Codec) MarshalContext(ctx context.Context, v interface{}) ([]byte, error) {
+	return c.withContext(ctx).marshal(v)
+}
+
+// DecodeContext reads the next record of form-urlencoded data and decodes it
+// into v, as [Decoder.Decode] does, using ctx as [Codec.UnmarshalContext]
+// does.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	return d.decode(d.codec.withContext(ctx), v)
+}
+
+// EncodeContext encodes v and writes it to the underlying [io.Writer], as
+// [Encoder.Encode] does, passing ctx to hooks.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}) error {
+	return e.encode(e.codec.withContext(ctx), v)
+}
+
+// withContext returns a copy of c that uses ctx for the duration of a call.
+func (c *Codec) withContext(ctx context.Context) *Codec {
+	d := *c
+	d.ctx = ctx
+	return &d
+}
+
+// context returns the context of the current call, or [context.Background]
+// outside calls made with a context.
+func (c *Codec) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,135 @@
+package formenc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type ctxKey struct{}
+
+type Tenant string
+
+type Booking struct {
+	Tenant Tenant `form:"tenant"`
+	Room   string `form:"room"`
+}
+
+func (b *Booking) ValidateContext(ctx context.Context) error {
+	if allowed, _ := ctx.Value(ctxKey{}).(string); allowed != string(b.Tenant) {
+		return fmt.Errorf("tenant %q is not allowed", b.Tenant)
+	}
+	return nil
+}
+
+func TestUnmarshalContext(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(
+		formenc.WithValidation(nil),
+		formenc.WithDecodeFuncContext(Tenant(""), func(ctx context.Context, s string) (interface{}, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return Tenant(strings.ToUpper(s)), nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "ACME")
+
+	var got Booking
+	if err := codec.UnmarshalContext(ctx, []byte("tenant=acme&room=1"), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Tenant != "ACME" || got.Room != "1" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	var validationErr *formenc.ValidationError
+	err = codec.UnmarshalContext(ctx, []byte("tenant=other"), &Booking{})
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError, got: %v", err)
+	}
+
+	// Without a context the validator sees no tenant.
+	if err := codec.Unmarshal([]byte("tenant=acme"), &Booking{}); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError, got: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := codec.UnmarshalContext(cancelled, []byte("tenant=acme"), &Booking{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestWithValidationContext(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithValidationContext(func(ctx context.Context, v interface{}) error {
+		if ctx.Value(ctxKey{}) == nil {
+			return errors.New("missing context value")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "ACME")
+	if err := codec.UnmarshalContext(ctx, []byte("name=alice"), &Person{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := codec.Unmarshal([]byte("name=alice"), &Person{}); err == nil {
+		t.Error("expected error without a context, got nil")
+	}
+}
+
+func TestContext_Streams(t *testing.T) {
+	t.Parallel()
+
+	var seen []interface{}
+	codec, err := formenc.NewCodec(formenc.WithHooks(formenc.Hooks{
+		OnDecodeDone: func(ctx context.Context, _ formenc.DecodeStats) {
+			seen = append(seen, ctx.Value(ctxKey{}))
+		},
+		OnEncodeDone: func(ctx context.Context, _ formenc.EncodeStats) {
+			seen = append(seen, ctx.Value(ctxKey{}))
+		},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	var b bytes.Buffer
+	if err := codec.NewEncoder(&b).EncodeContext(ctx, Person{Name: "alice"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var p Person
+	if err := codec.NewDecoder(&b).DecodeContext(ctx, &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "alice" {
+		t.Errorf("expected name alice, got %q", p.Name)
+	}
+	if _, err := codec.Marshal(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []interface{}{"request", "request", nil}
+	if diff := cmp.Diff(want, seen); diff != "" {
+		t.Errorf("context values seen by hooks (-want +got):\n%s", diff)
+	}
+}
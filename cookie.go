@@ -0,0 +1,74 @@
+package formenc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxCookieSize is the largest cookie, counting its name and value, that
+// browsers are required to store by RFC 6265.
+const maxCookieSize = 4096
+
+// NewCookie returns a cookie named name holding the form encoding of v. See
+// [Codec.NewCookie].
+func NewCookie(name string, v interface{}) (*http.Cookie, error) {
+	return defaultCodec.NewCookie(name, v)
+}
+
+// DecodeCookie parses the form data held by cookie into the value pointed to
+// by v. See [Codec.DecodeCookie].
+func DecodeCookie(cookie *http.Cookie, v interface{}) error {
+	return defaultCodec.DecodeCookie(cookie, v)
+}
+
+// NewCookie returns a cookie named name holding the form encoding of v by c,
+// with any byte not permitted in a cookie value percent-encoded. Attributes
+// such as Path and MaxAge are left for the caller to set. A
+// [LimitExceededError] is returned if the name and value together exceed the
+// 4096 bytes browsers are required to store.
+func (c *Codec) NewCookie(name string, v interface{}) (*http.Cookie, error) {
+	data, err := c.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	value := cookieEscape(string(data))
+	if size := len(name) + len("=") + len(value); size > maxCookieSize {
+		return nil, fmt.Errorf("form: %w", &LimitExceededError{Limit: "cookie size", Max: maxCookieSize, Key: name})
+	}
+	return &http.Cookie{Name: name, Value: value}, nil
+}
+
+// DecodeCookie parses the form data held by cookie, as written by
+// [Codec.NewCookie], into the value pointed to by v.
+func (c *Codec) DecodeCookie(cookie *http.Cookie, v interface{}) error {
+	return c.unmarshal([]byte(cookie.Value), v)
+}
+
+// cookieEscape percent-encodes the bytes of s that may not appear in a cookie
+// value: controls, spaces, double quotes, commas, semicolons, backslashes and
+// bytes outside ASCII.
+func cookieEscape(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool { return !isCookieOctet(r) }) < 0 {
+		return s
+	}
+
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if isCookieOctet(rune(s[i])) {
+			b.WriteByte(s[i])
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[s[i]>>4])
+		b.WriteByte(hex[s[i]&0x0f])
+	}
+	return b.String()
+}
+
+// isCookieOctet reports whether r is a cookie-octet as defined by RFC 6265.
+func isCookieOctet(r rune) bool {
+	return r > ' ' && r < 0x7f && r != '"' && r != ',' && r != ';' && r != '\\'
+}
@@ -0,0 +1,66 @@
+package formenc_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Preferences struct {
+	Theme    string   `form:"theme"`
+	Language string   `form:"lang"`
+	Pinned   []string `form:"pinned"`
+}
+
+func TestNewCookie(t *testing.T) {
+	t.Parallel()
+
+	prefs := Preferences{Theme: "dark; bold", Language: "en-GB", Pinned: []string{"inbox", "drafts, sent"}}
+	cookie, err := formenc.NewCookie("prefs", prefs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cookie.Name != "prefs" {
+		t.Errorf("expected name prefs, got %q", cookie.Name)
+	}
+	if strings.ContainsAny(cookie.Value, " \";,\\") {
+		t.Errorf("cookie value is not cookie-safe: %q", cookie.Value)
+	}
+
+	// The cookie survives being sent by a client and read by a server.
+	req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.AddCookie(cookie)
+	received, err := req.Cookie("prefs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Preferences
+	if err := formenc.DecodeCookie(received, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(prefs, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCookie_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	_, err := formenc.NewCookie("prefs", Preferences{Theme: strings.Repeat("x", 4096)})
+	var limitErr *formenc.LimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected LimitExceededError, got: %v", err)
+	}
+	if limitErr.Limit != "cookie size" || limitErr.Max != 4096 || limitErr.Key != "prefs" {
+		t.Errorf("unexpected error: %+v", limitErr)
+	}
+}
@@ -1,30 +1,17 @@
 package formenc
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
-	"strings"
 )
 
-// InvalidUnmarshalError describes an invalid argument passed to [Unmarshal].
-// (The argument to [Unmarshal] must be a non-nil pointer.)
-type InvalidUnmarshalError struct {
-	Type reflect.Type
-}
-
-func (e *InvalidUnmarshalError) Error() string {
-	if e.Type == nil {
-		return "form: Unmarshal(nil)"
-	}
-
-	if e.Type.Kind() != reflect.Pointer {
-		return "form: Unmarshal(non-pointer " + e.Type.String() + ")"
-	}
-	return "form: Unmarshal(nil " + e.Type.String() + ")"
-}
-
 // Unmarshaler is the interface implemented by types that can unmarshal a form
 // description of themselves. The input can be assumed to be a valid encoding of
 // a form value. [Unmarshaler.UnmarshalForm] must copy the form data if it
@@ -33,6 +20,23 @@ type Unmarshaler interface {
 	UnmarshalForm(string) error
 }
 
+// DataUnmarshaler is the interface implemented by types that decode complete
+// form data themselves, the counterpart of [Appender]. When the value passed
+// to [Unmarshal] implements DataUnmarshaler, UnmarshalFormData is called with
+// the form data, trimmed of surrounding white space, in place of decoding it
+// by reflection. Only the size limit and validation of the codec apply.
+// UnmarshalFormData must copy the data if it wishes to retain it after
+// returning.
+type DataUnmarshaler interface {
+	UnmarshalFormData(data []byte) error
+}
+
+// Validator is the interface implemented by types that can validate
+// themselves once decoded. See [WithValidation].
+type Validator interface {
+	Validate() error
+}
+
 // DecodeString is a convenience function that parses the form data in the
 // string and stores the result in the value pointed to by v. If v is nil or not
 // a pointer, DecodeString returns an [InvalidValueError].
@@ -43,18 +47,37 @@ func DecodeString(data string, v interface{}) error {
 // Unmarshal parses the form data and stores the result in the value pointed to
 // by v. If v is nil or not a pointer, Unmarshal returns an [InvalidValueError].
 func Unmarshal(data []byte, v interface{}) error {
+	return defaultCodec.unmarshal(data, v)
+}
+
+func (c *Codec) unmarshal(data []byte, v interface{}) error {
+	if c.observesDecode() {
+		return c.observeUnmarshal(data, v)
+	}
 	if len(data) == 0 {
 		return fmt.Errorf("form: empty input")
 	}
+	if err := c.checkSize(data); err != nil {
+		return err
+	}
 
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
+	if u, ok := v.(DataUnmarshaler); ok {
+		if err := u.UnmarshalFormData(bytes.TrimSpace(data)); err != nil {
+			return fmt.Errorf("form: %w", err)
+		}
+		return c.validate(v)
+	}
 
-	rv = rv.Elem()
-	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map {
-		return fmt.Errorf("form: top-level value must be struct or map")
+	// Slices are decoded from keys whose first segment is an index, such as
+	// "[0][name]" or "0[name]". Pointers to the target, such as a *map
+	// passed by its address, are allocated if nil.
+	rv = deref(rv.Elem())
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map && rv.Kind() != reflect.Slice {
+		return fmt.Errorf("form: top-level value must be struct, map or slice")
 	}
 
 	// Ensure map keys are strings.
@@ -62,37 +85,390 @@ func Unmarshal(data []byte, v interface{}) error {
 		return fmt.Errorf("form: map keys must be strings")
 	}
 
-	// Make sure to trim spaces to avoid future parse errors. url.ParseQuery does
-	// not do this automatically and can produce keys containing only spaces.
-	values, err := url.ParseQuery(strings.TrimSpace(string(data)))
+	// Make sure to trim spaces to avoid future parse errors. The query parser
+	// does not do this automatically and can produce keys containing only
+	// spaces.
+	entries, err := c.parseBytes(bytes.TrimSpace(data), rv.Type())
 	if err != nil {
-		return fmt.Errorf("form: invalid form data: %w", err)
+		return err
+	}
+	entries = c.dropDiscriminator(entries, rv)
+	if c.decodeStats != nil {
+		c.decodeStats.Keys = len(entries)
+	}
+	if err := c.checkEntries(entries); err != nil {
+		return err
 	}
 
-	return unmarshalForm(values, rv)
-}
+	// Values that must keep their existing data are decoded afresh and merged
+	// once decoding succeeds.
+	target := rv
+	switch c.merge {
+	case MergeReplace:
+		rv.Set(reflect.Zero(rv.Type()))
+	case MergeKeepExisting:
+		target = reflect.New(rv.Type()).Elem()
+	}
 
-func unmarshalForm(values url.Values, v reflect.Value) error {
-	for rawKey, vals := range values {
-		path, err := parseKey(rawKey)
-		if err != nil {
+	if err := c.unmarshalEntries(entries, target); err != nil {
+		return err
+	}
+	if c.roundTrip {
+		if err := c.checkUnmarshal(entries, target); err != nil {
 			return err
 		}
-		for _, val := range vals {
-			if err := assign(v, path, val); err != nil {
+	}
+	if c.merge == MergeKeepExisting {
+		c.mergeValue(rv, target)
+	}
+	return c.validate(v)
+}
+
+// parse converts query into entries for a decode target of type t.
+func (c *Codec) parse(query string, t reflect.Type) ([]entry, error) {
+	if c.trimNoise {
+		query = trimNoise(query)
+	}
+	if c.separators != SeparatorsKeep {
+		var err error
+		if query, err = c.applySeparators(query); err != nil {
+			return nil, err
+		}
+	}
+	query = c.rewrite(query)
+
+	var entries []entry
+	var err error
+	if tp, ok := c.parser.(typedParser); ok {
+		entries, err = tp.parseType(c, query, t)
+	} else {
+		entries, err = c.parser.parse(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.nfcKeys || c.nfcValues {
+		c.normalize(entries)
+	}
+	return entries, nil
+}
+
+// largeInput is the size of input above which parseBytes parses data in place.
+// Below it, a single copy of the input is cheaper than copying each key and
+// value.
+const largeInput = 16 << 10
+
+// parseBytes converts data into entries for a decode target of type t, as
+// parse does. Large inputs using the default key syntax, with no options
+// rewriting the data, are parsed in place rather than copied into a string
+// first, so that only the keys and values decoded are allocated and decoded
+// strings do not keep the whole input alive.
+func (c *Codec) parseBytes(data []byte, t reflect.Type) ([]entry, error) {
+	bp, bracket := c.parser.(bracketParser)
+	if !bracket || len(data) < largeInput || c.trimNoise || c.lenient || c.separators != SeparatorsKeep || c.literalPlusKeys || c.literalPlusValues || c.keyNormalizer != nil {
+		return c.parse(string(data), t)
+	}
+
+	pairs, err := splitPairBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+	}
+	entries, err := bp.entries(pairs)
+	if err != nil {
+		return nil, err
+	}
+	if c.nfcKeys || c.nfcValues {
+		c.normalize(entries)
+	}
+	return entries, nil
+}
+
+// rewrite applies the options of c that rewrite form data before it is
+// parsed.
+func (c *Codec) rewrite(query string) string {
+	if c.lenient {
+		query = c.sanitize(query)
+	}
+	if c.literalPlusKeys || c.literalPlusValues {
+		query = c.escapePlus(query)
+	}
+	if c.keyNormalizer != nil {
+		query = c.normalizeKeys(query)
+	}
+	return query
+}
+
+// validate runs the validation configured by [WithValidation] against the
+// decoded value v.
+func (c *Codec) validate(v interface{}) error {
+	if !c.validation {
+		return nil
+	}
+	switch val := v.(type) {
+	case ContextValidator:
+		if err := val.ValidateContext(c.context()); err != nil {
+			return fmt.Errorf("form: %w", &ValidationError{Err: err})
+		}
+	case Validator:
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("form: %w", &ValidationError{Err: err})
+		}
+	}
+	if c.validator != nil {
+		if err := c.validator(c.context(), v); err != nil {
+			return fmt.Errorf("form: %w", &ValidationError{Err: err})
+		}
+	}
+	return nil
+}
+
+func (c *Codec) unmarshalEntries(entries []entry, v reflect.Value) error {
+	if v.Kind() == reflect.Slice {
+		entries = rootPositions(entries)
+	}
+
+	// The most common maps are assigned without reflection.
+	fast := c.mapFastPath(v, len(entries))
+
+	// Strict decoding tracks the fields assigned by each key, and dropped and
+	// deprecated keys are reported by the key being assigned, so these work on
+	// a copy of the codec private to this call. Large inputs that may be
+	// decoded by reflection also count the elements of each slice and map, so
+	// that they are allocated once at their final size.
+	presize := len(entries) >= presizeEntries && (fast == nil || v.Type() == interfaceMapType)
+	private := presize || c.strict || c.required || c.metadata != nil || c.hooks.OnKeyDropped != nil || c.hooks.OnDeprecatedKey != nil
+	if private {
+		s := *c
+		if c.strict {
+			s.assigned = make(map[fieldAddr]string)
+		}
+		if presize {
+			s.sizes = countSizes(entries)
+		}
+		c = &s
+	}
+	var root *presence
+	if c.required {
+		root = &presence{}
+	}
+
+	// Values appended to the slices held by other maps are collected and each
+	// slice stored once, rather than once for every value.
+	var batch *sliceBatch
+	if fast == nil && root == nil && v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Slice {
+		batch = &sliceBatch{m: v}
+		defer batch.flush()
+	}
+
+	var multi MultiError
+	for _, e := range entries {
+		if c.ctx != nil {
+			if err := c.ctx.Err(); err != nil {
+				return fmt.Errorf("form: %w", err)
+			}
+		}
+		if private {
+			c.key, c.path, c.present = e.key, e.path, root
+		}
+
+		var err error
+		done := false
+		if fast != nil {
+			done, err = fast(e)
+		}
+		switch {
+		case done:
+		case batch != nil && len(e.path) > 0:
+			err = c.appendBatched(batch, e.path[0].Key, e.value)
+		default:
+			err = c.assign(v, e.path, e.value)
+		}
+		if err != nil {
+			err = redactError(annotate(err, e.key, e.path, e.value))
+			if c.hooks.OnFieldError != nil {
+				c.hooks.OnFieldError(c.context(), e.key, err)
+			}
+			if !c.aggregateErrors {
 				return fmt.Errorf("form: %w", err)
 			}
+			multi.add(e.key, err)
+		}
+	}
+	if root != nil {
+		if err := c.missingFields(v, root, &multi); err != nil {
+			return err
+		}
+	}
+	if len(multi.Errors) > 0 {
+		return &multi
+	}
+	return nil
+}
+
+// rootPositions returns entries decoded into a top-level slice with the first
+// segment of each key, such as the "0" of "0[name]", made the position of an
+// element. Positions are compacted in numeric order, so that a sparse or huge
+// index cannot grow the slice beyond the elements present.
+func rootPositions(entries []entry) []entry {
+	seen := make(map[int]bool)
+	for _, e := range entries {
+		if len(e.path) == 0 || e.path[0].Index {
+			continue
+		}
+		if pos, err := strconv.Atoi(e.path[0].Key); err == nil && pos >= 0 {
+			seen[pos] = true
+		}
+	}
+	if len(seen) == 0 {
+		return entries
+	}
+
+	positions := make([]int, 0, len(seen))
+	for pos := range seen {
+		positions = append(positions, pos)
+	}
+	sort.Ints(positions)
+	rank := make(map[int]int, len(positions))
+	for i, pos := range positions {
+		rank[pos] = i
+	}
+
+	out := make([]entry, len(entries))
+	for i, e := range entries {
+		out[i] = e
+		if len(e.path) == 0 || e.path[0].Index {
+			continue
+		}
+		if pos, err := strconv.Atoi(e.path[0].Key); err == nil && pos >= 0 {
+			path := make([]pathSegment, len(e.path))
+			copy(path, e.path)
+			path[0] = pathSegment{Index: true, Pos: rank[pos]}
+			out[i].path = path
+		}
+	}
+	return out
+}
+
+// sliceBatch collects the slices held by the map m while values are appended
+// to them, so that each is stored in m once.
+type sliceBatch struct {
+	m      reflect.Value
+	slices map[string]reflect.Value
+	order  []string
+}
+
+// appendBatched decodes val and appends it to the slice held by b.m under
+// key, as assignMapValue does.
+func (c *Codec) appendBatched(b *sliceBatch, key, val string) error {
+	slice, ok := b.slices[key]
+	if !ok {
+		if !b.m.IsNil() {
+			slice = b.m.MapIndex(reflect.ValueOf(key))
+		}
+		if !slice.IsValid() {
+			slice = reflect.MakeSlice(b.m.Type().Elem(), 0, max(c.sizeHint(1), 1))
+		}
+	}
+
+	if err := c.checkSliceLength(slice.Len() + 1); err != nil {
+		return err
+	}
+	elem := reflect.New(slice.Type().Elem()).Elem()
+	if err := c.assignLeaf(deref(elem), val); err != nil {
+		return err
+	}
+	if !ok {
+		if b.slices == nil {
+			b.slices = make(map[string]reflect.Value)
 		}
+		b.order = append(b.order, key)
 	}
+	b.slices[key] = reflect.Append(slice, elem)
 	return nil
 }
 
-func assign(v reflect.Value, path []pathSegment, val string) error {
+// flush stores the collected slices in the map.
+func (b *sliceBatch) flush() {
+	if len(b.order) == 0 {
+		return
+	}
+	if b.m.IsNil() {
+		b.m.Set(reflect.MakeMapWithSize(b.m.Type(), len(b.order)))
+	}
+	for _, key := range b.order {
+		b.m.SetMapIndex(reflect.ValueOf(key), b.slices[key])
+	}
+}
+
+// annotate records the form key, its path and the value that caused err when
+// it is a decoding error that does not yet identify them.
+func annotate(err error, key string, path []pathSegment, value string) error {
+	var typeErr *UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Key == "" {
+		typeErr.Key, typeErr.Path = key, elementPath(keyPath(path), typeErr.Path)
+	}
+	var unknownErr *UnknownFieldError
+	if errors.As(err, &unknownErr) && unknownErr.Key == "" {
+		unknownErr.Key, unknownErr.Path, unknownErr.Value = key, keyPath(path), value
+	}
+	var unexportedErr *UnexportedFieldError
+	if errors.As(err, &unexportedErr) && unexportedErr.Key == "" {
+		unexportedErr.Key, unexportedErr.Path, unexportedErr.Value = key, keyPath(path), value
+	}
+	var unsupportedErr *UnsupportedTypeError
+	if errors.As(err, &unsupportedErr) && unsupportedErr.Key == "" {
+		unsupportedErr.Key, unsupportedErr.Path = key, keyPath(path)
+	}
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Key == "" {
+		syntaxErr.Key = key
+	}
+	var limitErr *LimitExceededError
+	if errors.As(err, &limitErr) && limitErr.Key == "" {
+		limitErr.Key, limitErr.Path = key, keyPath(path)
+	}
+	return err
+}
+
+// elementError records in err that the value it describes was appended to a
+// slice as the element at pos. Until the error is annotated with its key, the
+// path of an [UnmarshalTypeError] holds only that element.
+func elementError(err error, pos int) error {
+	var typeErr *UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Key == "" && typeErr.Path == nil {
+		typeErr.Path = KeyPath{{Index: true, Pos: pos}}
+	}
+	return err
+}
+
+// elementPath returns p with the position of the element recorded in elem by
+// elementError, if any, filled in.
+func elementPath(p KeyPath, elem KeyPath) KeyPath {
+	if len(elem) != 1 || !elem[0].Index {
+		return p
+	}
+	if n := len(p); n > 0 && p[n-1].Index {
+		p[n-1].Pos = elem[0].Pos
+		return p
+	}
+	return p.AppendIndex(elem[0].Pos)
+}
+
+func (c *Codec) assign(v reflect.Value, path []pathSegment, val string) error {
+	if len(path) == 0 && val == "" && c.emptyAsNil && clearsOnEmpty(v) {
+		v.SetZero()
+		return nil
+	}
 	v = deref(v)
 
-	// If the path is empty, we are at a leaf node.
+	// If the path is empty, we are at a leaf node. Repeated keys without an
+	// index are appended to slices that cannot decode the value themselves.
 	if len(path) == 0 {
-		return assignLeaf(v, val)
+		if v.Kind() == reflect.Slice && !c.decodesLeaf(v) {
+			c.presize(v, len(c.path))
+			return c.assignSliceValue(v, pathSegment{Index: true, Pos: -1}, nil, val)
+		}
+		return c.assignLeaf(v, val)
 	}
 
 	// Get the next segment of the path.
@@ -101,51 +477,247 @@ func assign(v reflect.Value, path []pathSegment, val string) error {
 	// Dispatch based on the kind of the value.
 	switch v.Kind() {
 	case reflect.Struct:
-		return assignStructField(v, seg.Key, path[1:], val)
+		return c.assignStructField(v, seg.Key, path[1:], val)
 	case reflect.Map:
-		return assignMapValue(v, seg, path[1:], val)
+		c.presize(v, len(c.path)-len(path))
+		return c.assignMapValue(v, seg, path[1:], val)
 	case reflect.Slice:
-		return assignSliceValue(v, seg, path[1:], val)
+		c.presize(v, len(c.path)-len(path))
+		return c.assignSliceValue(v, seg, path[1:], val)
 	case reflect.Interface:
-		return assignInterfaceValue(v, path, val)
+		return c.assignInterfaceValue(v, path, val)
 	default:
-		return fmt.Errorf("cannot assign to %v", v.Kind())
+		return &UnmarshalTypeError{Value: val, Type: v.Type(), Err: errors.New("value cannot have nested keys")}
+	}
+}
+
+// presize allocates the nil slice or map v with room for the elements expected
+// at the first depth segments of the entry being decoded, if they are known.
+func (c *Codec) presize(v reflect.Value, depth int) {
+	if c.sizes == nil || !v.IsNil() {
+		return
+	}
+	n := c.sizeHint(depth)
+	if n == 0 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 0, n))
+	case reflect.Map:
+		v.Set(reflect.MakeMapWithSize(v.Type(), n))
+	}
+}
+
+// clearsOnEmpty reports whether v is a pointer cleared by an empty value when
+// empty values decode as nil: one that leads, through as many pointers as v
+// has, to a value other than a slice or map.
+func clearsOnEmpty(v reflect.Value) bool {
+	if v.Kind() != reflect.Pointer {
+		return false
 	}
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Slice && t.Kind() != reflect.Map
 }
 
-// dereference a pointer value, allocating a new value if needed.
+// dereference a pointer value, allocating new values as needed, through as
+// many pointers as v has.
 func deref(v reflect.Value) reflect.Value {
-	if v.Kind() == reflect.Pointer {
+	for v.Kind() == reflect.Pointer {
 		if v.IsNil() {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
-		return v.Elem()
+		v = v.Elem()
 	}
 	return v
 }
 
-// assign a leaf value (string) to v. If v implements [Unmarshaler], use that.
-func assignLeaf(v reflect.Value, val string) error {
+// assign a leaf value (string) to v. If a decode function is registered for
+// the type of v, or v implements [Unmarshaler], use that.
+func (c *Codec) assignLeaf(v reflect.Value, val string) error {
+	if fn, ok := c.decoders[v.Type()]; ok {
+		c.planLeaf(v.Type(), "DecodeFunc")
+		return setDecoded(c.context(), v, fn, val)
+	}
 	if u, ok := asUnmarshaler(v); ok {
-		return u.UnmarshalForm(val)
+		c.planLeaf(v.Type(), "UnmarshalForm")
+		if err := u.UnmarshalForm(val); err != nil {
+			return &UnmarshalTypeError{Value: val, Type: v.Type(), Err: err}
+		}
+		return nil
+	}
+	c.planLeaf(v.Type(), conversion(v))
+	return c.setScalar(v, val)
+}
+
+// decodesLeaf reports whether v decodes a whole value itself, either through a
+// registered decode function or by implementing [Unmarshaler].
+func (c *Codec) decodesLeaf(v reflect.Value) bool {
+	if _, ok := c.decoders[v.Type()]; ok {
+		return true
+	}
+	_, ok := asUnmarshaler(v)
+	return ok
+}
+
+// setDecoded sets v to the value produced by the decode function fn.
+func setDecoded(ctx context.Context, v reflect.Value, fn func(context.Context, string) (interface{}, error), val string) error {
+	out, err := fn(ctx, val)
+	if err != nil {
+		return &UnmarshalTypeError{Value: val, Type: v.Type(), Err: err}
+	}
+
+	rv := reflect.ValueOf(out)
+	switch {
+	case !rv.IsValid():
+		v.Set(reflect.Zero(v.Type()))
+	case rv.Type().AssignableTo(v.Type()):
+		v.Set(rv)
+	case rv.Type().ConvertibleTo(v.Type()):
+		v.Set(rv.Convert(v.Type()))
+	default:
+		return &UnmarshalTypeError{Value: val, Type: v.Type(), Err: fmt.Errorf("decode func returned %v", rv.Type())}
 	}
-	return setScalar(v, val)
+	return nil
 }
 
 // assign a struct field identified by key.
-func assignStructField(v reflect.Value, key string, path []pathSegment, val string) error {
-	field := findStructField(v, key)
+func (c *Codec) assignStructField(v reflect.Value, key string, path []pathSegment, val string) error {
+	field, tag, rest := c.findDottedField(v, key, path)
+	if field.IsValid() {
+		key, path = tag.Name, rest
+	} else {
+		field, tag = c.findStructField(v, key)
+	}
+	deprecated := false
+	if !field.IsValid() {
+		field, tag = c.findDeprecatedField(v, key)
+		deprecated = field.IsValid()
+	}
+	if !field.IsValid() {
+		// Properties of exploded objects appear as keys of their own.
+		if ok, err := c.assignExploded(v, key, path, val); ok {
+			return err
+		}
+	}
 	if !field.IsValid() || !field.CanSet() {
-		return fmt.Errorf("unknown field %q in struct %v", key, v.Type())
+		if c.plan != nil {
+			c.planUnmatched()
+			return nil
+		}
+		if field.IsValid() {
+			// The field exists but is unexported.
+			if c.ignoreUnknownKeys || c.skipUnexported {
+				c.dropKey(c.key, DropIgnoredField)
+				return nil
+			}
+			return &UnexportedFieldError{Field: key, Type: v.Type()}
+		}
+		if c.ignoreUnknownKeys {
+			reason := DropUnknownField
+			if c.ignoresField(v, key) {
+				reason = DropIgnoredField
+			}
+			c.dropKey(c.key, reason)
+			return nil
+		}
+		return &UnknownFieldError{Field: key, Type: v.Type()}
+	}
+	if tag.Err != nil {
+		err := *tag.Err
+		return &err
 	}
-	return assign(field, path, val)
+	if tag.ReadOnly {
+		if c.plan != nil {
+			c.planUnmatched()
+			return nil
+		}
+		c.dropKey(c.key, DropReadOnlyField)
+		return nil
+	}
+	if deprecated {
+		key = tag.Name
+		if c.plan == nil {
+			c.deprecateKey(key)
+		}
+	}
+	c.planField(v, key)
+	if c.present != nil {
+		defer c.enter("." + tag.Name)()
+	}
+	if c.assigned != nil && len(path) == 0 {
+		if err := c.assignOnce(v, field, key); err != nil {
+			return err
+		}
+	}
+	var err error
+	if tag.KVPairs != "" && len(path) == 0 {
+		err = c.assignKV(field, tag, val)
+	} else if style, explode := c.fieldStyle(tag); style != "" && len(path) == 0 {
+		err = c.assignStyled(field, style, explode, val)
+	} else {
+		err = c.assign(field, path, val)
+	}
+	if tag.Secret {
+		c.redactIssues(c.key)
+		if c.plan != nil {
+			c.plan.secret = true
+		}
+		if err != nil {
+			err = &secretError{err: err, value: val}
+		}
+	}
+	return withMessage(err, tag.ErrMsg)
+}
+
+// withMessage sets the message of an [UnmarshalTypeError] in err to msg, the
+// errmsg flag of a field's tag, unless a field nested within it set one
+// first.
+func withMessage(err error, msg string) error {
+	var typeErr *UnmarshalTypeError
+	if msg != "" && errors.As(err, &typeErr) && typeErr.Message == "" {
+		typeErr.Message = msg
+	}
+	return err
+}
+
+// fieldAddr identifies a struct field assigned while decoding in strict mode.
+type fieldAddr struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// assignOnce records that the current key assigns field, the struct field of
+// v named key, returning a [DuplicateFieldError] if another key already has.
+// Fields accepting repeated values, such as slices, may be assigned by any
+// number of keys.
+func (c *Codec) assignOnce(v, field reflect.Value, key string) error {
+	if !field.CanAddr() {
+		return nil
+	}
+	if t := field.Type(); t.Kind() == reflect.Slice || (t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Slice) {
+		if !c.decodesLeaf(field) {
+			return nil
+		}
+	}
+
+	addr := fieldAddr{ptr: field.UnsafeAddr(), typ: field.Type()}
+	if prev, ok := c.assigned[addr]; ok {
+		return &DuplicateFieldError{Key: c.key, Path: keyPath(c.path), Previous: prev, Field: key, Type: v.Type()}
+	}
+	c.assigned[addr] = c.key
+	return nil
 }
 
 // assign a map value identified by a path segment.
-func assignMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) error {
+func (c *Codec) assignMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) error {
 	if v.IsNil() {
 		v.Set(reflect.MakeMap(v.Type()))
 	}
+	c.planElem(seg)
 
 	key := reflect.ValueOf(seg.Key)
 	elem := v.MapIndex(key)
@@ -153,7 +725,8 @@ func assignMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val st
 
 	switch elemType.Kind() {
 	case reflect.Interface:
-		newVal, err := inferInterfaceValue(elem, path, val)
+		c.planLeaf(elemType, "interface")
+		newVal, err := c.inferInterfaceValue(elem, path, val)
 		if err != nil {
 			return err
 		}
@@ -169,9 +742,13 @@ func assignMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val st
 			slice = reflect.MakeSlice(elemType, 0, 1)
 		}
 
+		if err := c.checkSliceLength(slice.Len() + 1); err != nil {
+			return err
+		}
+
 		// New element
 		newElem := reflect.New(elemType.Elem()).Elem()
-		if err := assignLeaf(newElem, val); err != nil {
+		if err := c.assignLeaf(deref(newElem), val); err != nil {
 			return err
 		}
 
@@ -179,30 +756,75 @@ func assignMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val st
 		v.SetMapIndex(key, slice)
 		return nil
 
-	// Single value
+	// Single value, or a struct whose fields are assigned by the rest of the
+	// path. Map elements cannot be modified in place, so the existing value is
+	// copied, assigned and stored again.
 	default:
-		if !elem.IsValid() {
-			elem = reflect.New(elemType).Elem()
+		newElem := reflect.New(elemType).Elem()
+		if elem.IsValid() {
+			newElem.Set(elem)
 		}
-		if err := assign(deref(elem), path, val); err != nil {
+		if c.present != nil {
+			defer c.enter("[" + seg.Key + "]")()
+		}
+		if err := c.assign(deref(newElem), path, val); err != nil {
 			return err
 		}
-		v.SetMapIndex(key, elem)
+		v.SetMapIndex(key, newElem)
 		return nil
 	}
 }
 
 // assign a slice value identified by a path segment.
-func assignSliceValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) error {
+func (c *Codec) assignSliceValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) error {
+	// Numeric keys address elements by position when the key syntax has no
+	// notation of its own for them.
 	if !seg.Index {
-		return fmt.Errorf("form: expected slice index")
+		pos, err := strconv.Atoi(seg.Key)
+		if err != nil || pos < 0 || !c.positionalKeys {
+			return &UnmarshalTypeError{Value: val, Type: v.Type(), Err: errors.New("expected slice index")}
+		}
+		seg = pathSegment{Index: true, Pos: pos}
 	}
+	c.planElem(seg)
+
+	// Positional segments address an existing element, growing the slice when
+	// the position lies beyond its end.
+	if seg.Pos >= 0 {
+		if err := c.checkSliceLength(seg.Pos + 1); err != nil {
+			return err
+		}
+		if n := v.Len(); seg.Pos >= v.Cap() {
+			grow := reflect.MakeSlice(v.Type(), seg.Pos+1-n, seg.Pos+1-n)
+			v.Set(reflect.AppendSlice(v, grow))
+		} else if seg.Pos >= n {
+			// The slice has room for the element, as when it was allocated
+			// at its expected size, so is extended in place.
+			v.SetLen(seg.Pos + 1)
+			for i := n; i <= seg.Pos; i++ {
+				v.Index(i).SetZero()
+			}
+		}
+		if c.present != nil {
+			defer c.enter("[" + strconv.Itoa(seg.Pos) + "]")()
+		}
+		return c.assign(v.Index(seg.Pos), path, val)
+	}
+
+	if err := c.checkSliceLength(v.Len() + 1); err != nil {
+		return err
+	}
+
 	elemType := v.Type().Elem()
+	if c.present != nil {
+		defer c.enter("[" + strconv.Itoa(v.Len()) + "]")()
+	}
 
 	var newElem reflect.Value
 	if elemType.Kind() == reflect.Interface {
+		c.planLeaf(elemType, "interface")
 		var err error
-		newElem, err = inferInterfaceValue(reflect.Value{}, path, val)
+		newElem, err = c.inferInterfaceValue(reflect.Value{}, path, val)
 		if err != nil {
 			return err
 		}
@@ -210,12 +832,12 @@ func assignSliceValue(v reflect.Value, seg pathSegment, path []pathSegment, val
 		newElem = reflect.New(elemType).Elem()
 		if len(path) == 0 {
 			// Leaf element
-			if err := assignLeaf(newElem, val); err != nil {
-				return err
+			if err := c.assignLeaf(deref(newElem), val); err != nil {
+				return elementError(err, v.Len())
 			}
 		} else {
 			// Nested struct/map
-			if err := assign(newElem, path, val); err != nil {
+			if err := c.assign(newElem, path, val); err != nil {
 				return err
 			}
 		}
@@ -224,23 +846,37 @@ func assignSliceValue(v reflect.Value, seg pathSegment, path []pathSegment, val
 	return nil
 }
 
-func assignInterfaceValue(v reflect.Value, path []pathSegment, val string) error {
-	if !v.IsValid() || v.IsNil() {
-		newVal, err := inferInterfaceValue(v, path, val)
-		if err != nil {
-			return err
-		}
-		v.Set(newVal)
-		return nil
+func (c *Codec) assignInterfaceValue(v reflect.Value, path []pathSegment, val string) error {
+	// Values of a concrete type already held by the interface are decoded in
+	// place, provided they can be modified through a pointer.
+	if !v.IsNil() && v.Elem().Kind() == reflect.Pointer {
+		return c.assign(v.Elem(), path, val)
 	}
-	return assign(v.Elem(), path, val)
+
+	c.planLeaf(v.Type(), "interface")
+	newVal, err := c.inferInterfaceValue(v, path, val)
+	if err != nil {
+		return err
+	}
+	v.Set(newVal)
+	return nil
 }
 
 // infer the value for an interface type based on the path segments.
-func inferInterfaceValue(v reflect.Value, path []pathSegment, val string) (reflect.Value, error) {
+func (c *Codec) inferInterfaceValue(v reflect.Value, path []pathSegment, val string) (reflect.Value, error) {
+	// Work with the dynamic value held by an interface, treating a nil
+	// interface as though no value exists yet.
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
 	// Leaf node. When no type information is available, default to string. This
 	// is consistent with form value semantics, and guarantees round-trip safety.
+	// Numeric values are decoded as [json.Number] when requested instead.
 	if len(path) == 0 {
+		if c.useNumber && isNumber(val) {
+			return reflect.ValueOf(json.Number(val)), nil
+		}
 		return reflect.ValueOf(val), nil
 	}
 
@@ -250,39 +886,101 @@ func inferInterfaceValue(v reflect.Value, path []pathSegment, val string) (refle
 
 	// If the next segment has an index, it's a slice element.
 	if seg.Index {
-		return inferSliceValue(v, path, val)
+		return c.inferSliceValue(v, path, val)
 	}
 
 	// Otherwise it's a map element.
-	return inferMapValue(v, seg, path, val)
+	return c.inferMapValue(v, seg, path, val)
+}
+
+// numberPattern matches the number grammar of JSON.
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// isNumber reports whether s is a valid JSON number.
+func isNumber(s string) bool {
+	return numberPattern.MatchString(s)
 }
 
 // infer a slice value for the given path segment.
-func inferSliceValue(v reflect.Value, path []pathSegment, val string) (reflect.Value, error) {
+func (c *Codec) inferSliceValue(v reflect.Value, path []pathSegment, val string) (reflect.Value, error) {
 	var slice []interface{}
-	if v.IsValid() && !v.IsNil() {
-		slice = v.Interface().([]interface{})
+	if n := c.sizeHint(len(c.path) - len(path)); n > 0 {
+		slice = make([]interface{}, 0, n)
+	}
+	seg := path[0]
+	if v.IsValid() {
+		s, ok := v.Interface().([]interface{})
+		if !ok {
+			// Array notation below a key already decoded as a map is
+			// resolved by the notation policy.
+			m, isMap := v.Interface().(map[string]interface{})
+			if !isMap || c.strict {
+				return reflect.Value{}, &UnmarshalTypeError{Value: val, Type: v.Type(), Err: errors.New("existing value is not an array")}
+			}
+			if c.notation == NotationMapWins {
+				return c.inferMapValue(v, pathSegment{Key: elementKey(m, seg)}, path, val)
+			}
+		} else {
+			slice = s
+		}
+	}
+
+	n := seg.Pos + 1
+	if seg.Pos < 0 {
+		n = len(slice) + 1
+	}
+	if err := c.checkSliceLength(n); err != nil {
+		return reflect.Value{}, err
+	}
+	if seg.Pos < 0 {
+		elem, err := c.inferInterfaceValue(reflect.Value{}, path[1:], val)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		slice = append(slice, elem.Interface())
+		return reflect.ValueOf(slice), nil
 	}
 
-	elem, err := inferInterfaceValue(reflect.Value{}, path[1:], val)
+	// Positional segments address an existing element, growing the slice with
+	// nil elements when the position lies beyond its end.
+	for len(slice) <= seg.Pos {
+		slice = append(slice, nil)
+	}
+
+	elem, err := c.inferInterfaceValue(reflect.ValueOf(slice[seg.Pos]), path[1:], val)
 	if err != nil {
 		return reflect.Value{}, err
 	}
 
-	slice = append(slice, elem.Interface())
+	slice[seg.Pos] = elem.Interface()
 	return reflect.ValueOf(slice), nil
 }
 
 // infer a map value for the given path segment. Unlike slices, we need to
 // explicitly instantiate the map if it doesn't exist, as it is not possible to
 // insert into a nil map.
-func inferMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) (reflect.Value, error) {
-	m := make(map[string]interface{})
-	if v.IsValid() && !v.IsNil() {
-		m = v.Interface().(map[string]interface{})
+func (c *Codec) inferMapValue(v reflect.Value, seg pathSegment, path []pathSegment, val string) (reflect.Value, error) {
+	var m map[string]interface{}
+	if v.IsValid() {
+		existing, ok := v.Interface().(map[string]interface{})
+		if !ok {
+			// Map notation below a key already decoded as an array is
+			// resolved by the notation policy.
+			slice, isSlice := v.Interface().([]interface{})
+			if !isSlice || c.strict {
+				return reflect.Value{}, &UnmarshalTypeError{Value: val, Type: v.Type(), Err: errors.New("existing value is not a map")}
+			}
+			if c.notation == NotationMapWins {
+				existing = sliceToMap(slice)
+			}
+		}
+		m = existing
+	}
+	if m == nil {
+		m = make(map[string]interface{}, c.sizeHint(len(c.path)-len(path)))
 	}
 
-	elem, err := inferInterfaceValue(reflect.ValueOf(m[seg.Key]), path[1:], val)
+	elem, err := c.inferInterfaceValue(reflect.ValueOf(m[seg.Key]), path[1:], val)
 	if err != nil {
 		return reflect.Value{}, err
 	}
@@ -303,71 +1001,122 @@ func asUnmarshaler(v reflect.Value) (Unmarshaler, bool) {
 	return nil, false
 }
 
-func findStructField(v reflect.Value, key string) reflect.Value {
-	tags := tags(v)
+// ignoresField reports whether the struct v has a field named key that is
+// ignored by its tag.
+func (c *Codec) ignoresField(v reflect.Value, key string) bool {
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		if tags[i].Ignore && v.Type().Field(i).Name == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Codec) findStructField(v reflect.Value, key string) (reflect.Value, *tag) {
+	tags := c.tags(v)
 	for i := 0; i < v.NumField(); i++ {
 		if tags[i].Ignore {
 			continue
 		}
 		if tags[i].Name == key {
-			return v.Field(i)
+			return v.Field(i), tags[i]
 		}
 	}
-	return reflect.Value{}
+	return reflect.Value{}, nil
 }
 
-func setScalar(v reflect.Value, val string) error {
+// findDottedField returns the field of the struct v with a dotted name
+// spelled by key followed by the leading segments of path, together with the
+// rest of path.
+func (c *Codec) findDottedField(v reflect.Value, key string, path []pathSegment) (reflect.Value, *tag, []pathSegment) {
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		if tags[i].matchesPath(key, path) {
+			return v.Field(i), tags[i], path[len(tags[i].Path)-1:]
+		}
+	}
+	return reflect.Value{}, nil, path
+}
+
+// findDeprecatedField returns the field of the struct v of which key is a
+// deprecated name.
+func (c *Codec) findDeprecatedField(v reflect.Value, key string) (reflect.Value, *tag) {
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		if tags[i].Ignore {
+			continue
+		}
+		for _, name := range tags[i].Deprecated {
+			if name == key {
+				return v.Field(i), tags[i]
+			}
+		}
+	}
+	return reflect.Value{}, nil
+}
+
+func (c *Codec) setScalar(v reflect.Value, val string) error {
 	switch v.Kind() {
 	case reflect.String:
 		v.SetString(val)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return setInt(v, val)
+		return c.setInt(v, val)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return setUint(v, val)
+		return c.setUint(v, val)
 	case reflect.Float32, reflect.Float64:
-		return setFloat(v, val)
+		return c.setFloat(v, val)
 	case reflect.Bool:
 		return parseBool(v, val)
 	default:
-		return fmt.Errorf("unsupported type: %v", v.Type())
+		return &UnsupportedTypeError{Type: v.Type()}
 	}
 	return nil
 }
 
-func setInt(v reflect.Value, s string) error {
+func (c *Codec) setInt(v reflect.Value, s string) error {
 	if s == "" {
 		v.SetInt(0)
 		return nil
 	}
 	i, err := strconv.ParseInt(s, 10, v.Type().Bits())
 	if err != nil {
-		return fmt.Errorf("setInt: %w", err)
+		var ok bool
+		if i, ok = c.overflow.fitInt(s, v.Type().Bits()); !ok {
+			return parseError(v, s, err)
+		}
 	}
 	v.SetInt(i)
 	return nil
 }
 
-func setUint(v reflect.Value, s string) error {
+func (c *Codec) setUint(v reflect.Value, s string) error {
 	if s == "" {
 		v.SetUint(0)
 		return nil
 	}
 	i, err := strconv.ParseUint(s, 10, v.Type().Bits())
 	if err != nil {
-		return fmt.Errorf("parseUint: %w", err)
+		var ok bool
+		if i, ok = c.overflow.fitUint(s, v.Type().Bits()); !ok {
+			return parseError(v, s, err)
+		}
 	}
 	v.SetUint(i)
 	return nil
 }
 
-func setFloat(v reflect.Value, s string) error {
+func (c *Codec) setFloat(v reflect.Value, s string) error {
 	if s == "" {
 		v.SetFloat(0)
 		return nil
 	}
 	f, err := strconv.ParseFloat(s, v.Type().Bits())
 	if err != nil {
-		return fmt.Errorf("parseFloat: %w", err)
+		var ok bool
+		if f, ok = c.overflow.fitFloat(f, err, v.Type().Bits()); !ok {
+			return parseError(v, s, err)
+		}
 	}
 	v.SetFloat(f)
 	return nil
@@ -380,8 +1129,18 @@ func parseBool(v reflect.Value, s string) error {
 	}
 	b, err := strconv.ParseBool(s)
 	if err != nil {
-		return fmt.Errorf("parseBool: %w", err)
+		return parseError(v, s, err)
 	}
 	v.SetBool(b)
 	return nil
 }
+
+// parseError describes the failure to parse s as the type of v, reporting only
+// the reason given by [strconv] since the value is already part of the error.
+func parseError(v reflect.Value, s string, err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return &UnmarshalTypeError{Value: s, Type: v.Type(), Err: err}
+}
@@ -1,8 +1,12 @@
 package formenc_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -190,6 +194,72 @@ func TestUnmarshal(t *testing.T) {
 				"tags": {"go", "golang", "programming"},
 			},
 		},
+		"struct values in typed map": {
+			input:  []byte("home[street]=1+Main+St&home[city]=Springfield&work[city]=Shelbyville"),
+			target: new(map[string]Address),
+			want: &map[string]Address{
+				"home": {Street: "1 Main St", City: "Springfield"},
+				"work": {City: "Shelbyville"},
+			},
+		},
+		"pointer struct values in typed map": {
+			input:  []byte("home[street]=1+Main+St&home[city]=Springfield"),
+			target: new(map[string]*Address),
+			want: &map[string]*Address{
+				"home": {Street: "1 Main St", City: "Springfield"},
+			},
+		},
+		"nested struct values in typed map": {
+			input:  []byte("alice[name]=Alice&alice[address][city]=Springfield&alice[age]=30"),
+			target: new(map[string]User),
+			want: &map[string]User{
+				"alice": {Name: "Alice", Age: 30, Address: Address{City: "Springfield"}},
+			},
+		},
+		"top-level slice with bracketed indices": {
+			input:  []byte("[0][name]=alice&[1][name]=bob&[1][age]=30"),
+			target: new([]Person),
+			want:   &[]Person{{Name: "alice"}, {Name: "bob", Age: 30}},
+		},
+		"top-level slice with bare indices": {
+			input:  []byte("0[name]=alice&1[name]=bob"),
+			target: new([]Person),
+			want:   &[]Person{{Name: "alice"}, {Name: "bob"}},
+		},
+		"top-level slice of scalars": {
+			input:  []byte("0=a&1=b"),
+			target: new([]string),
+			want:   &[]string{"a", "b"},
+		},
+		"top-level slice with sparse indices": {
+			input:  []byte("5=b&50000000=c&0=a"),
+			target: new([]string),
+			want:   &[]string{"a", "b", "c"},
+		},
+		"indexed key below a field": {
+			input:   []byte("items[0][sku]=a"),
+			target:  &Basket{},
+			wantErr: true,
+		},
+		"percent-encoded brackets": {
+			input:  []byte("data%5Bitems%5D%5B%5D=x&data%5Bitems%5D%5B%5D=y"),
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"data": map[string]interface{}{"items": []interface{}{"x", "y"}},
+			},
+		},
+		"percent-encoded brackets within segments": {
+			input:  []byte("data[a%5Bb%5D]=x&data[%5D]=y"),
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"data": map[string]interface{}{"a[b]": "x", "]": "y"},
+			},
+		},
+		"slice of pointers": {
+			input:  []byte("ids[]=1&ids[]=2"),
+			target: new(map[string][]*int),
+			want:   &map[string][]*int{"ids": {intPtr(1), intPtr(2)}},
+		},
 		"unicode in keys and values": {
 			input:  []byte("名前=太郎&city=東京"),
 			target: new(map[string]string),
@@ -502,6 +572,632 @@ func TestInvalidUnmarshalError(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_FieldNamer(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    GeneratedPerson
+		wantErr bool
+	}{
+		"named by method": {
+			input: "first_name=john",
+			want:  GeneratedPerson{FirstName: "john"},
+		},
+		"tag takes precedence": {
+			input: "surname=smith",
+			want:  GeneratedPerson{LastName: "smith"},
+		},
+		"go name replaced": {
+			input:   "FirstName=john",
+			wantErr: true,
+		},
+		"ignored by method": {
+			input:   "Internal=secret",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got GeneratedPerson
+			err := formenc.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDottedTagNames(t *testing.T) {
+	t.Parallel()
+
+	type Shipment struct {
+		Name string   `form:"name"`
+		City string   `form:"address.city"`
+		Zip  string   `form:"address.zip"`
+		Tags []string `form:"meta.tags"`
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input string
+		want  Shipment
+	}{
+		"bracketed keys": {
+			input: "address%5Bcity%5D=York&address%5Bzip%5D=YO1&meta%5Btags%5D%5B%5D=a&meta%5Btags%5D%5B%5D=b&name=box",
+			want:  Shipment{Name: "box", City: "York", Zip: "YO1", Tags: []string{"a", "b"}},
+		},
+		"dotted keys": {
+			opts:  []formenc.Option{formenc.WithDottedKeys()},
+			input: "address.city=York&address.zip=YO1&name=box",
+			want:  Shipment{Name: "box", City: "York", Zip: "YO1"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Shipment
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+
+			data, err := codec.Marshal(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.input, string(data)); diff != "" {
+				t.Errorf("encode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKVTagFlag(t *testing.T) {
+	t.Parallel()
+
+	type Deployment struct {
+		Labels map[string]string `form:"labels,kv=;:"`
+		Limits map[string]int    `form:"limits,kv=|="`
+	}
+
+	tests := map[string]struct {
+		input   string
+		want    Deployment
+		wantErr bool
+	}{
+		"packed maps": {
+			input: "labels=env%3Aprod%3Btier%3Aweb&limits=cpu%3D2%7Cmem%3D512",
+			want: Deployment{
+				Labels: map[string]string{"env": "prod", "tier": "web"},
+				Limits: map[string]int{"cpu": 2, "mem": 512},
+			},
+		},
+		"empty map": {
+			input: "labels=",
+			want:  Deployment{Labels: map[string]string{}},
+		},
+		"missing separator": {
+			input:   "labels=env",
+			wantErr: true,
+		},
+		"invalid value": {
+			input:   "limits=cpu%3Dtwo",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Deployment
+			err := formenc.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+
+			data, err := formenc.Marshal(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.input, string(data)); diff != "" {
+				t.Errorf("encode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKVTagFlag_InvalidSeparators(t *testing.T) {
+	t.Parallel()
+
+	type Deployment struct {
+		Bad map[string]string `form:"bad,kv=;"`
+	}
+
+	wantMsg := `form: invalid tag flag "kv=;" on field Bad of formenc_test.Deployment: want two different separators, got ";"`
+
+	err := formenc.Unmarshal([]byte("bad=a%3Ab"), &Deployment{})
+	var tagErr *formenc.TagError
+	if !errors.As(err, &tagErr) {
+		t.Fatalf("expected TagError, got: %v", err)
+	}
+	if diff := cmp.Diff(wantMsg, err.Error()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	_, err = formenc.Marshal(Deployment{Bad: map[string]string{"a": "b"}})
+	if !errors.As(err, &tagErr) {
+		t.Fatalf("expected TagError, got: %v", err)
+	}
+	if diff := cmp.Diff(wantMsg, err.Error()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestKVTagFlag_Separators(t *testing.T) {
+	t.Parallel()
+
+	type Deployment struct {
+		Labels map[string]string `form:"labels,kv=;:"`
+	}
+
+	tests := map[string]map[string]string{
+		"value with pair separator": {"a": "x;y:z"},
+		"value with key separator":  {"a": "x:y"},
+		"key with pair separator":   {"a;b": "x"},
+		"key with key separator":    {"a:b": "x"},
+	}
+	for name, labels := range tests {
+		labels := labels
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := formenc.Marshal(Deployment{Labels: labels})
+			var valueErr *formenc.UnsupportedValueError
+			if !errors.As(err, &valueErr) {
+				t.Fatalf("expected UnsupportedValueError, got: %v", err)
+			}
+			if valueErr.Key != "labels" {
+				t.Errorf("expected key %q, got %q", "labels", valueErr.Key)
+			}
+		})
+	}
+
+	// Values free of the separators survive a round trip.
+	want := Deployment{Labels: map[string]string{"a": "x y", "b": "x=y"}}
+	data, err := formenc.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got Deployment
+	if err := formenc.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshal_DataUnmarshaler(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    Coordinate
+		wantErr bool
+	}{
+		"data unmarshaler": {
+			input: " x=3&y=4\n",
+			want:  Coordinate{X: 3, Y: 4},
+		},
+		"error": {
+			input:   "y=4&x=3",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Coordinate
+			err := formenc.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_CommonMaps(t *testing.T) {
+	t.Parallel()
+
+	upper := formenc.WithDecodeFunc("", func(s string) (interface{}, error) {
+		return strings.ToUpper(s), nil
+	})
+
+	tests := map[string]struct {
+		opts      []formenc.Option
+		useNumber bool
+		input     string
+		target    interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		"string map": {
+			input:  "a=1&b=2&a=3",
+			target: &map[string]string{"c": "4"},
+			want:   &map[string]string{"a": "3", "b": "2", "c": "4"},
+		},
+		"string map nested key": {
+			input:   "a[b]=1",
+			target:  &map[string]string{},
+			wantErr: true,
+		},
+		"string map decode func": {
+			opts:   []formenc.Option{upper},
+			input:  "a=x",
+			target: &map[string]string{},
+			want:   &map[string]string{"a": "X"},
+		},
+		"strings map": {
+			input:  "a=1&b[]=2&a=3&b[]=4",
+			target: &map[string][]string{"a": {"0"}},
+			want:   &map[string][]string{"a": {"0", "1", "3"}, "b": {"2", "4"}},
+		},
+		"strings map slice length": {
+			opts:    []formenc.Option{formenc.WithLimits(formenc.Limits{MaxSliceLength: 1})},
+			input:   "a=1&a=2",
+			target:  &map[string][]string{},
+			wantErr: true,
+		},
+		"interface map": {
+			input:  "a=1&b[c]=2&d[]=3",
+			target: &map[string]interface{}{},
+			want: &map[string]interface{}{
+				"a": "1",
+				"b": map[string]interface{}{"c": "2"},
+				"d": []interface{}{"3"},
+			},
+		},
+		"interface map numbers": {
+			useNumber: true,
+			input:     "a=1&b=x",
+			target:    &map[string]interface{}{},
+			want:      &map[string]interface{}{"a": json.Number("1"), "b": "x"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			dec := codec.NewDecoder(strings.NewReader(tt.input))
+			if tt.useNumber {
+				dec.UseNumber()
+			}
+			err = dec.Decode(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, tt.target); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_MapOfSlices(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithAggregateErrors())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string][]int{"ids": {1}}
+	err = codec.Unmarshal([]byte("ids[]=2&sizes[]=10&ids[]=x&ids[]=3&bad[]=y"), &got)
+
+	want := map[string][]int{"ids": {1, 2, 3}, "sizes": {10}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decode mismatch (-want +got):\n%s", diff)
+	}
+	var multi *formenc.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, got: %v", err)
+	}
+	if len(multi.Errors) != 2 {
+		t.Errorf("expected 2 errors, got: %v", multi)
+	}
+}
+
+func TestUnmarshal_RepeatedKeys(t *testing.T) {
+	t.Parallel()
+
+	type Post struct {
+		Title string   `form:"title"`
+		Tags  []string `form:"tags"`
+	}
+
+	// Enough values to be parsed in place, as large inputs are.
+	many := make([]string, 2000)
+	for i := range many {
+		many[i] = fmt.Sprintf("t%04d", i)
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input string
+		want  []string
+	}{
+		"default": {
+			input: "tags=go&title=x&tags=web&tags=api",
+			want:  []string{"go", "web", "api"},
+		},
+		"mixed with brackets": {
+			input: "tags=go&tags%5B%5D=web&tags=api",
+			want:  []string{"go", "web", "api"},
+		},
+		"dotted keys": {
+			opts:  []formenc.Option{formenc.WithDottedKeys()},
+			input: "tags=go&tags=web&tags=api",
+			want:  []string{"go", "web", "api"},
+		},
+		"path delimiters": {
+			opts:  []formenc.Option{formenc.WithPathDelimiters('(', ')')},
+			input: "tags=go&tags=web&tags=api",
+			want:  []string{"go", "web", "api"},
+		},
+		"qs": {
+			opts:  []formenc.Option{formenc.WithQSCompat(formenc.QSOptions{})},
+			input: "tags=go&tags=web&tags=api",
+			want:  []string{"go", "web", "api"},
+		},
+		"large input": {
+			input: "tags=" + strings.Join(many, "&tags="),
+			want:  many,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Post
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.Tags); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_PointerTargets(t *testing.T) {
+	t.Parallel()
+
+	type Settings struct {
+		Labels    *map[string]string `form:"labels"`
+		Tags      *[]string          `form:"tags"`
+		Weights   **map[string]int   `form:"weights"`
+		Addresses *[]Address         `form:"addresses"`
+	}
+
+	data := []byte("labels[env]=prod&tags[]=a&tags[]=b&weights[x]=3&addresses[][city]=Paris")
+
+	var got Settings
+	if err := formenc.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	weights := &map[string]int{"x": 3}
+	want := Settings{
+		Labels:    &map[string]string{"env": "prod"},
+		Tags:      &[]string{"a", "b"},
+		Weights:   &weights,
+		Addresses: &[]Address{{City: "Paris"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// Pointers are followed when encoding too.
+	want.Addresses = nil
+	encoded, err := formenc.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var again Settings
+	if err := formenc.Unmarshal(encoded, &again); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, again); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+
+	var m *map[string]string
+	if err := formenc.Unmarshal([]byte("a=1"), &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&map[string]string{"a": "1"}, m); diff != "" {
+		t.Errorf("top-level map (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshal_LargeInput(t *testing.T) {
+	t.Parallel()
+
+	// Inputs above 16KiB are parsed without first being copied into a string.
+	padding := strings.Repeat("x", 16<<10)
+
+	tests := map[string]struct {
+		input   string
+		want    Basket
+		wantErr bool
+	}{
+		"escaped and repeated keys": {
+			input: "items%5B0%5D%5Bsku%5D=a%2Bb&items[0][qty]=1&items%5B1%5D%5Bsku%5D=" + padding + "&items[1][qty]=2",
+			want:  Basket{Items: []OrderLine{{SKU: "a+b", Qty: 1}, {SKU: padding, Qty: 2}}},
+		},
+		"spaces": {
+			input: "items[0][sku]=a+b+c&&items[0][qty]=3&" + padding + "=",
+			want:  Basket{Items: []OrderLine{{SKU: "a b c", Qty: 3}}},
+		},
+		"semicolon": {
+			input:   "items[][sku]=a;b&" + padding + "=",
+			wantErr: true,
+		},
+		"invalid escape": {
+			input:   "items[][sku]=%zz&" + padding + "=",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithIgnoreUnknownKeys(), formenc.WithIndexedKeys())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data := []byte(tt.input)
+			var got Basket
+			err = codec.Unmarshal(data, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Decoded strings do not share memory with the input.
+			for i := range data {
+				data[i] = '?'
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Presize(t *testing.T) {
+	t.Parallel()
+
+	// Slices of large inputs are allocated once, at the size they are given.
+	var parts []string
+	var items []OrderLine
+	var list []interface{}
+	for i := 0; i < 40; i++ {
+		parts = append(parts, fmt.Sprintf("items[%d][sku]=sku_%d&items[%d][qty]=%d&list[]=%d", i, i, i, i, i))
+		items = append(items, OrderLine{SKU: fmt.Sprintf("sku_%d", i), Qty: i})
+		list = append(list, fmt.Sprint(i))
+	}
+	data := []byte(strings.Join(parts, "&"))
+
+	codec, err := formenc.NewCodec(formenc.WithIgnoreUnknownKeys(), formenc.WithIndexedKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var basket Basket
+	if err := codec.Unmarshal(data, &basket); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(items, basket.Items); diff != "" {
+		t.Errorf("decode mismatch (-want +got):\n%s", diff)
+	}
+	if cap(basket.Items) != len(items) {
+		t.Errorf("expected capacity %d, got %d", len(items), cap(basket.Items))
+	}
+
+	var m map[string]interface{}
+	if err := codec.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := m["list"].([]interface{})
+	if diff := cmp.Diff(list, got); diff != "" {
+		t.Errorf("decode mismatch (-want +got):\n%s", diff)
+	}
+	if cap(got) != len(list) {
+		t.Errorf("expected capacity %d, got %d", len(list), cap(got))
+	}
+}
+
+func TestCodec_Prepare(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Recursive types and types without struct fields are prepared.
+	codec.Prepare(reflect.TypeOf(&Node{}), reflect.TypeOf(map[string][]User{}), nil)
+
+	want := Node{Name: "a", Next: &Node{Name: "b"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var got Node
+			if err := codec.Unmarshal([]byte("name=a&next[name]=b"), &got); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("decode mismatch (-want +got):\n%s", diff)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func BenchmarkUnmarshal(b *testing.B) {
 	benchmarks := map[string]struct {
 		input  []byte
@@ -555,6 +1251,14 @@ func BenchmarkUnmarshal(b *testing.B) {
 			input:  []byte("name=%E5%A4%AA%E9%83%8E&city=%E6%9D%B1%E4%BA%AC"),
 			target: func() interface{} { return new(map[string]string) },
 		},
+		"large array": {
+			input:  generateEncodedItems(500),
+			target: func() interface{} { return &Basket{} },
+		},
+		"large indexed array": {
+			input:  generateIndexedItems(500),
+			target: func() interface{} { return &Basket{} },
+		},
 	}
 	for name, bm := range benchmarks {
 		bm := bm
@@ -578,3 +1282,27 @@ func generateEncodedMap(size int) []byte {
 	}
 	return []byte(strings.Join(parts, "&"))
 }
+
+type Basket struct {
+	Items []OrderLine `form:"items"`
+}
+
+func generateEncodedItems(size int) []byte {
+	var parts []string
+	for i := 0; i < size; i++ {
+		parts = append(parts, fmt.Sprintf("items%%5B%%5D%%5Bsku%%5D=sku_%d&items%%5B%%5D%%5Bqty%%5D=%d", i, i))
+	}
+	return []byte(strings.Join(parts, "&"))
+}
+
+func generateIndexedItems(size int) []byte {
+	var parts []string
+	for i := 0; i < size; i++ {
+		parts = append(parts, fmt.Sprintf("items[%d][sku]=sku_%d&items[%d][qty]=%d", i, i, i, i))
+	}
+	return []byte(strings.Join(parts, "&"))
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,138 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Shape is the structure of the form data decoded into a field.
+type Shape int
+
+const (
+	// ShapeScalar is a single value, such as a string, number or a type
+	// implementing [Unmarshaler].
+	ShapeScalar Shape = iota
+
+	// ShapeStruct is a set of nested fields, described by
+	// [FieldDescriptor.Fields].
+	ShapeStruct
+
+	// ShapeSlice is a list of values. If the elements are structs their
+	// fields are described by [FieldDescriptor.Fields].
+	ShapeSlice
+
+	// ShapeMap is a set of values keyed by arbitrary names. If the values are
+	// structs their fields are described by [FieldDescriptor.Fields].
+	ShapeMap
+)
+
+// String returns the name of the shape.
+func (s Shape) String() string {
+	switch s {
+	case ShapeScalar:
+		return "scalar"
+	case ShapeStruct:
+		return "struct"
+	case ShapeSlice:
+		return "slice"
+	case ShapeMap:
+		return "map"
+	}
+	return fmt.Sprintf("Shape(%d)", int(s))
+}
+
+// FieldDescriptor describes a struct field as it appears in form data.
+//
+// Required, Default and Enum are read from the required, default=value and
+// enum=a|b|c tag flags, and ReadOnly and WriteOnly from the readonly and
+// writeonly flags. They describe the field to form builders and documentation
+// generators.
+type FieldDescriptor struct {
+	Name     string       // the form name of the field
+	Type     reflect.Type // the Go type of the field
+	Shape    Shape        // the structure of the field's form data
+	Required bool         // whether the field must be present
+	Default  string       // the value used when the field is absent, if any
+	Enum     []string     // the values the field accepts, if restricted
+
+	ReadOnly  bool // whether the field is encoded but never decoded
+	WriteOnly bool // whether the field is decoded but never encoded
+
+	// Fields describes the fields of a struct, or of the struct elements of
+	// a slice or map. It is empty for a type that contains itself, where the
+	// fields are already described by an enclosing descriptor.
+	Fields []FieldDescriptor
+}
+
+// Describe returns descriptors of the fields of the struct type t, or of the
+// struct t points to, as they are decoded by [Unmarshal].
+func Describe(t reflect.Type) ([]FieldDescriptor, error) {
+	return defaultCodec.Describe(t)
+}
+
+// Describe returns descriptors of the fields of the struct type t, or of the
+// struct t points to, as they are decoded by c.
+func (c *Codec) Describe(t reflect.Type) ([]FieldDescriptor, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form: cannot describe %v, which is not a struct", t)
+	}
+	return c.describeFields(t, map[reflect.Type]bool{}), nil
+}
+
+// describeFields describes the fields of the struct type t. Types being
+// described are recorded in visiting, so that recursive types terminate.
+func (c *Codec) describeFields(t reflect.Type, visiting map[reflect.Type]bool) []FieldDescriptor {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	tags := c.tags(reflect.New(t).Elem())
+	fields := make([]FieldDescriptor, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f, tag := t.Field(i), tags[i]
+		if tag.Ignore || tag.Name == "" || !f.IsExported() {
+			continue
+		}
+
+		d := FieldDescriptor{
+			Name:     tag.Name,
+			Type:     f.Type,
+			Required: tag.Required,
+			Default:  tag.Default,
+			Enum:     append([]string(nil), tag.Enum...),
+
+			ReadOnly:  tag.ReadOnly,
+			WriteOnly: tag.WriteOnly,
+		}
+		d.Shape, d.Fields = c.describeType(f.Type, visiting)
+		fields = append(fields, d)
+	}
+	return fields
+}
+
+// describeType returns the shape of t and the fields it contains, if any.
+func (c *Codec) describeType(t reflect.Type, visiting map[reflect.Type]bool) (Shape, []FieldDescriptor) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if c.decodesLeaf(reflect.New(t).Elem()) {
+		return ShapeScalar, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return ShapeStruct, c.describeFields(t, visiting)
+	case reflect.Slice, reflect.Array:
+		_, fields := c.describeType(t.Elem(), visiting)
+		return ShapeSlice, fields
+	case reflect.Map:
+		_, fields := c.describeType(t.Elem(), visiting)
+		return ShapeMap, fields
+	}
+	return ShapeScalar, nil
+}
@@ -0,0 +1,67 @@
+package formenc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Order struct {
+	ID       int               `form:"id,required"`
+	Status   string            `form:"status,default=pending,enum=pending|paid|shipped"`
+	Placed   MyDate            `form:"placed"`
+	Lines    []OrderLine       `form:"lines"`
+	Tags     []string          `form:"tags,omitempty"`
+	Shipping *Address          `form:"shipping"`
+	Extra    map[string]string `form:"extra"`
+	Parent   *Order            `form:"parent"`
+	Internal string            `form:"-"`
+	secret   string
+}
+
+type OrderLine struct {
+	SKU string `form:"sku,required"`
+	Qty int    `form:"qty,default=1"`
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	got, err := formenc.Describe(reflect.TypeOf(&Order{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	str := reflect.TypeOf("")
+	address := []formenc.FieldDescriptor{
+		{Name: "street", Type: str, Shape: formenc.ShapeScalar},
+		{Name: "city", Type: str, Shape: formenc.ShapeScalar},
+		{Name: "state", Type: str, Shape: formenc.ShapeScalar},
+		{Name: "zip", Type: str, Shape: formenc.ShapeScalar},
+	}
+	want := []formenc.FieldDescriptor{
+		{Name: "id", Type: reflect.TypeOf(0), Shape: formenc.ShapeScalar, Required: true},
+		{Name: "status", Type: str, Shape: formenc.ShapeScalar, Default: "pending", Enum: []string{"pending", "paid", "shipped"}},
+		{Name: "placed", Type: reflect.TypeOf(MyDate{}), Shape: formenc.ShapeScalar},
+		{Name: "lines", Type: reflect.TypeOf([]OrderLine{}), Shape: formenc.ShapeSlice, Fields: []formenc.FieldDescriptor{
+			{Name: "sku", Type: str, Shape: formenc.ShapeScalar, Required: true},
+			{Name: "qty", Type: reflect.TypeOf(0), Shape: formenc.ShapeScalar, Default: "1"},
+		}},
+		{Name: "tags", Type: reflect.TypeOf([]string{}), Shape: formenc.ShapeSlice},
+		{Name: "shipping", Type: reflect.TypeOf(&Address{}), Shape: formenc.ShapeStruct, Fields: address},
+		{Name: "extra", Type: reflect.TypeOf(map[string]string{}), Shape: formenc.ShapeMap},
+		{Name: "parent", Type: reflect.TypeOf(&Order{}), Shape: formenc.ShapeStruct},
+	}
+
+	typeComparer := cmp.Comparer(func(a, b reflect.Type) bool { return a == b })
+	if diff := cmp.Diff(want, got, typeComparer); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, err := formenc.Describe(reflect.TypeOf(0)); err == nil {
+		t.Error("expected error describing a non-struct type, got nil")
+	}
+}
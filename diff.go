@@ -0,0 +1,61 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MarshalDiff returns the form encoding of the fields of next whose values
+// differ from those of prev, producing a minimal body for a partial update.
+// See [Codec.MarshalDiff].
+func MarshalDiff(prev, next interface{}) ([]byte, error) {
+	return defaultCodec.MarshalDiff(prev, next)
+}
+
+// MarshalDiff returns the form encoding of the fields of next whose values
+// differ from those of prev, which must have the same type. A key whose
+// values differ is written with all of its values in next, so a changed slice
+// is written in full. A key present in prev but absent from next, such as an
+// omitempty field that has been cleared, is written with an empty value. If
+// prev is nil the whole of next is encoded.
+func (c *Codec) MarshalDiff(prev, next interface{}) ([]byte, error) {
+	if prev != nil && next != nil && reflect.TypeOf(prev) != reflect.TypeOf(next) {
+		return nil, fmt.Errorf("form: cannot diff %T against %T", next, prev)
+	}
+
+	before, _, err := c.encodePairs(prev)
+	if err != nil {
+		return nil, err
+	}
+	after, _, err := c.encodePairs(next)
+	if err != nil {
+		return nil, err
+	}
+
+	was, is := groupPairs(before), groupPairs(after)
+
+	var changed []pair
+	for _, p := range after {
+		if !reflect.DeepEqual(was[p.key], is[p.key]) {
+			changed = append(changed, p)
+		}
+	}
+	for _, p := range before {
+		if _, ok := is[p.key]; !ok {
+			// Record the key so that repeated keys are cleared once.
+			is[p.key] = nil
+			changed = append(changed, pair{key: p.key})
+		}
+	}
+	return c.format(changed), nil
+}
+
+// groupPairs returns the values of pairs grouped by key, in the order they
+// appear.
+func groupPairs(pairs []pair) map[string][]string {
+	groups := make(map[string][]string, len(pairs))
+	for _, p := range pairs {
+		groups[p.key] = append(groups[p.key], p.value)
+	}
+	return groups
+}
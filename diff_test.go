@@ -0,0 +1,77 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestMarshalDiff(t *testing.T) {
+	t.Parallel()
+
+	base := ComplexPerson{
+		ID:       1,
+		Name:     "john",
+		Age:      30,
+		Pronouns: []string{"he", "him"},
+	}
+
+	tests := map[string]struct {
+		prev    interface{}
+		next    interface{}
+		want    []byte
+		wantErr bool
+	}{
+		"no changes": {
+			prev: base,
+			next: base,
+			want: []byte{},
+		},
+		"changed scalar": {
+			prev: base,
+			next: func() ComplexPerson { p := base; p.Name = "jane"; return p }(),
+			want: pathEscape("name=jane"),
+		},
+		"changed slice written in full": {
+			prev: base,
+			next: func() ComplexPerson { p := base; p.Pronouns = []string{"he", "they"}; return p }(),
+			want: pathEscape("pronouns[]=he&pronouns[]=they"),
+		},
+		"cleared omitempty fields": {
+			prev: base,
+			next: func() ComplexPerson { p := base; p.Age = 0; p.Pronouns = nil; return p }(),
+			want: pathEscape("age=&pronouns[]="),
+		},
+		"nested fields": {
+			prev: &User{Name: "john", Address: Address{City: "London", Zip: "N1"}},
+			next: &User{Name: "john", Address: Address{City: "Leeds", Zip: "N1"}},
+			want: pathEscape("address[city]=Leeds"),
+		},
+		"nil previous value": {
+			prev: nil,
+			next: Person{Name: "john"},
+			want: pathEscape("name=john"),
+		},
+		"mismatched types": {
+			prev:    Person{},
+			next:    User{},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.MarshalDiff(tt.prev, tt.next)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WithDiscriminator configures a [Codec] to decode form data into one of
+// several types, selected by the value of the top-level key named key, with
+// [Codec.UnmarshalDiscriminated]. Each value of types is an example of the
+// type decoded for its discriminator value:
+//
+//	formenc.WithDiscriminator("type", map[string]interface{}{
+//		"card": CardPayment{},
+//		"bank": BankPayment{},
+//	})
+//
+// The discriminator key is not treated as unknown by types without a field of
+// that name.
+func WithDiscriminator(key string, types map[string]interface{}) Option {
+	return func(c *Codec) error {
+		if key == "" {
+			return fmt.Errorf("form: empty discriminator key")
+		}
+		if len(types) == 0 {
+			return fmt.Errorf("form: discriminator %q has no types", key)
+		}
+		variants := make(map[string]reflect.Type, len(types))
+		for value, example := range types {
+			t := reflect.TypeOf(example)
+			if t == nil {
+				return fmt.Errorf("form: discriminator value %q has no type", value)
+			}
+			t = indirectType(t)
+			if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+				return fmt.Errorf("form: discriminator value %q selects %v, not a struct or map", value, t)
+			}
+			variants[value] = t
+		}
+		c.discriminator = key
+		c.variants = variants
+		return nil
+	}
+}
+
+// UnmarshalDiscriminated parses the form data into a new value of the type
+// selected by its discriminator key, as configured by [WithDiscriminator],
+// returning a pointer to the decoded value and the type selected. A
+// [DiscriminatorError] is returned if the key is missing or its value selects
+// no type.
+func (c *Codec) UnmarshalDiscriminated(data []byte) (interface{}, reflect.Type, error) {
+	if c.discriminator == "" {
+		return nil, nil, fmt.Errorf("form: no discriminator configured")
+	}
+
+	value, err := c.discriminatorValue(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, ok := c.variants[value]
+	if !ok {
+		return nil, nil, fmt.Errorf("form: %w", &DiscriminatorError{Key: c.discriminator, Value: value})
+	}
+
+	v := reflect.New(t)
+	if err := c.unmarshal(data, v.Interface()); err != nil {
+		return nil, t, err
+	}
+	return v.Interface(), t, nil
+}
+
+// discriminatorValue returns the value of the discriminator key in data.
+func (c *Codec) discriminatorValue(data []byte) (string, error) {
+	// Issues found while parsing are reported when the data is decoded, so
+	// the discriminator is found without recording them.
+	p := c.plain()
+	p.metadata = nil
+	entries, err := p.parse(strings.TrimSpace(string(data)), reflect.TypeOf(map[string]interface{}(nil)))
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if len(e.path) == 1 && !e.path[0].Index && e.path[0].Key == c.discriminator {
+			return e.value, nil
+		}
+	}
+	return "", fmt.Errorf("form: %w", &DiscriminatorError{Key: c.discriminator})
+}
+
+// dropDiscriminator removes the discriminator key from entries decoded into
+// v, a struct without a field of that name.
+func (c *Codec) dropDiscriminator(entries []entry, v reflect.Value) []entry {
+	if c.discriminator == "" || v.Kind() != reflect.Struct {
+		return entries
+	}
+	if f, _ := c.findStructField(v, c.discriminator); f.IsValid() {
+		return entries
+	}
+
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if len(e.path) == 1 && !e.path[0].Index && e.path[0].Key == c.discriminator {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,110 @@
+package formenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type CardPayment struct {
+	Amount int    `form:"amount"`
+	Number string `form:"number"`
+}
+
+type BankPayment struct {
+	Type    string `form:"type"`
+	Amount  int    `form:"amount"`
+	Account string `form:"account"`
+}
+
+func TestUnmarshalDiscriminated(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithDiscriminator("type", map[string]interface{}{
+		"card": CardPayment{},
+		"bank": &BankPayment{},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		input    string
+		want     interface{}
+		wantType reflect.Type
+		wantErr  *formenc.DiscriminatorError
+	}{
+		"type without discriminator field": {
+			input:    "amount=10&type=card&number=4242",
+			want:     &CardPayment{Amount: 10, Number: "4242"},
+			wantType: reflect.TypeOf(CardPayment{}),
+		},
+		"type with discriminator field": {
+			input:    "type=bank&amount=20&account=12345678",
+			want:     &BankPayment{Type: "bank", Amount: 20, Account: "12345678"},
+			wantType: reflect.TypeOf(BankPayment{}),
+		},
+		"missing discriminator": {
+			input:   "amount=10",
+			wantErr: &formenc.DiscriminatorError{Key: "type"},
+		},
+		"unknown discriminator": {
+			input:   "type=cash&amount=10",
+			wantErr: &formenc.DiscriminatorError{Key: "type", Value: "cash"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, gotType, err := codec.UnmarshalDiscriminated([]byte(tt.input))
+			if tt.wantErr != nil {
+				var discErr *formenc.DiscriminatorError
+				if !errors.As(err, &discErr) {
+					t.Fatalf("expected DiscriminatorError, got: %v", err)
+				}
+				if diff := cmp.Diff(tt.wantErr, discErr); diff != "" {
+					t.Errorf("error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotType != tt.wantType {
+				t.Errorf("expected type %v, got %v", tt.wantType, gotType)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDiscriminated_UnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithDiscriminator("type", map[string]interface{}{
+		"card": CardPayment{},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the discriminator key is accepted by types without a field for it.
+	_, _, err = codec.UnmarshalDiscriminated([]byte("type=card&colour=red"))
+	var unknownErr *formenc.UnknownFieldError
+	if !errors.As(err, &unknownErr) || unknownErr.Key != "colour" {
+		t.Errorf("expected UnknownFieldError for colour, got: %v", err)
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithDiscriminator("type", map[string]interface{}{"n": 1})); err == nil {
+		t.Error("expected error for a discriminator selecting an int, got nil")
+	}
+}
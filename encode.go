@@ -1,11 +1,15 @@
 package formenc
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Marshaler is the interface implemented by types that can marshal themselves
@@ -14,6 +18,16 @@ type Marshaler interface {
 	MarshalForm() (string, error)
 }
 
+// Appender is the interface implemented by types that append their complete
+// form encoding, as "key=value" pairs joined by '&', to a buffer. When the
+// value passed to [Marshal] implements Appender, AppendForm is called in place
+// of walking the value, so that frequently encoded types can avoid reflection.
+// AppendForm must escape the keys and values it writes, and is given no
+// options of the codec: it writes the same output whatever the codec.
+type Appender interface {
+	AppendForm(dst []byte) ([]byte, error)
+}
+
 // EncodeToString is a convenience function that returns the form encoding of v
 // as a string.
 func EncodeToString(v interface{}) (string, error) {
@@ -26,133 +40,478 @@ func EncodeToString(v interface{}) (string, error) {
 
 // Marshal returns the form encoding of v.
 func Marshal(v interface{}) ([]byte, error) {
+	return defaultCodec.Marshal(v)
+}
+
+// AppendMarshal appends the form encoding of v to dst and returns the extended
+// buffer, so that a buffer can be reused or a larger payload built without
+// the allocation made by [Marshal]. On error dst is returned unchanged.
+func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	return defaultCodec.AppendMarshal(dst, v)
+}
+
+func (c *Codec) marshal(v interface{}) ([]byte, error) {
+	data, err := c.appendMarshal(nil, v)
+	if err == nil && data == nil {
+		data = []byte{}
+	}
+	return data, err
+}
+
+func (c *Codec) appendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	if c.hooks.OnEncodeStart != nil || c.hooks.OnEncodeDone != nil {
+		return c.observeMarshal(dst, v)
+	}
+	data, _, err := c.encode(dst, v)
+	return data, err
+}
+
+// encode appends the form encoding of v to dst, returning the extended buffer
+// together with the number of keys appended.
+func (c *Codec) encode(dst []byte, v interface{}) ([]byte, int, error) {
+	if a, ok := v.(Appender); ok {
+		return c.encodeAppender(dst, a)
+	}
+
+	pairs, rv, err := c.encodePairs(v)
+	if err != nil {
+		return dst, 0, err
+	}
+	if !rv.IsValid() {
+		return dst, 0, nil
+	}
+
+	data := c.appendFormat(dst, pairs)
+	if c.roundTrip {
+		if err := c.checkMarshal(rv, data[len(dst):]); err != nil {
+			return dst, 0, err
+		}
+	}
+	return data, len(pairs), nil
+}
+
+// encodeAppender appends the form encoding written by a to dst, returning the
+// extended buffer together with the number of keys appended.
+func (c *Codec) encodeAppender(dst []byte, a Appender) ([]byte, int, error) {
+	rv := reflect.ValueOf(a)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return dst, 0, nil
+		}
+		rv = rv.Elem()
+	}
+
+	data, err := a.AppendForm(dst)
+	if err != nil {
+		return dst, 0, fmt.Errorf("form: %w", err)
+	}
+	appended := data[len(dst):]
+	if c.roundTrip {
+		if err := c.checkMarshal(rv, appended); err != nil {
+			return dst, 0, err
+		}
+	}
+
+	keys := 0
+	if len(appended) > 0 {
+		keys = bytes.Count(appended, []byte{'&'}) + 1
+	}
+	return data, keys, nil
+}
+
+// encodePairs returns the pairs encoding v, in the order they are produced,
+// together with the value encoded. The value is invalid if v is nil.
+func (c *Codec) encodePairs(v interface{}) ([]pair, reflect.Value, error) {
+	e := &encodeState{}
+	rv, err := c.encodeInto(e, v)
+	if err != nil {
+		return nil, rv, err
+	}
+	return e.pairs, rv, nil
+}
+
+// encodeInto encodes v into e, returning the value encoded, which is invalid
+// if v is nil.
+func (c *Codec) encodeInto(e *encodeState, v interface{}) (reflect.Value, error) {
 	if v == nil {
-		return []byte{}, nil
+		return reflect.Value{}, nil
 	}
 
-	// Dereference pointer if needed.
+	// Dereference pointers if needed.
 	rv := reflect.ValueOf(v)
-	if rv.Kind() == reflect.Pointer {
+	for rv.Kind() == reflect.Pointer {
 		if rv.IsNil() {
-			return []byte{}, nil
+			return reflect.Value{}, nil
 		}
 		rv = rv.Elem()
 	}
 
-	// Ensure the top-level value is a struct or map.
-	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map {
-		return nil, fmt.Errorf("form: top-level value must be struct or map")
+	// Ensure the top-level value is a struct, map or slice.
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map && rv.Kind() != reflect.Slice {
+		return rv, fmt.Errorf("form: top-level value must be struct, map or slice")
 	}
 
 	// Ensure map keys are strings.
 	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() != reflect.String {
-		return nil, fmt.Errorf("form: map keys must be strings")
+		return rv, fmt.Errorf("form: map keys must be strings")
+	}
+
+	if rv.Kind() == reflect.Slice {
+		// Elements of a top-level slice are keyed by their position, as in
+		// "0[name]", since there is no enclosing key to index.
+		for i := 0; i < rv.Len(); i++ {
+			if err := c.marshalValue(e, []pathSegment{{Key: strconv.Itoa(i)}}, rv.Index(i)); err != nil {
+				return rv, err
+			}
+		}
+		return rv, nil
+	}
+	return rv, c.marshalValue(e, nil, rv)
+}
+
+// encodeState accumulates the pairs produced while walking a value, in the
+// order they are produced.
+type encodeState struct {
+	pairs []pair
+
+	// fn, if set, is called with the path and value of each pair instead of
+	// the pair being added, and err holds the first error it returns. path
+	// is reused for each call.
+	fn   func(path KeyPath, value string) error
+	err  error
+	path KeyPath
+
+	// visiting holds the pointers, maps and slices currently being encoded,
+	// so that cycles can be detected.
+	visiting map[visit]struct{}
+}
+
+// visit identifies a pointer, map or slice by its address and type. Slices
+// also record their length, as slices of the same array may differ.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// add adds the pair of key, the rendered form of path, and value.
+func (e *encodeState) add(path []pathSegment, key, value string) {
+	if e.fn != nil {
+		e.visit(path, value)
+		return
+	}
+	e.pairs = append(e.pairs, pair{key: key, value: value})
+}
+
+// visit calls fn with path and value, unless it has already failed.
+func (e *encodeState) visit(path []pathSegment, value string) {
+	if e.err != nil {
+		return
+	}
+	e.path = e.path[:0]
+	for _, seg := range path {
+		s := Segment{Key: seg.Key, Index: seg.Index}
+		if seg.Index {
+			s.Pos = seg.Pos
+		}
+		e.path = append(e.path, s)
+	}
+	e.err = e.fn(e.path, value)
+}
+
+// addLeaf adds the pair encoding the single value s found at path.
+func (c *Codec) addLeaf(e *encodeState, path []pathSegment, s string) {
+	if e.fn != nil {
+		e.visit(path, s)
+		return
+	}
+	static := true
+	for _, seg := range path {
+		if seg.Index || seg.Map {
+			static = false
+			break
+		}
 	}
+	e.pairs = append(e.pairs, pair{key: c.renderer.render(path), value: s, static: static})
+}
 
-	values := url.Values{}
-	if err := marshalValue(values, nil, rv); err != nil {
-		return nil, err
+// format escapes and joins pairs into the encoded output. Unless the codec
+// preserves order, pairs are sorted by key as [url.Values.Encode] does.
+func (c *Codec) format(pairs []pair) []byte {
+	if b := c.appendFormat(nil, pairs); b != nil {
+		return b
 	}
+	return []byte{}
+}
 
-	return []byte(values.Encode()), nil
+// appendFormat appends the encoded output of pairs to b, as format does.
+func (c *Codec) appendFormat(b []byte, pairs []pair) []byte {
+	if !c.preserveOrder {
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return pairs[i].key < pairs[j].key
+		})
+	}
+
+	for i, p := range pairs {
+		if i > 0 {
+			b = append(b, '&')
+		}
+		if p.static {
+			b = append(b, c.escapedKeys.escape(c, p.key)...)
+		} else {
+			b = append(b, c.escapeKey(p.key)...)
+		}
+		b = append(b, '=')
+		b = append(b, c.escapeValue(p.value)...)
+	}
+	return b
 }
 
-func marshalValue(out url.Values, path []string, v reflect.Value) error {
+// escapeKey escapes a key in encoded output.
+func (c *Codec) escapeKey(s string) string {
+	if c.literalPlusKeys {
+		return strings.ReplaceAll(c.escape(s), "+", "%20")
+	}
+	return c.escape(s)
+}
+
+// keyCache holds the escaped forms of the static keys encoded by a [Codec],
+// which name only struct fields and so are escaped once rather than for every
+// value encoded. Readers never lock; the map is replaced as keys are added.
+type keyCache struct {
+	mu   sync.Mutex
+	keys atomic.Pointer[map[string]string]
+}
+
+// escape returns key escaped by c, caching the result. A nil cache escapes
+// key without caching it.
+func (kc *keyCache) escape(c *Codec, key string) string {
+	if kc == nil {
+		return c.escapeKey(key)
+	}
+	if m := kc.keys.Load(); m != nil {
+		if escaped, ok := (*m)[key]; ok {
+			return escaped
+		}
+	}
+
+	escaped := c.escapeKey(key)
+
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	old := kc.keys.Load()
+	m := make(map[string]string, 1)
+	if old != nil {
+		m = make(map[string]string, len(*old)+1)
+		for k, v := range *old {
+			m[k] = v
+		}
+	}
+	m[key] = escaped
+	kc.keys.Store(&m)
+	return escaped
+}
+
+// escapeValue escapes a value in encoded output.
+func (c *Codec) escapeValue(s string) string {
+	if c.literalPlusValues {
+		return strings.ReplaceAll(c.escape(s), "+", "%20")
+	}
+	return c.escape(s)
+}
+
+func (c *Codec) marshalValue(e *encodeState, path []pathSegment, v reflect.Value) error {
+	if e.err != nil {
+		return e.err
+	}
 	// Handle nill pointers early to avoid dereferencing them.
 	if v.Kind() == reflect.Pointer && v.IsNil() {
 		return nil
 	}
 
+	// Values reachable from themselves would otherwise recurse forever.
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			break
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if _, ok := e.visiting[key]; ok {
+			err := &UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+			if len(path) > 0 {
+				err.Key = c.renderer.render(path)
+			}
+			return fmt.Errorf("form: %w", err)
+		}
+		if e.visiting == nil {
+			e.visiting = make(map[visit]struct{})
+		}
+		e.visiting[key] = struct{}{}
+		defer delete(e.visiting, key)
+	}
+
 	// Only deref if we can actually modify the value (it's addressable) or if
 	// it's not nil
 	if v.Kind() == reflect.Pointer {
 		v = v.Elem()
+		if v.Kind() == reflect.Pointer {
+			return c.marshalValue(e, path, v)
+		}
 	}
 
-	// Handle custom Marshaler first.
+	// Handle registered encode functions and custom Marshalers first.
+	if fn, ok := c.encoders[v.Type()]; ok {
+		s, err := fn(v.Interface())
+		if err != nil {
+			return err
+		}
+		c.addLeaf(e, path, s)
+		return nil
+	}
 	if m, ok := asMarshaler(v); ok {
-		return marshaler(out, path, m)
+		return c.marshaler(e, path, m)
+	}
+
+	// Some key syntaxes cannot express nested values, and instead collapse
+	// them into a single value.
+	if col, ok := c.renderer.(collapser); ok && len(path) > 0 {
+		if s, ok, err := col.collapse(path, v); ok || err != nil {
+			if err != nil {
+				return c.encodeError(err, path)
+			}
+			c.addLeaf(e, path, s)
+			return nil
+		}
 	}
 
 	// Dispatch based on the kind of the value.
 	switch v.Kind() {
 	case reflect.Struct:
-		return marshalStruct(out, path, v)
+		return c.marshalStruct(e, path, v)
 	case reflect.Map:
-		return marshalMap(out, path, v)
+		return c.marshalMap(e, path, v)
 	case reflect.Slice, reflect.Array:
-		return marshalSlice(out, path, v)
+		return c.marshalSlice(e, path, v)
 	case reflect.Interface:
 		if !v.IsNil() {
-			return marshalValue(out, path, v.Elem())
+			return c.marshalValue(e, path, v.Elem())
 		}
 		return nil
 	default:
-		return marshalScalar(out, path, v)
+		return c.marshalScalar(e, path, v)
 	}
 }
 
-func marshaler(out url.Values, path []string, m Marshaler) error {
+func (c *Codec) marshaler(e *encodeState, path []pathSegment, m Marshaler) error {
 	s, err := m.MarshalForm()
 	if err != nil {
 		return err
 	}
-	out.Add(renderPath(path), s)
+	c.addLeaf(e, path, s)
 	return nil
 }
 
-func marshalStruct(out url.Values, path []string, v reflect.Value) error {
-	tags := tags(v)
+func (c *Codec) marshalStruct(e *encodeState, path []pathSegment, v reflect.Value) error {
+	tags := c.tags(v)
 	for i := 0; i < v.NumField(); i++ {
 		tag := tags[i]
 		if tag.Ignore {
 			continue
 		}
 		fv := v.Field(i)
-		if tag.Omit && isEmptyValue(fv) {
+		if tag.WriteOnly || omits(tag, fv) || !c.inGroups(tag) {
 			continue
 		}
 		if tag.Name == "" {
 			continue
 		}
-		if err := marshalValue(out, append(path, tag.Name), fv); err != nil {
+		if tag.Err != nil {
+			err := *tag.Err
+			return fmt.Errorf("form: %w", &err)
+		}
+		segs := tag.segments()
+		if tag.KVPairs != "" {
+			if err := c.marshalKV(e, append(path, segs...), tag, fv); err != nil {
+				return c.encodeError(err, append(path, segs...))
+			}
+			continue
+		}
+		if style, explode := c.fieldStyle(tag); style != "" {
+			last := len(segs) - 1
+			if err := c.marshalStyled(e, append(path, segs[:last]...), segs[last].Key, fv, style, explode); err != nil {
+				return c.encodeError(err, append(path, segs...))
+			}
+			continue
+		}
+		if err := c.marshalValue(e, append(path, segs...), fv); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func marshalMap(out url.Values, path []string, v reflect.Value) error {
-	for _, k := range v.MapKeys() {
+func (c *Codec) marshalMap(e *encodeState, path []pathSegment, v reflect.Value) error {
+	// Visit keys in sorted order so output is deterministic even when the
+	// codec preserves the order pairs are produced in.
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	for i, k := range keys {
 		mv := v.MapIndex(k)
 		if !mv.IsValid() || (mv.Kind() == reflect.Interface && mv.IsNil()) {
 			continue
 		}
-		if err := marshalValue(out, append(path, k.String()), mv); err != nil {
+		seg := pathSegment{Key: k.String(), Map: true, Pos: i}
+		// The key of an entry is written as a pair of its own, which is
+		// syntax rather than a value, so is not visited by Walk.
+		if er, ok := c.renderer.(entryRenderer); ok && len(path) > 0 && e.fn == nil {
+			e.pairs = append(e.pairs, pair{key: er.renderEntryKey(append(path, seg)), value: k.String()})
+		}
+		if err := c.marshalValue(e, append(path, seg), mv); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func marshalSlice(out url.Values, path []string, v reflect.Value) error {
+func (c *Codec) marshalSlice(e *encodeState, path []pathSegment, v reflect.Value) error {
 	for i := 0; i < v.Len(); i++ {
 		elem := v.Index(i)
 		if !elem.IsValid() || (elem.Kind() == reflect.Interface && elem.IsNil()) {
 			continue
 		}
-		if err := marshalValue(out, append(path, ""), elem); err != nil {
+		if err := c.marshalValue(e, append(path, pathSegment{Index: true, Pos: i}), elem); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func marshalScalar(out url.Values, path []string, v reflect.Value) error {
-	out.Add(renderPath(path), getScalar(v))
+func (c *Codec) marshalScalar(e *encodeState, path []pathSegment, v reflect.Value) error {
+	s, err := getScalar(v)
+	if err != nil {
+		return c.encodeError(err, path)
+	}
+	c.addLeaf(e, path, s)
 	return nil
 }
 
+// encodeError records the key of path in err when it is an
+// [UnsupportedTypeError] that does not yet identify its key.
+func (c *Codec) encodeError(err error, path []pathSegment) error {
+	var unsupported *UnsupportedTypeError
+	if errors.As(err, &unsupported) && unsupported.Key == "" {
+		unsupported.Key = c.renderer.render(path)
+		return fmt.Errorf("form: %w", err)
+	}
+	return err
+}
+
 func asMarshaler(v reflect.Value) (Marshaler, bool) {
 	if v.CanAddr() {
 		if m, ok := v.Addr().Interface().(Marshaler); ok {
@@ -165,39 +524,86 @@ func asMarshaler(v reflect.Value) (Marshaler, bool) {
 	return nil, false
 }
 
-func renderPath(path []string) string {
-	var b strings.Builder
-	b.WriteString(path[0])
-	for _, p := range path[1:] {
-		if p == "" {
-			b.WriteString("[]")
-		} else {
-			b.WriteString("[")
-			b.WriteString(p)
-			b.WriteString("]")
-		}
-	}
-	return b.String()
-}
-
-func getScalar(v reflect.Value) string {
+// getScalar formats v, returning an [UnsupportedTypeError] if its kind has no
+// form representation.
+func getScalar(v reflect.Value) (string, error) {
 	switch v.Kind() {
 	case reflect.String:
-		return v.String()
+		return v.String(), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.FormatInt(v.Int(), 10)
+		return strconv.FormatInt(v.Int(), 10), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(v.Uint(), 10)
+		return strconv.FormatUint(v.Uint(), 10), nil
 	case reflect.Float32, reflect.Float64:
-		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
 	case reflect.Bool:
-		return strconv.FormatBool(v.Bool())
+		return strconv.FormatBool(v.Bool()), nil
 	default:
-		panic("form: unsupported type: " + v.Type().String())
+		return "", &UnsupportedTypeError{Type: v.Type()}
 	}
 }
 
+// inGroups reports whether a field is encoded given the groups selected by
+// [WithGroups]. Fields without groups are always encoded, as are all fields
+// when no groups are selected.
+func (c *Codec) inGroups(t *tag) bool {
+	if len(c.groups) == 0 || len(t.Groups) == 0 {
+		return true
+	}
+	for _, g := range t.Groups {
+		for _, selected := range c.groups {
+			if g == selected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// omits reports whether the field v is omitted from the output by the
+// omitempty or omitzero flags of its tag.
+func omits(t *tag, v reflect.Value) bool {
+	return (t.Omit && isEmptyValue(v)) || (t.OmitZero && isZeroValue(v))
+}
+
+// isZeroer is implemented by types that define their own zero value, such as
+// [time.Time].
+type isZeroer interface {
+	IsZero() bool
+}
+
+// asZeroer returns the IsZero method of v, if it has one that can be called.
+func asZeroer(v reflect.Value) (isZeroer, bool) {
+	if v.Kind() == reflect.Pointer && v.IsNil() || !v.CanInterface() {
+		return nil, false
+	}
+	if z, ok := v.Interface().(isZeroer); ok {
+		return z, true
+	}
+	if v.CanAddr() {
+		if z, ok := v.Addr().Interface().(isZeroer); ok {
+			return z, true
+		}
+	}
+	return nil, false
+}
+
+// isZeroValue reports whether v is zero, as decided by its IsZero method if it
+// has one.
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := asZeroer(v); ok {
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
+
+// isEmptyValue reports whether v is empty: false, 0, a nil pointer or
+// interface, an empty array, map, slice or string, or a value whose IsZero
+// method reports that it is zero.
 func isEmptyValue(v reflect.Value) bool {
+	if z, ok := asZeroer(v); ok {
+		return z.IsZero()
+	}
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
@@ -148,6 +148,10 @@ func TestMarshal(t *testing.T) {
 			},
 			want: pathEscape("outer[inner]=value"),
 		},
+		"top-level slice": {
+			input: []Person{{Name: "alice"}, {Name: "bob", Age: 30}},
+			want:  pathEscape("0[name]=alice&1[age]=30&1[name]=bob"),
+		},
 		"map with slice values": {
 			input: map[string]interface{}{
 				"items": []string{"a", "b", "c"},
@@ -331,6 +335,138 @@ func TestEncodeToString(t *testing.T) {
 	}
 }
 
+func TestAppendMarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dst     string
+		input   interface{}
+		want    string
+		wantErr bool
+	}{
+		"empty buffer": {
+			input: Person{Name: "john", Age: 20},
+			want:  "age=20&name=john",
+		},
+		"existing payload": {
+			dst:   "token=abc&",
+			input: Person{Name: "john"},
+			want:  "token=abc&name=john",
+		},
+		"nil pointer": {
+			dst:   "token=abc",
+			input: (*Person)(nil),
+			want:  "token=abc",
+		},
+		"invalid input": {
+			dst:     "token=abc",
+			input:   42,
+			want:    "token=abc",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.AppendMarshal([]byte(tt.dst), tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshal_FieldNamer(t *testing.T) {
+	t.Parallel()
+
+	got, err := formenc.Marshal(GeneratedPerson{FirstName: "john", LastName: "smith", Internal: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("first_name=john&surname=smith", string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// Maybe is a string that may be unset, which is zero when unset even if its
+// value is not empty.
+type Maybe struct {
+	Value string
+	Set   bool
+}
+
+func (m Maybe) IsZero() bool {
+	return !m.Set
+}
+
+func (m Maybe) MarshalForm() (string, error) {
+	return m.Value, nil
+}
+
+// Counter is zero when its count is, using a pointer receiver.
+type Counter struct {
+	N int `form:"n"`
+}
+
+func (c *Counter) IsZero() bool {
+	return c.N == 0
+}
+
+func TestMarshal_OmitZero(t *testing.T) {
+	t.Parallel()
+
+	type Profile struct {
+		Nickname Maybe    `form:"nickname,omitempty"`
+		Motto    Maybe    `form:"motto,omitzero"`
+		Visits   Counter  `form:"visits,omitempty"`
+		Score    int      `form:"score,omitzero"`
+		Address  Address  `form:"address,omitzero"`
+		Home     *Address `form:"home,omitzero"`
+	}
+
+	tests := map[string]struct {
+		input Profile
+		want  string
+	}{
+		"zero values": {
+			input: Profile{Nickname: Maybe{Value: "unset"}},
+			want:  "",
+		},
+		"set values": {
+			input: Profile{
+				Nickname: Maybe{Set: true},
+				Motto:    Maybe{Value: "carpe diem", Set: true},
+				Visits:   Counter{N: 2},
+				Score:    3,
+				Address:  Address{City: "Anytown"},
+				Home:     &Address{},
+			},
+			want: "address%5Bcity%5D=Anytown&address%5Bstate%5D=&address%5Bstreet%5D=&address%5Bzip%5D=&" +
+				"home%5Bcity%5D=&home%5Bstate%5D=&home%5Bstreet%5D=&home%5Bzip%5D=&" +
+				"motto=carpe+diem&nickname=&score=3&visits%5Bn%5D=2",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.Marshal(&tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestMarshal_UnsupportedTypes(t *testing.T) {
 	t.Parallel()
 
@@ -441,6 +577,52 @@ func TestMarshal_CustomMarshaler(t *testing.T) {
 	}
 }
 
+func TestMarshal_Appender(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dst     []byte
+		input   interface{}
+		want    []byte
+		wantErr bool
+	}{
+		"appender": {
+			input: &Coordinate{X: 1, Y: 2},
+			want:  []byte("x=1&y=2"),
+		},
+		"existing buffer": {
+			dst:   []byte("a=b&"),
+			input: &Coordinate{X: 1, Y: 2},
+			want:  []byte("a=b&x=1&y=2"),
+		},
+		"nil pointer": {
+			dst:   []byte("a=b"),
+			input: (*Coordinate)(nil),
+			want:  []byte("a=b"),
+		},
+		"error": {
+			dst:     []byte("a=b"),
+			input:   &Coordinate{X: -1},
+			want:    []byte("a=b"),
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.AppendMarshal(tt.dst, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestMarshal_RoundTrip(t *testing.T) {
 	t.Parallel()
 
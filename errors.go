@@ -0,0 +1,314 @@
+package formenc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// InvalidUnmarshalError describes an invalid argument passed to [Unmarshal].
+// (The argument to [Unmarshal] must be a non-nil pointer.)
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "form: Unmarshal(nil)"
+	}
+
+	if e.Type.Kind() != reflect.Pointer {
+		return "form: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "form: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// UnknownFieldError describes a key that does not correspond to any field of
+// the struct it addresses.
+type UnknownFieldError struct {
+	Field string       // the name that matched no field
+	Type  reflect.Type // the struct type
+	Key   string       // the full form key, such as "users[2][nickname]"
+	Path  KeyPath      // the segments of Key
+	Value string       // the form value
+}
+
+func (e *UnknownFieldError) Error() string {
+	s := "unknown field " + strconv.Quote(e.Field) + " in struct " + e.Type.String()
+	if e.Key != "" {
+		s = "cannot unmarshal " + strconv.Quote(e.Value) + " into key " + strconv.Quote(e.Key) + ": " + s
+	}
+	return s
+}
+
+// UnexportedFieldError describes a key that names a struct field that cannot
+// be set, because it is unexported.
+type UnexportedFieldError struct {
+	Field string       // the name of the field
+	Type  reflect.Type // the struct type
+	Key   string       // the full form key, such as "users[2][password]"
+	Path  KeyPath      // the segments of Key
+	Value string       // the form value
+}
+
+func (e *UnexportedFieldError) Error() string {
+	s := "cannot set unexported field " + strconv.Quote(e.Field) + " in struct " + e.Type.String()
+	if e.Key != "" {
+		s = "cannot unmarshal " + strconv.Quote(e.Value) + " into key " + strconv.Quote(e.Key) + ": " + s
+	}
+	return s
+}
+
+// UnmarshalTypeError describes a form value that was not appropriate for a
+// value of a specific Go type.
+type UnmarshalTypeError struct {
+	Value string       // the form value
+	Type  reflect.Type // type of Go value it could not be assigned to
+	Key   string       // the full form key, such as "users[2][age]"
+	Err   error        // the reason the value was rejected, if known
+
+	// Path is the segments of Key. When Key appends the value to a slice, as
+	// "tags[]" or a repeated "tags" does, the final segment holds the
+	// position of the element it was decoded into.
+	Path KeyPath
+
+	// Message is the message given by the errmsg flag of the field's tag, if
+	// any. It replaces the description of the error returned by Error.
+	Message string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	s := "cannot unmarshal " + strconv.Quote(e.Value) + " into Go value of type " + e.Type.String()
+	if e.Key != "" {
+		s = "cannot unmarshal " + strconv.Quote(e.Value) + " into key " + strconv.Quote(e.Key) + " of type " + e.Type.String()
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedTypeError is returned when attempting to encode or decode a value
+// of a type that has no form representation, such as a channel or function.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+	Key  string  // the full form key, if known
+	Path KeyPath // the segments of Key, if known when decoding
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	s := "unsupported type: " + e.Type.String()
+	if e.Key != "" {
+		s += " for key " + strconv.Quote(e.Key)
+	}
+	return s
+}
+
+// UnsupportedValueError is returned when attempting to encode a value that has
+// no form representation, such as one that refers back to itself.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+	Key   string // the full form key, if known
+}
+
+func (e *UnsupportedValueError) Error() string {
+	s := "unsupported value: " + e.Str
+	if e.Key != "" {
+		s += " for key " + strconv.Quote(e.Key)
+	}
+	return s
+}
+
+// SyntaxError describes form data or a key that could not be parsed.
+type SyntaxError struct {
+	Msg string // description of the error
+	Key string // the key being parsed, if known
+	Err error  // the underlying error, if any
+}
+
+func (e *SyntaxError) Error() string {
+	s := e.Msg
+	if e.Key != "" {
+		s += " in key " + strconv.Quote(e.Key)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// LimitExceededError is returned when form data exceeds a limit enforced while
+// decoding, such as the maximum nesting depth of a key.
+type LimitExceededError struct {
+	Limit string  // the name of the limit, such as "depth"
+	Max   int     // the largest value permitted
+	Key   string  // the key that exceeded the limit, if known
+	Path  KeyPath // the segments of Key, if known when decoding
+	Err   error   // the error reporting the limit, such as *http.MaxBytesError, if any
+}
+
+func (e *LimitExceededError) Error() string {
+	s := "exceeded the " + e.Limit + " limit of " + strconv.Itoa(e.Max)
+	if e.Key != "" {
+		s += " in key " + strconv.Quote(e.Key)
+	}
+	return s
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateFieldError describes two keys assigning the same struct field when
+// decoding with [WithStrict].
+type DuplicateFieldError struct {
+	Key      string       // the full form key, such as "user[name]"
+	Path     KeyPath      // the segments of Key
+	Previous string       // the key that first assigned the field
+	Field    string       // the name of the field
+	Type     reflect.Type // the struct type
+}
+
+func (e *DuplicateFieldError) Error() string {
+	return "key " + strconv.Quote(e.Key) + " assigns field " + strconv.Quote(e.Field) + " in struct " + e.Type.String() +
+		" already assigned by key " + strconv.Quote(e.Previous)
+}
+
+// MissingFieldError describes a struct field tagged required that no key
+// assigned, when decoding with [WithRequired].
+type MissingFieldError struct {
+	Key   string       // the form key expected, such as "user[email]"
+	Path  KeyPath      // the segments of Key
+	Field string       // the name of the field
+	Type  reflect.Type // the struct type
+}
+
+func (e *MissingFieldError) Error() string {
+	return "missing key " + strconv.Quote(e.Key) + " for required field " + strconv.Quote(e.Field) + " in struct " + e.Type.String()
+}
+
+// RoundTripError describes a value that changed when encoded and decoded, or
+// decoded and encoded, with [WithRoundTripCheck].
+type RoundTripError struct {
+	Key  string // the form key at which the values differ
+	Want string // the original value
+	Got  string // the value after the round trip
+}
+
+func (e *RoundTripError) Error() string {
+	return "round trip changed key " + strconv.Quote(e.Key) + " from " + e.Want + " to " + e.Got
+}
+
+// DiscriminatorError describes form data decoded with
+// [Codec.UnmarshalDiscriminated] whose discriminator key is missing or selects
+// no type. See [WithDiscriminator].
+type DiscriminatorError struct {
+	Key   string // the discriminator key
+	Value string // the value of the key, empty if it is missing
+}
+
+func (e *DiscriminatorError) Error() string {
+	if e.Value == "" {
+		return "missing discriminator key " + strconv.Quote(e.Key)
+	}
+	return "unknown value " + strconv.Quote(e.Value) + " for discriminator key " + strconv.Quote(e.Key)
+}
+
+// TagError describes a struct tag flag that could not be parsed. It is
+// returned whenever the field is encoded or decoded.
+type TagError struct {
+	Type  reflect.Type // the struct type
+	Field string       // the Go name of the field
+	Flag  string       // the flag, such as "kv=;"
+	Err   error        // the reason the flag was rejected
+}
+
+func (e *TagError) Error() string {
+	return "invalid tag flag " + strconv.Quote(e.Flag) + " on field " + e.Field + " of " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *TagError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError describes a decoded value that failed validation. See
+// [WithValidation].
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the field-level errors encountered while decoding with
+// [WithAggregateErrors], in the order the failing keys appeared.
+type MultiError struct {
+	Errors []error
+
+	// keys holds the form key of each error in Errors.
+	keys []string
+}
+
+func (e *MultiError) add(key string, err error) {
+	e.Errors = append(e.Errors, err)
+	e.keys = append(e.keys, key)
+}
+
+func (e *MultiError) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		return "form: no errors"
+	case 1:
+		return "form: " + e.Errors[0].Error()
+	case 2:
+		return "form: " + e.Errors[0].Error() + " (and 1 other error)"
+	default:
+		return "form: " + e.Errors[0].Error() + " (and " + strconv.Itoa(len(e.Errors)-1) + " other errors)"
+	}
+}
+
+// Unwrap returns the collected errors, so [errors.Is] and [errors.As] match
+// any one of them.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// Fields maps the form key of each failing field, such as "users[2][age]", to
+// a message describing the failure. Only the first failure of a repeated key
+// is included.
+func (e *MultiError) Fields() map[string]string {
+	fields := make(map[string]string, len(e.Errors))
+	for i, err := range e.Errors {
+		var key string
+		if i < len(e.keys) {
+			key = e.keys[i]
+		}
+		if _, ok := fields[key]; !ok {
+			fields[key] = err.Error()
+		}
+	}
+	return fields
+}
+
+// MarshalJSON encodes the failures as a JSON object mapping form keys to
+// messages, as returned by [MultiError.Fields].
+func (e *MultiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Fields())
+}
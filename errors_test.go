@@ -0,0 +1,376 @@
+package formenc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Registration struct {
+	Age      int           `form:"age,errmsg=age must be a whole number"`
+	Scores   []int         `form:"scores,errmsg=scores must be whole numbers, such as 7"`
+	Guardian *Registration `form:"guardian,errmsg=guardian is invalid"`
+}
+
+func TestUnmarshal_UnmarshalTypeError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		target  interface{}
+		want    formenc.UnmarshalTypeError
+		wantMsg string
+	}{
+		"invalid integer": {
+			input:   "name=john&age=old",
+			target:  &Person{},
+			want:    formenc.UnmarshalTypeError{Value: "old", Type: reflect.TypeOf(0), Key: "age", Path: mustPath("age")},
+			wantMsg: `form: cannot unmarshal "old" into key "age" of type int: invalid syntax`,
+		},
+		"nested integer": {
+			input:   "address[zip]=x",
+			target:  &map[string]map[string]int{},
+			want:    formenc.UnmarshalTypeError{Value: "x", Type: reflect.TypeOf(0), Key: "address[zip]", Path: mustPath("address[zip]")},
+			wantMsg: `form: cannot unmarshal "x" into key "address[zip]" of type int: invalid syntax`,
+		},
+		"out of range": {
+			input:   "v=300",
+			target:  &map[string]uint8{},
+			want:    formenc.UnmarshalTypeError{Value: "300", Type: reflect.TypeOf(uint8(0)), Key: "v", Path: mustPath("v")},
+			wantMsg: `form: cannot unmarshal "300" into key "v" of type uint8: value out of range`,
+		},
+		"nested key below scalar": {
+			input:   "name[first]=john",
+			target:  &Person{},
+			want:    formenc.UnmarshalTypeError{Value: "john", Type: reflect.TypeOf(""), Key: "name[first]", Path: mustPath("name[first]")},
+			wantMsg: `form: cannot unmarshal "john" into key "name[first]" of type string: value cannot have nested keys`,
+		},
+		"rejected by unmarshaler": {
+			input:   "created_at=yesterday",
+			target:  &ComplexPerson{},
+			want:    formenc.UnmarshalTypeError{Value: "yesterday", Type: reflect.TypeOf(MyDate{}), Key: "created_at", Path: mustPath("created_at")},
+			wantMsg: `form: cannot unmarshal "yesterday" into key "created_at" of type formenc_test.MyDate: parsing time "yesterday" as "2006.01.02": cannot parse "yesterday" as "2006"`,
+		},
+		"conflicting interface values": {
+			input:   "a=1&a[b]=2",
+			target:  &map[string]interface{}{},
+			want:    formenc.UnmarshalTypeError{Value: "2", Type: reflect.TypeOf(""), Key: "a[b]", Path: mustPath("a[b]")},
+			wantMsg: `form: cannot unmarshal "2" into key "a[b]" of type string: existing value is not a map`,
+		},
+		"custom message": {
+			input:   "age=old",
+			target:  &Registration{},
+			want:    formenc.UnmarshalTypeError{Value: "old", Type: reflect.TypeOf(0), Key: "age", Path: mustPath("age"), Message: "age must be a whole number"},
+			wantMsg: `form: age must be a whole number`,
+		},
+		"custom message with commas": {
+			input:   "scores[]=1&scores[]=x",
+			target:  &Registration{},
+			want:    formenc.UnmarshalTypeError{Value: "x", Type: reflect.TypeOf(0), Key: "scores[]", Path: mustPath("scores").AppendIndex(1), Message: "scores must be whole numbers, such as 7"},
+			wantMsg: `form: scores must be whole numbers, such as 7`,
+		},
+		"repeated key": {
+			input:   "scores=1&scores=2&scores=x",
+			target:  &Registration{},
+			want:    formenc.UnmarshalTypeError{Value: "x", Type: reflect.TypeOf(0), Key: "scores", Path: mustPath("scores").AppendIndex(2), Message: "scores must be whole numbers, such as 7"},
+			wantMsg: `form: scores must be whole numbers, such as 7`,
+		},
+		"nested custom message": {
+			input:   "guardian[age]=old",
+			target:  &Registration{},
+			want:    formenc.UnmarshalTypeError{Value: "old", Type: reflect.TypeOf(0), Key: "guardian[age]", Path: mustPath("guardian[age]"), Message: "age must be a whole number"},
+			wantMsg: `form: age must be a whole number`,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := formenc.DecodeString(tt.input, tt.target)
+
+			var typeErr *formenc.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("expected UnmarshalTypeError, got: %v", err)
+			}
+
+			opts := cmp.Comparer(func(a, b reflect.Type) bool { return a == b })
+			got := *typeErr
+			got.Err = nil
+			if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantMsg, err.Error()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_SyntaxError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		wantKey string
+	}{
+		"invalid escape": {
+			input: "name=%zz",
+		},
+		"unterminated bracket": {
+			input:   "address[city=x",
+			wantKey: "address[city",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := formenc.DecodeString(tt.input, &ComplexPerson{})
+
+			var syntaxErr *formenc.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected SyntaxError, got: %v", err)
+			}
+			if syntaxErr.Key != tt.wantKey {
+				t.Errorf("expected key %q, got %q", tt.wantKey, syntaxErr.Key)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_UnsupportedTypeError(t *testing.T) {
+	t.Parallel()
+
+	type Target struct {
+		Done chan bool `form:"done"`
+	}
+
+	err := formenc.DecodeString("done=true", &Target{})
+
+	var unsupportedErr *formenc.UnsupportedTypeError
+	if !errors.As(err, &unsupportedErr) {
+		t.Fatalf("expected UnsupportedTypeError, got: %v", err)
+	}
+	if unsupportedErr.Key != "done" {
+		t.Errorf("expected key %q, got %q", "done", unsupportedErr.Key)
+	}
+}
+
+func TestUnmarshal_LimitExceededError(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithRackCompat())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := "a"
+	for i := 0; i < 40; i++ {
+		key += "[a]"
+	}
+
+	var got map[string]interface{}
+	err = codec.Unmarshal([]byte(key+"=1"), &got)
+
+	var limitErr *formenc.LimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected LimitExceededError, got: %v", err)
+	}
+	if limitErr.Max != 32 {
+		t.Errorf("expected limit 32, got %d", limitErr.Max)
+	}
+}
+
+func TestUnmarshal_MultiError(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithAggregateErrors())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got User
+	err = codec.Unmarshal([]byte("name=john&age=old&address[planet]=mars&address[city]=Paris&age=older"), &got)
+
+	var multi *formenc.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, got: %v", err)
+	}
+
+	wantFields := map[string]string{
+		"age":             `cannot unmarshal "old" into key "age" of type int: invalid syntax`,
+		"address[planet]": `cannot unmarshal "mars" into key "address[planet]": unknown field "planet" in struct formenc_test.Address`,
+	}
+	if diff := cmp.Diff(wantFields, multi.Fields()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	wantMsg := `form: cannot unmarshal "old" into key "age" of type int: invalid syntax (and 2 other errors)`
+	if diff := cmp.Diff(wantMsg, err.Error()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// Keys that decoded successfully are still assigned.
+	want := User{Name: "john", Address: Address{City: "Paris"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var unknown *formenc.UnknownFieldError
+	if !errors.As(err, &unknown) {
+		t.Error("expected MultiError to unwrap to UnknownFieldError")
+	}
+
+	b, err := json.Marshal(multi)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(wantFields, decoded); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshal_UnsupportedTypeError(t *testing.T) {
+	t.Parallel()
+
+	type Pointer struct {
+		Addr uintptr `form:"addr"`
+	}
+	type Nested struct {
+		Meta map[string]interface{} `form:"meta"`
+	}
+	type Callback struct {
+		Items []interface{} `form:"items"`
+	}
+
+	tests := map[string]struct {
+		input   interface{}
+		wantKey string
+		wantMsg string
+	}{
+		"uintptr": {
+			input:   Pointer{Addr: 1},
+			wantKey: "addr",
+			wantMsg: `form: unsupported type: uintptr for key "addr"`,
+		},
+		"chan inside nested interfaces": {
+			input: Nested{Meta: map[string]interface{}{
+				"inner": map[string]interface{}{"done": make(chan bool)},
+			}},
+			wantKey: "meta[inner][done]",
+			wantMsg: `form: unsupported type: chan bool for key "meta[inner][done]"`,
+		},
+		"func in slice": {
+			input:   Callback{Items: []interface{}{"a", func() {}}},
+			wantKey: "items[]",
+			wantMsg: `form: unsupported type: func() for key "items[]"`,
+		},
+		"complex": {
+			input:   map[string]interface{}{"z": complex(1, 2)},
+			wantKey: "z",
+			wantMsg: `form: unsupported type: complex128 for key "z"`,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := formenc.Marshal(tt.input)
+
+			var unsupportedErr *formenc.UnsupportedTypeError
+			if !errors.As(err, &unsupportedErr) {
+				t.Fatalf("expected UnsupportedTypeError, got: %v", err)
+			}
+			if unsupportedErr.Key != tt.wantKey {
+				t.Errorf("expected key %q, got %q", tt.wantKey, unsupportedErr.Key)
+			}
+			if diff := cmp.Diff(tt.wantMsg, err.Error()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type Node struct {
+	Name string `form:"name"`
+	Next *Node  `form:"next,omitempty"`
+}
+
+func TestMarshal_UnsupportedValueError(t *testing.T) {
+	t.Parallel()
+
+	node := &Node{Name: "a"}
+	node.Next = &Node{Name: "b", Next: node}
+
+	loop := map[string]interface{}{"name": "a"}
+	loop["self"] = loop
+
+	list := []interface{}{"a", nil}
+	list[1] = list
+
+	tests := map[string]struct {
+		input   interface{}
+		wantKey string
+	}{
+		"pointer cycle": {
+			input:   node,
+			wantKey: "next[next][next]",
+		},
+		"map cycle": {
+			input:   map[string]interface{}{"loop": loop},
+			wantKey: "loop[self]",
+		},
+		"slice cycle": {
+			input:   map[string]interface{}{"list": list},
+			wantKey: "list[]",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := formenc.Marshal(tt.input)
+
+			var valueErr *formenc.UnsupportedValueError
+			if !errors.As(err, &valueErr) {
+				t.Fatalf("expected UnsupportedValueError, got: %v", err)
+			}
+			if valueErr.Key != tt.wantKey {
+				t.Errorf("expected key %q, got %q", tt.wantKey, valueErr.Key)
+			}
+		})
+	}
+}
+
+func TestMarshal_SharedValues(t *testing.T) {
+	t.Parallel()
+
+	// Values referenced more than once, but not from themselves, are not
+	// cycles.
+	shared := &Address{City: "Paris"}
+	input := map[string]interface{}{"home": shared, "work": shared}
+
+	got, err := formenc.EncodeToString(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "home%5Bcity%5D=Paris&home%5Bstate%5D=&home%5Bstreet%5D=&home%5Bzip%5D=" +
+		"&work%5Bcity%5D=Paris&work%5Bstate%5D=&work%5Bstreet%5D=&work%5Bzip%5D="
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
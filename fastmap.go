@@ -0,0 +1,94 @@
+package formenc
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	stringMapType    = reflect.TypeOf(map[string]string(nil))
+	stringsMapType   = reflect.TypeOf(map[string][]string(nil))
+	interfaceMapType = reflect.TypeOf(map[string]interface{}(nil))
+	stringType       = reflect.TypeOf("")
+	interfaceType    = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// mapAssigner assigns the value of an entry to a map without reflection,
+// reporting false if the entry must be assigned by reflection instead.
+type mapAssigner func(e entry) (bool, error)
+
+// mapFastPath returns a mapAssigner for v when it is one of the map types most
+// often decoded into, so that their entries are assigned directly rather than
+// by reflection. It returns nil for other types, and for those whose values
+// are decoded by a function registered with c. A nil map is allocated with
+// room for n entries.
+//
+// Entries with nested keys are left to reflection, which shares the map and so
+// decodes them as before.
+func (c *Codec) mapFastPath(v reflect.Value, n int) mapAssigner {
+	if c.plan != nil || !v.CanAddr() {
+		return nil
+	}
+
+	switch v.Type() {
+	case stringMapType:
+		if c.decoders[stringType] != nil {
+			return nil
+		}
+		m := v.Addr().Interface().(*map[string]string)
+		if *m == nil {
+			*m = make(map[string]string, n)
+		}
+		return func(e entry) (bool, error) {
+			if len(e.path) != 1 || e.path[0].Index {
+				return false, nil
+			}
+			(*m)[e.path[0].Key] = e.value
+			return true, nil
+		}
+
+	case stringsMapType:
+		if c.decoders[stringType] != nil || c.decoders[stringsMapType.Elem()] != nil {
+			return nil
+		}
+		m := v.Addr().Interface().(*map[string][]string)
+		if *m == nil {
+			*m = make(map[string][]string, n)
+		}
+		// Every value is appended to the slice named by the first segment
+		// of its key, whatever follows it.
+		return func(e entry) (bool, error) {
+			if len(e.path) == 0 {
+				return false, nil
+			}
+			key := e.path[0].Key
+			values := (*m)[key]
+			if err := c.checkSliceLength(len(values) + 1); err != nil {
+				return true, err
+			}
+			(*m)[key] = append(values, e.value)
+			return true, nil
+		}
+
+	case interfaceMapType:
+		if c.decoders[interfaceType] != nil {
+			return nil
+		}
+		m := v.Addr().Interface().(*map[string]interface{})
+		if *m == nil {
+			*m = make(map[string]interface{}, n)
+		}
+		return func(e entry) (bool, error) {
+			if len(e.path) != 1 || e.path[0].Index {
+				return false, nil
+			}
+			if c.useNumber && isNumber(e.value) {
+				(*m)[e.path[0].Key] = json.Number(e.value)
+			} else {
+				(*m)[e.path[0].Key] = e.value
+			}
+			return true, nil
+		}
+	}
+	return nil
+}
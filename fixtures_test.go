@@ -1,6 +1,9 @@
 package formenc_test
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -64,3 +67,42 @@ func (d *MyDate) UnmarshalForm(b string) error {
 	*d = MyDate(t)
 	return nil
 }
+
+// GeneratedPerson names its fields with FormFieldName, as a generated type
+// without struct tags might.
+type GeneratedPerson struct {
+	FirstName string
+	LastName  string `form:"surname"`
+	Internal  string
+}
+
+func (GeneratedPerson) FormFieldName(field string) string {
+	switch field {
+	case "FirstName":
+		return "first_name"
+	case "Internal":
+		return "-"
+	}
+	return ""
+}
+
+// Coordinate encodes and decodes itself without reflection.
+type Coordinate struct {
+	X int `form:"x"`
+	Y int `form:"y"`
+}
+
+func (p *Coordinate) AppendForm(dst []byte) ([]byte, error) {
+	if p.X < 0 || p.Y < 0 {
+		return dst, errors.New("negative coordinate")
+	}
+	dst = append(dst, "x="...)
+	dst = strconv.AppendInt(dst, int64(p.X), 10)
+	dst = append(dst, "&y="...)
+	return strconv.AppendInt(dst, int64(p.Y), 10), nil
+}
+
+func (p *Coordinate) UnmarshalFormData(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "x=%d&y=%d", &p.X, &p.Y)
+	return err
+}
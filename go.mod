@@ -2,4 +2,7 @@ module github.com/tomasbasham/formenc
 
 go 1.21
 
-require github.com/google/go-cmp v0.7.0
+require (
+	github.com/google/go-cmp v0.7.0
+	golang.org/x/text v0.22.0
+)
@@ -0,0 +1,136 @@
+package formenc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithGRPCGatewayCompat configures a [Codec] to follow the query parameter
+// rules of grpc-gateway, so that REST façades over protobuf services accept
+// the same URLs as the gateway.
+//
+// In this mode nested message fields are joined with dots, as in
+// "book.author.name=Ann", map entries are bracketed, as in "labels[env]=prod",
+// and repeated fields repeat their key, as in "ids=1&ids=2". Repeated fields
+// of messages are not supported, as they are not by grpc-gateway.
+//
+// Well-known types use their protobuf JSON mapping: values of type [time.Time]
+// are read and written as RFC 3339 timestamps, values of type [time.Duration]
+// as seconds with the suffix "s", as in "1.5s", and a [FieldMask] as comma
+// separated paths. Booleans are read only as "true" or "false".
+func WithGRPCGatewayCompat() Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithGRPCGatewayCompat", true, true); err != nil {
+			return err
+		}
+		c.parser = playgroundParser{}
+		c.renderer = grpcGatewayRenderer{}
+
+		opts := []Option{
+			WithDecodeFunc(time.Time{}, grpcGatewayParseTimestamp),
+			WithEncodeFunc(time.Time{}, grpcGatewayFormatTimestamp),
+			WithDecodeFunc(time.Duration(0), grpcGatewayParseDuration),
+			WithEncodeFunc(time.Duration(0), grpcGatewayFormatDuration),
+			WithDecodeFunc(false, stripeParseBool),
+		}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FieldMask is a set of field paths, such as "name" or "address.city",
+// selecting the fields changed by an update. It is encoded as comma separated
+// paths, as google.protobuf.FieldMask is in query parameters.
+type FieldMask struct {
+	Paths []string
+}
+
+// MarshalForm implements [Marshaler].
+func (m FieldMask) MarshalForm() (string, error) {
+	return strings.Join(m.Paths, ","), nil
+}
+
+// UnmarshalForm implements [Unmarshaler].
+func (m *FieldMask) UnmarshalForm(s string) error {
+	m.Paths = nil
+	if s == "" {
+		return nil
+	}
+	m.Paths = strings.Split(s, ",")
+	return nil
+}
+
+// grpcGatewayRenderer produces keys such as "book.author.name" and
+// "labels[env]", writing array elements as repeated keys.
+type grpcGatewayRenderer struct{}
+
+func (grpcGatewayRenderer) render(path []pathSegment) string {
+	var b strings.Builder
+	b.WriteString(path[0].Key)
+	for _, seg := range path[1:] {
+		switch {
+		case seg.Index:
+			// Repeated fields repeat the key.
+		case seg.Map:
+			b.WriteString("[")
+			b.WriteString(seg.Key)
+			b.WriteString("]")
+		default:
+			b.WriteString(".")
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
+
+func grpcGatewayParseTimestamp(s string) (interface{}, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func grpcGatewayFormatTimestamp(v interface{}) (string, error) {
+	return v.(time.Time).UTC().Format(time.RFC3339Nano), nil
+}
+
+func grpcGatewayParseDuration(s string) (interface{}, error) {
+	if s == "" {
+		return time.Duration(0), nil
+	}
+	secs, ok := strings.CutSuffix(s, "s")
+	if !ok || strings.Trim(secs, "-.0123456789") != "" {
+		return nil, errors.New("duration must be seconds with the suffix \"s\"")
+	}
+	return time.ParseDuration(s)
+}
+
+// grpcGatewayFormatDuration writes a duration as seconds with 0, 3, 6 or 9
+// fractional digits, as the protobuf JSON mapping does.
+func grpcGatewayFormatDuration(v interface{}) (string, error) {
+	d := v.(time.Duration)
+	sign := ""
+	if d < 0 {
+		sign = "-"
+	}
+	secs, nanos := int64(d/time.Second), int64(d%time.Second)
+	if d < 0 {
+		secs, nanos = -secs, -nanos
+	}
+	s := sign + strconv.FormatInt(secs, 10)
+	if nanos == 0 {
+		return s + "s", nil
+	}
+
+	frac := strconv.FormatInt(nanos+int64(time.Second), 10)[1:]
+	for len(frac) > 3 && strings.HasSuffix(frac, "000") {
+		frac = frac[:len(frac)-3]
+	}
+	return s + "." + frac + "s", nil
+}
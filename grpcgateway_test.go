@@ -0,0 +1,124 @@
+package formenc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type GatewayAuthor struct {
+	Name string `form:"name"`
+}
+
+type GatewayBook struct {
+	Title  string        `form:"title"`
+	Author GatewayAuthor `form:"author"`
+}
+
+type GatewayUpdateBook struct {
+	Book       GatewayBook       `form:"book"`
+	IDs        []int             `form:"ids"`
+	Labels     map[string]string `form:"labels"`
+	UpdateMask formenc.FieldMask `form:"update_mask"`
+	Published  time.Time         `form:"published"`
+	Timeout    time.Duration     `form:"timeout"`
+	Dry        bool              `form:"dry"`
+}
+
+func TestCodec_GRPCGatewayCompat(t *testing.T) {
+	t.Parallel()
+
+	req := GatewayUpdateBook{
+		Book:       GatewayBook{Title: "Dune", Author: GatewayAuthor{Name: "Herbert"}},
+		IDs:        []int{1, 2},
+		Labels:     map[string]string{"env": "prod"},
+		UpdateMask: formenc.FieldMask{Paths: []string{"book.title", "book.author.name"}},
+		Published:  time.Date(2025, 2, 8, 10, 0, 0, 500000000, time.UTC),
+		Timeout:    1500 * time.Millisecond,
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithGRPCGatewayCompat())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := codec.Marshal(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "book.author.name=Herbert&book.title=Dune&dry=false&ids=1&ids=2&labels%5Benv%5D=prod" +
+		"&published=2025-02-08T10%3A00%3A00.5Z&timeout=1.500s&update_mask=book.title%2Cbook.author.name"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var decoded GatewayUpdateBook
+	if err := codec.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(req, decoded); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestCodec_GRPCGatewayCompat_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    GatewayUpdateBook
+		wantErr bool
+	}{
+		"offset timestamp": {
+			input: "published=2025-02-08T12:00:00%2B02:00",
+			want:  GatewayUpdateBook{Published: time.Date(2025, 2, 8, 10, 0, 0, 0, time.UTC)},
+		},
+		"negative duration": {
+			input: "timeout=-0.5s",
+			want:  GatewayUpdateBook{Timeout: -500 * time.Millisecond},
+		},
+		"duration with other unit": {
+			input:   "timeout=1m",
+			wantErr: true,
+		},
+		"boolean other than true or false": {
+			input:   "dry=yes",
+			wantErr: true,
+		},
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithGRPCGatewayCompat())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got GatewayUpdateBook
+			err := codec.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Published.Equal(tt.want.Published) {
+				t.Errorf("expected published %v, got %v", tt.want.Published, got.Published)
+			}
+			got.Published = tt.want.Published
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
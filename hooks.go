@@ -0,0 +1,169 @@
+package formenc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Hooks are callbacks invoked by a [Codec] as it encodes and decodes, so that
+// metrics and traces can be recorded for every call. Any of them may be nil.
+// Hooks are called synchronously and should return quickly. Each receives the
+// context passed to [Codec.UnmarshalContext] or [Codec.MarshalContext], or
+// [context.Background] for calls without one.
+type Hooks struct {
+	// OnDecodeStart is called before decoding, with the size of the input in
+	// bytes.
+	OnDecodeStart func(ctx context.Context, size int)
+
+	// OnDecodeDone is called once decoding finishes, whether or not it
+	// succeeded.
+	OnDecodeDone func(ctx context.Context, stats DecodeStats)
+
+	// OnEncodeStart is called before encoding.
+	OnEncodeStart func(ctx context.Context)
+
+	// OnEncodeDone is called once encoding finishes, whether or not it
+	// succeeded.
+	OnEncodeDone func(ctx context.Context, stats EncodeStats)
+
+	// OnFieldError is called for each key that fails to decode, with the key
+	// and the error describing the failure. With [WithAggregateErrors] it may
+	// be called several times in one decode.
+	OnFieldError func(ctx context.Context, key string, err error)
+
+	// OnKeyDropped is called for each key that is skipped without error, with
+	// the key and the reason it was skipped. Keys are only skipped for
+	// unknown or ignored fields with [WithIgnoreUnknownKeys], for read-only
+	// fields, or when a limit of the key syntax, such as the parameter limit
+	// of [WithQSCompat], is reached.
+	OnKeyDropped func(ctx context.Context, key string, reason DropReason)
+
+	// OnDeprecatedKey is called for each key that names a field by a
+	// deprecated name, with the key and the current name of the field.
+	OnDeprecatedKey func(ctx context.Context, key, name string)
+}
+
+// DropReason is the reason a key was skipped while decoding.
+type DropReason int
+
+const (
+	// DropUnknownField is a key that does not correspond to any struct field.
+	DropUnknownField DropReason = iota
+
+	// DropIgnoredField is a key naming a struct field that is ignored, either
+	// by its tag or because it is unexported.
+	DropIgnoredField
+
+	// DropLimitExceeded is a key beyond a limit of the key syntax.
+	DropLimitExceeded
+
+	// DropReadOnlyField is a key naming a struct field tagged readonly, which
+	// is encoded but never decoded.
+	DropReadOnlyField
+)
+
+// String returns a description of the reason.
+func (r DropReason) String() string {
+	switch r {
+	case DropUnknownField:
+		return "unknown field"
+	case DropIgnoredField:
+		return "ignored field"
+	case DropLimitExceeded:
+		return "limit exceeded"
+	case DropReadOnlyField:
+		return "read-only field"
+	}
+	return fmt.Sprintf("DropReason(%d)", int(r))
+}
+
+// DecodeStats describes a completed decode.
+type DecodeStats struct {
+	Bytes    int           // the size of the input in bytes
+	Keys     int           // the number of keys parsed from the input
+	Duration time.Duration // the time taken to decode
+	Err      error         // the error returned, if any
+}
+
+// EncodeStats describes a completed encode.
+type EncodeStats struct {
+	Bytes    int           // the size of the output in bytes
+	Keys     int           // the number of keys written
+	Duration time.Duration // the time taken to encode
+	Err      error         // the error returned, if any
+}
+
+// WithHooks configures a [Codec] to call h as it encodes and decodes.
+func WithHooks(h Hooks) Option {
+	return func(c *Codec) error {
+		c.hooks = h
+		return nil
+	}
+}
+
+// dropKey reports that key was skipped for reason to the OnKeyDropped hook, if
+// any.
+func (c *Codec) dropKey(key string, reason DropReason) {
+	if c.hooks.OnKeyDropped != nil {
+		c.hooks.OnKeyDropped(c.context(), key, reason)
+	}
+}
+
+// deprecateKey reports that the key being assigned names a field by a
+// deprecated name, name being its current one, to the metadata and the
+// OnDeprecatedKey hook, if any.
+func (c *Codec) deprecateKey(name string) {
+	if c.metadata != nil {
+		c.metadata.Deprecated = append(c.metadata.Deprecated, DeprecatedKey{Key: c.key, Path: keyPath(c.path), Name: name})
+	}
+	if c.hooks.OnDeprecatedKey != nil {
+		c.hooks.OnDeprecatedKey(c.context(), c.key, name)
+	}
+}
+
+// observesDecode reports whether c has hooks to call around a decode that is
+// not already being observed.
+func (c *Codec) observesDecode() bool {
+	return c.decodeStats == nil && (c.hooks.OnDecodeStart != nil || c.hooks.OnDecodeDone != nil)
+}
+
+// observeUnmarshal decodes data into v as unmarshal does, calling the decode
+// hooks of c around it.
+func (c *Codec) observeUnmarshal(data []byte, v interface{}) error {
+	stats := &DecodeStats{Bytes: len(data)}
+	if c.hooks.OnDecodeStart != nil {
+		c.hooks.OnDecodeStart(c.context(), stats.Bytes)
+	}
+
+	d := *c
+	d.decodeStats = stats
+	start := time.Now()
+	err := d.unmarshal(data, v)
+	stats.Duration, stats.Err = time.Since(start), err
+
+	if c.hooks.OnDecodeDone != nil {
+		c.hooks.OnDecodeDone(c.context(), *stats)
+	}
+	return err
+}
+
+// observeMarshal appends the encoding of v to dst as appendMarshal does,
+// calling the encode hooks of c around it.
+func (c *Codec) observeMarshal(dst []byte, v interface{}) ([]byte, error) {
+	if c.hooks.OnEncodeStart != nil {
+		c.hooks.OnEncodeStart(c.context())
+	}
+
+	start := time.Now()
+	data, keys, err := c.encode(dst, v)
+	if c.hooks.OnEncodeDone != nil {
+		c.hooks.OnEncodeDone(c.context(), EncodeStats{
+			Bytes:    len(data) - len(dst),
+			Keys:     keys,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+	return data, err
+}
@@ -0,0 +1,225 @@
+package formenc_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithHooks_Decode(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		starts []int
+		done   []formenc.DecodeStats
+		failed []string
+	)
+	codec, err := formenc.NewCodec(
+		formenc.WithAggregateErrors(),
+		formenc.WithHooks(formenc.Hooks{
+			OnDecodeStart: func(_ context.Context, size int) {
+				mu.Lock()
+				defer mu.Unlock()
+				starts = append(starts, size)
+			},
+			OnDecodeDone: func(_ context.Context, s formenc.DecodeStats) {
+				mu.Lock()
+				defer mu.Unlock()
+				done = append(done, s)
+			},
+			OnFieldError: func(_ context.Context, key string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				failed = append(failed, key)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var user User
+	if err := codec.Unmarshal([]byte("name=alice&address[city]=Leeds"), &user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodeErr := codec.Unmarshal([]byte("name=bob&age=old&address=x"), &user)
+	if decodeErr == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if diff := cmp.Diff([]int{30, 26}, starts); diff != "" {
+		t.Errorf("start sizes mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"age", "address"}, failed); diff != "" {
+		t.Errorf("field errors mismatch (-want +got):\n%s", diff)
+	}
+	if len(done) != 2 {
+		t.Fatalf("expected 2 completed decodes, got %d", len(done))
+	}
+	if done[0].Bytes != 30 || done[0].Keys != 2 || done[0].Err != nil || done[0].Duration <= 0 {
+		t.Errorf("unexpected stats for first decode: %+v", done[0])
+	}
+	if done[1].Keys != 3 || !errors.Is(done[1].Err, decodeErr) {
+		t.Errorf("unexpected stats for second decode: %+v", done[1])
+	}
+}
+
+func TestWithHooks_Encode(t *testing.T) {
+	t.Parallel()
+
+	var started bool
+	var stats formenc.EncodeStats
+	codec, err := formenc.NewCodec(formenc.WithHooks(formenc.Hooks{
+		OnEncodeStart: func(context.Context) { started = true },
+		OnEncodeDone:  func(_ context.Context, s formenc.EncodeStats) { stats = s },
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := codec.Marshal(Person{Name: "alice", Age: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !started {
+		t.Error("expected OnEncodeStart to be called")
+	}
+	if stats.Bytes != len(data) || stats.Keys != 2 || stats.Err != nil {
+		t.Errorf("unexpected stats: %+v for %q", stats, data)
+	}
+}
+
+func TestWithHooks_KeyDropped(t *testing.T) {
+	t.Parallel()
+
+	type Account struct {
+		Name     string `form:"name"`
+		Password string `form:"-"`
+		Owner    User   `form:"owner"`
+		internal string
+	}
+
+	type dropped struct {
+		Key    string
+		Reason formenc.DropReason
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input string
+		want  []dropped
+	}{
+		"unknown and ignored fields": {
+			opts:  []formenc.Option{formenc.WithIgnoreUnknownKeys()},
+			input: "name=alice&Password=secret&internal=x&owner[nickname]=al&colour=red",
+			want: []dropped{
+				{Key: "Password", Reason: formenc.DropIgnoredField},
+				{Key: "internal", Reason: formenc.DropIgnoredField},
+				{Key: "owner[nickname]", Reason: formenc.DropUnknownField},
+				{Key: "colour", Reason: formenc.DropUnknownField},
+			},
+		},
+		"strict": {
+			opts:  []formenc.Option{formenc.WithIgnoreUnknownKeys(), formenc.WithStrict()},
+			input: "colour=red&name=alice",
+			want:  []dropped{{Key: "colour", Reason: formenc.DropUnknownField}},
+		},
+		"parameter limit": {
+			opts: []formenc.Option{
+				formenc.WithQSCompat(formenc.QSOptions{ParameterLimit: 1}),
+				formenc.WithIgnoreUnknownKeys(),
+			},
+			input: "name=alice&owner[name]=bob",
+			want:  []dropped{{Key: "owner[name]", Reason: formenc.DropLimitExceeded}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []dropped
+			opts := append(tt.opts, formenc.WithHooks(formenc.Hooks{
+				OnKeyDropped: func(_ context.Context, key string, reason formenc.DropReason) {
+					got = append(got, dropped{Key: key, Reason: reason})
+				},
+			}))
+			codec, err := formenc.NewCodec(opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var account Account
+			if err := codec.Unmarshal([]byte(tt.input), &account); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if account.Name != "alice" {
+				t.Errorf("expected name alice, got %q", account.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("dropped keys mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithHooks_DeprecatedKey(t *testing.T) {
+	t.Parallel()
+
+	type Member struct {
+		UserID int    `form:"user_id,deprecated=uid|userid"`
+		Name   string `form:"name"`
+	}
+	type Team struct {
+		Lead Member `form:"lead"`
+	}
+
+	type deprecated struct {
+		Key  string
+		Name string
+	}
+
+	var got []deprecated
+	codec, err := formenc.NewCodec(formenc.WithHooks(formenc.Hooks{
+		OnDeprecatedKey: func(_ context.Context, key, name string) {
+			got = append(got, deprecated{Key: key, Name: name})
+		},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var team Team
+	md, err := codec.UnmarshalWithMetadata([]byte("lead[uid]=7&lead[name]=alice"), &team)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Team{Lead: Member{UserID: 7, Name: "alice"}}, team); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	want := []deprecated{{Key: "lead[uid]", Name: "user_id"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("deprecated keys mismatch (-want +got):\n%s", diff)
+	}
+	wantMeta := []formenc.DeprecatedKey{{Key: "lead[uid]", Path: mustPath("lead[uid]"), Name: "user_id"}}
+	if diff := cmp.Diff(wantMeta, md.Deprecated); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	// The current name is not reported.
+	got = nil
+	if err := codec.Unmarshal([]byte("lead[user_id]=8&lead[userid]=9"), &team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]deprecated{{Key: "lead[userid]", Name: "user_id"}}, got); diff != "" {
+		t.Errorf("deprecated keys mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,200 @@
+package formenc
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderHTML returns HTML form controls for the fields of the struct v. See
+// [Codec.RenderHTML].
+func RenderHTML(v interface{}) (string, error) {
+	return defaultCodec.RenderHTML(v)
+}
+
+// RenderHTML returns HTML form controls for the fields of the struct v, named
+// with the keys c decodes, so that submitting the form decodes into the same
+// struct. Fields hold the current values of v; v may be a nil pointer to
+// render an empty form.
+//
+// Each scalar field is rendered as an <input> inside a <label>, or a <select>
+// if the field has the enum tag flag. Booleans are checkboxes and numbers are
+// number inputs. The required flag adds the required attribute, and the
+// default flag supplies the value of a zero field. Nested structs are wrapped
+// in a <fieldset>. Slices render a control for each element, or a single empty
+// control if they have none. Elements of slices of structs and maps are named
+// by position, as in "lines[0][sku]", so are decoded only by a Codec
+// configured with [WithIndexedKeys] or an option implying it. The result is
+// escaped and safe to include in a page, for example as an html/template.HTML
+// value.
+func (c *Codec) RenderHTML(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv = reflect.New(rv.Type().Elem())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("form: cannot render %T, which is not a struct", v)
+	}
+
+	var b strings.Builder
+	if err := c.renderStruct(&b, nil, rv); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderStruct writes controls for the fields of the struct v at path.
+func (c *Codec) renderStruct(b *strings.Builder, path []pathSegment, v reflect.Value) error {
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		tag := tags[i]
+		if tag.Ignore || tag.Name == "" || tag.ReadOnly || !v.Type().Field(i).IsExported() {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], tag.segments()...)
+		if err := c.renderValue(b, fieldPath, tag, v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderValue writes controls for v, the value of the field described by tag,
+// at path.
+func (c *Codec) renderValue(b *strings.Builder, path []pathSegment, tag *tag, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if v.Kind() == reflect.Interface {
+				return nil
+			}
+			v = reflect.New(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+
+	if c.decodesLeaf(v) {
+		return c.renderControl(b, path, tag, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		b.WriteString("<fieldset><legend>" + html.EscapeString(tag.Name) + "</legend>\n")
+		if err := c.renderStruct(b, path, v); err != nil {
+			return err
+		}
+		b.WriteString("</fieldset>\n")
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		n := v.Len()
+		if n == 0 {
+			// Render a single empty element for the user to fill in.
+			v, n = reflect.New(reflect.ArrayOf(1, v.Type().Elem())).Elem(), 1
+		}
+		for i := 0; i < n; i++ {
+			// Elements holding nested fields are addressed by position so that
+			// their fields are decoded into the same element.
+			seg := pathSegment{Index: true, Pos: i}
+			if k := indirectType(v.Type().Elem()).Kind(); k == reflect.Struct || k == reflect.Map {
+				seg = pathSegment{Key: strconv.Itoa(i)}
+			}
+			if err := c.renderValue(b, append(path[:len(path):len(path)], seg), tag, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			seg := pathSegment{Key: fmt.Sprint(k.Interface()), Map: true}
+			if err := c.renderValue(b, append(path[:len(path):len(path)], seg), tag, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return c.renderControl(b, path, tag, v)
+}
+
+// renderControl writes a single control for the scalar v at path.
+func (c *Codec) renderControl(b *strings.Builder, path []pathSegment, tag *tag, v reflect.Value) error {
+	value, ok, err := c.formatScalar(v)
+	if err != nil {
+		return c.encodeError(err, path)
+	}
+	if !ok {
+		return c.encodeError(&UnsupportedTypeError{Type: v.Type()}, path)
+	}
+	if v.IsZero() && v.Kind() != reflect.Bool {
+		value = tag.Default
+	}
+
+	name := html.EscapeString(c.renderer.render(path))
+	attrs := ` name="` + name + `"`
+	if tag.Required {
+		attrs += " required"
+	}
+
+	b.WriteString("<label>" + html.EscapeString(tag.Name) + " ")
+	switch {
+	case len(tag.Enum) > 0:
+		b.WriteString("<select" + attrs + ">")
+		for _, opt := range tag.Enum {
+			b.WriteString(`<option value="` + html.EscapeString(opt) + `"`)
+			if opt == value {
+				b.WriteString(" selected")
+			}
+			b.WriteString(">" + html.EscapeString(opt) + "</option>")
+		}
+		b.WriteString("</select>")
+
+	case v.Kind() == reflect.Bool && !c.decodesLeaf(v):
+		b.WriteString(`<input type="checkbox"` + attrs + ` value="true"`)
+		if v.Bool() || (tag.Default == "true" && v.IsZero()) {
+			b.WriteString(" checked")
+		}
+		b.WriteString(">")
+
+	case tag.Secret:
+		// Secret values are never written into the page.
+		b.WriteString(`<input type="password"` + attrs + `>`)
+
+	default:
+		if k := v.Kind(); k == reflect.Float32 || k == reflect.Float64 {
+			attrs += ` step="any"`
+		}
+		b.WriteString(`<input type="` + inputType(v) + `"` + attrs + ` value="` + html.EscapeString(value) + `">`)
+	}
+	b.WriteString("</label>\n")
+	return nil
+}
+
+// inputType returns the type of the <input> element for v.
+func inputType(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "text"
+}
+
+// indirectType returns the type t points to, if t is a pointer.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
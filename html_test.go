@@ -0,0 +1,110 @@
+package formenc_test
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Profile struct {
+	Name    string      `form:"name,required"`
+	Role    string      `form:"role,default=member,enum=member|admin"`
+	Age     int         `form:"age"`
+	Score   float64     `form:"score"`
+	Active  bool        `form:"active"`
+	Tags    []string    `form:"tags"`
+	Address Address     `form:"address"`
+	Lines   []OrderLine `form:"lines"`
+}
+
+func TestRenderHTML(t *testing.T) {
+	t.Parallel()
+
+	got, err := formenc.RenderHTML((*Profile)(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`<label>name <input type="text" name="name" required value=""></label>`,
+		`<label>role <select name="role"><option value="member" selected>member</option><option value="admin">admin</option></select></label>`,
+		`<label>age <input type="number" name="age" value=""></label>`,
+		`<label>score <input type="number" name="score" step="any" value=""></label>`,
+		`<label>active <input type="checkbox" name="active" value="true"></label>`,
+		`<label>tags <input type="text" name="tags[]" value=""></label>`,
+		`<fieldset><legend>address</legend>`,
+		`<label>street <input type="text" name="address[street]" value=""></label>`,
+		`<label>city <input type="text" name="address[city]" value=""></label>`,
+		`<label>state <input type="text" name="address[state]" value=""></label>`,
+		`<label>zip <input type="text" name="address[zip]" value=""></label>`,
+		`</fieldset>`,
+		`<fieldset><legend>lines</legend>`,
+		`<label>sku <input type="text" name="lines[0][sku]" required value=""></label>`,
+		`<label>qty <input type="number" name="lines[0][qty]" value="1"></label>`,
+		`</fieldset>`,
+		``,
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+// submit returns the form data a browser would submit for the controls in
+// form.
+func submit(form string) string {
+	var values []string
+	add := func(name, value string) {
+		values = append(values, url.QueryEscape(html.UnescapeString(name))+"="+url.QueryEscape(html.UnescapeString(value)))
+	}
+
+	input := regexp.MustCompile(`<input type="(\w+)" name="([^"]*)"[^>]*? value="([^"]*)"( checked)?>`)
+	for _, m := range input.FindAllStringSubmatch(form, -1) {
+		if m[1] != "checkbox" || m[4] != "" {
+			add(m[2], m[3])
+		}
+	}
+	sel := regexp.MustCompile(`<select name="([^"]*)"[^>]*>.*?<option value="([^"]*)" selected>`)
+	for _, m := range sel.FindAllStringSubmatch(form, -1) {
+		add(m[1], m[2])
+	}
+	return strings.Join(values, "&")
+}
+
+func TestRenderHTML_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	want := Profile{
+		Name:    `Ada "The Countess" <Lovelace>`,
+		Role:    "admin",
+		Age:     36,
+		Score:   9.5,
+		Active:  true,
+		Tags:    []string{"math", "poetry"},
+		Address: Address{City: "London"},
+		Lines:   []OrderLine{{SKU: "a", Qty: 2}, {SKU: "b", Qty: 3}},
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithIndexedKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	form, err := codec.RenderHTML(&want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Profile
+	if err := codec.Unmarshal([]byte(submit(form)), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
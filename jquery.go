@@ -0,0 +1,137 @@
+package formenc
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jqueryObject is the string JavaScript produces for a plain object.
+const jqueryObject = "[object Object]"
+
+// WithJQueryCompat configures a [Codec] to encode form data exactly as jQuery's
+// $.param does, which is useful when replacing JavaScript that signs or caches
+// requests on the encoded string.
+//
+// Pairs are written in struct field order, with map keys sorted, and escaped
+// as encodeURIComponent does. When traditional is false arrays of scalars are
+// written as a[]=1, and arrays of objects with their index as a[0][b]=2. When
+// traditional is true arrays are written as repeated keys, a=1&a=2, and nested
+// objects are written as JavaScript would convert them to strings.
+func WithJQueryCompat(traditional bool) Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithJQueryCompat", false, true); err != nil {
+			return err
+		}
+		c.renderer = jqueryRenderer{traditional: traditional}
+		c.escape = escapeURIComponent
+		c.preserveOrder = true
+		return nil
+	}
+}
+
+// jqueryRenderer reproduces the keys written by jQuery's buildParams.
+type jqueryRenderer struct {
+	traditional bool
+}
+
+func (r jqueryRenderer) render(path []pathSegment) string {
+	var b strings.Builder
+	b.WriteString(path[0].Key)
+	for i, seg := range path[1:] {
+		last := i == len(path)-2
+		switch {
+		case seg.Index && r.traditional:
+			// Traditional mode repeats the key for each element.
+		case seg.Index && last:
+			// Scalar elements are appended, unless the key already ends in [].
+			if !strings.HasSuffix(b.String(), "[]") {
+				b.WriteString("[]")
+			}
+		case seg.Index:
+			b.WriteString("[")
+			b.WriteString(strconv.Itoa(seg.Pos))
+			b.WriteString("]")
+		default:
+			b.WriteString("[")
+			b.WriteString(seg.Key)
+			b.WriteString("]")
+		}
+	}
+	return b.String()
+}
+
+// collapse converts nested values to strings in traditional mode, as the
+// implicit string conversion in JavaScript would.
+func (r jqueryRenderer) collapse(path []pathSegment, v reflect.Value) (string, bool, error) {
+	if !r.traditional {
+		return "", false, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		return jqueryObject, true, nil
+	case reflect.Slice, reflect.Array:
+		if len(path) == 1 {
+			// Top-level arrays are written as repeated keys.
+			return "", false, nil
+		}
+		s, err := jsString(v)
+		return s, true, err
+	}
+	return "", false, nil
+}
+
+// jsString converts v to a string as JavaScript's String function would, with
+// arrays joined by commas and objects written as [object Object].
+func jsString(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if m, ok := asMarshaler(v); ok {
+		return m.MarshalForm()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		return jqueryObject, nil
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, v.Len())
+		for i := range elems {
+			s, err := jsString(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return strings.Join(elems, ","), nil
+	default:
+		return getScalar(v)
+	}
+}
+
+// escapeURIComponent escapes s as JavaScript's encodeURIComponent does,
+// leaving only letters, digits and the marks -_.!~*'() unescaped.
+func escapeURIComponent(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		case strings.IndexByte("-_.!~*'()", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
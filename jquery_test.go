@@ -0,0 +1,82 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestCodec_JQueryCompat(t *testing.T) {
+	t.Parallel()
+
+	type Line struct {
+		B int `form:"b"`
+	}
+
+	type Payload struct {
+		Name   string     `form:"name"`
+		A      []int      `form:"a"`
+		Lines  []Line     `form:"lines"`
+		Matrix [][]string `form:"matrix"`
+		Owner  User       `form:"owner"`
+	}
+
+	payload := Payload{
+		Name:   "John Doe!",
+		A:      []int{1, 2},
+		Lines:  []Line{{B: 2}},
+		Matrix: [][]string{{"x", "y"}},
+		Owner:  User{Name: "Al", Address: Address{City: "Rome"}},
+	}
+
+	tests := map[string]struct {
+		traditional bool
+		input       interface{}
+		want        string
+	}{
+		"non-traditional": {
+			input: payload,
+			want: "name=John%20Doe!&a%5B%5D=1&a%5B%5D=2&lines%5B0%5D%5Bb%5D=2" +
+				"&matrix%5B0%5D%5B%5D=x&matrix%5B0%5D%5B%5D=y" +
+				"&owner%5Bname%5D=Al&owner%5Baddress%5D%5Bstreet%5D=" +
+				"&owner%5Baddress%5D%5Bcity%5D=Rome&owner%5Baddress%5D%5Bstate%5D=" +
+				"&owner%5Baddress%5D%5Bzip%5D=",
+		},
+		"traditional": {
+			traditional: true,
+			input:       payload,
+			want: "name=John%20Doe!&a=1&a=2&lines=%5Bobject%20Object%5D" +
+				"&matrix=x%2Cy&owner=%5Bobject%20Object%5D",
+		},
+		"map keys sorted": {
+			input: map[string]interface{}{"b": "2", "a": []string{"x"}, "c[]": []string{"y", "z"}},
+			want:  "a%5B%5D=x&b=2&c%5B%5D=y&c%5B%5D=z",
+		},
+		"custom marshaler is not collapsed": {
+			traditional: true,
+			input:       map[string]interface{}{"date": MyDate(baseTime)},
+			want:        "date=2025.02.08",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithJQueryCompat(tt.traditional))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := codec.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
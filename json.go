@@ -0,0 +1,39 @@
+package formenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// FormToJSON converts form data into the equivalent JSON object, nesting
+// values by their bracketed keys as [Unmarshal] does when decoding into a
+// map[string]interface{}. Every value is written as a JSON string, since form
+// data does not distinguish strings from numbers or booleans.
+func FormToJSON(data []byte) ([]byte, error) {
+	var m map[string]interface{}
+	if err := Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// JSONToForm converts a JSON object into the equivalent form data, flattening
+// nested objects and arrays into bracketed keys as [Marshal] does. Numbers are
+// written exactly as they appear in the JSON document, and null values are
+// left out.
+func JSONToForm(data []byte) ([]byte, error) {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("form: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("form: invalid JSON after top-level value")
+	}
+	if m == nil {
+		return nil, fmt.Errorf("form: JSON document must be an object")
+	}
+	return Marshal(m)
+}
@@ -0,0 +1,78 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestFormToJSON(t *testing.T) {
+	t.Parallel()
+
+	got, err := formenc.FormToJSON([]byte("user[name]=Diana&user[permissions][]=read&user[permissions][]=write&age=30"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"age":"30","user":{"name":"Diana","permissions":["read","write"]}}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONToForm(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		"nested": {
+			input: `{"user":{"name":"Diana","permissions":["read","write"]},"age":30}`,
+			want:  "age=30&user%5Bname%5D=Diana&user%5Bpermissions%5D%5B%5D=read&user%5Bpermissions%5D%5B%5D=write",
+		},
+		"numbers and booleans": {
+			input: `{"price":1.50,"big":12345678901234567890,"active":true}`,
+			want:  "active=true&big=12345678901234567890&price=1.50",
+		},
+		"null": {
+			input: `{"name":"alice","nickname":null}`,
+			want:  "name=alice",
+		},
+		"array": {
+			input:   `["a","b"]`,
+			wantErr: true,
+		},
+		"top-level null": {
+			input:   `null`,
+			wantErr: true,
+		},
+		"trailing data": {
+			input:   `{"a":"b"} {}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.JSONToForm([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
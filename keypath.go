@@ -0,0 +1,128 @@
+package formenc
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Segment is a segment of a [KeyPath].
+type Segment struct {
+	// Key is the name of a struct field or the key of a map element. A
+	// numeric key, as in "items[0]", may also address an array element,
+	// depending on the value it is decoded into.
+	Key string
+
+	// Index is set for a segment addressing an array element, and Pos is
+	// its position, or -1 for a segment appending an element, as in
+	// "items[]".
+	Index bool
+	Pos   int
+}
+
+// KeyPath is a form key split into its segments, such as "user", "tags" and
+// an appended element for "user[tags][]".
+type KeyPath []Segment
+
+// ParsePath splits key into its segments by the bracketed key syntax of
+// [Unmarshal], so that "user[tags][]" becomes the segments "user" and "tags"
+// followed by one appending an array element. The key must be unescaped. A
+// [SyntaxError] is returned if a bracket is not closed.
+func ParsePath(key string) (KeyPath, error) {
+	segs, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return keyPath(segs), nil
+}
+
+// keyPath returns the public form of segs.
+func keyPath(segs []pathSegment) KeyPath {
+	if segs == nil {
+		return nil
+	}
+	p := make(KeyPath, len(segs))
+	for i, seg := range segs {
+		p[i] = Segment{Key: seg.Key, Index: seg.Index}
+		if seg.Index {
+			p[i].Pos = seg.Pos
+		}
+	}
+	return p
+}
+
+// segments returns the internal form of p.
+func (p KeyPath) segments() []pathSegment {
+	segs := make([]pathSegment, len(p))
+	for i, seg := range p {
+		segs[i] = pathSegment{Key: seg.Key, Index: seg.Index, Pos: seg.Pos}
+	}
+	return segs
+}
+
+// String returns the key p was parsed from, in the bracketed key syntax of
+// [Marshal], except that positioned array segments are written with their
+// position, as in "items[0]".
+func (p KeyPath) String() string {
+	return renderSegments(p.segments())
+}
+
+// Append returns p with the segment addressing key appended, leaving p
+// unmodified.
+func (p KeyPath) Append(key string) KeyPath {
+	return append(p[:len(p):len(p)], Segment{Key: key})
+}
+
+// AppendIndex returns p with the segment addressing the array element at pos
+// appended, leaving p unmodified. A negative pos appends an element.
+func (p KeyPath) AppendIndex(pos int) KeyPath {
+	if pos < 0 {
+		pos = -1
+	}
+	return append(p[:len(p):len(p)], Segment{Index: true, Pos: pos})
+}
+
+// String returns the segment as written in a bracketed key, without brackets:
+// its key, its position, or an empty string for an appended element.
+func (s Segment) String() string {
+	switch {
+	case !s.Index:
+		return s.Key
+	case s.Pos < 0:
+		return ""
+	}
+	return strconv.Itoa(s.Pos)
+}
+
+// ErrorPath returns the path of the key that caused err, for errors returned
+// while decoding, such as an [UnmarshalTypeError], and passed to the
+// OnFieldError hook, so that they can be mapped onto the fields of a form. It
+// reports false if err does not identify a key.
+func ErrorPath(err error) (KeyPath, bool) {
+	var (
+		typeErr        *UnmarshalTypeError
+		unknownErr     *UnknownFieldError
+		unexportedErr  *UnexportedFieldError
+		unsupportedErr *UnsupportedTypeError
+		limitErr       *LimitExceededError
+		duplicateErr   *DuplicateFieldError
+		missingErr     *MissingFieldError
+	)
+	var path KeyPath
+	switch {
+	case errors.As(err, &typeErr):
+		path = typeErr.Path
+	case errors.As(err, &unknownErr):
+		path = unknownErr.Path
+	case errors.As(err, &unexportedErr):
+		path = unexportedErr.Path
+	case errors.As(err, &unsupportedErr):
+		path = unsupportedErr.Path
+	case errors.As(err, &limitErr):
+		path = limitErr.Path
+	case errors.As(err, &duplicateErr):
+		path = duplicateErr.Path
+	case errors.As(err, &missingErr):
+		path = missingErr.Path
+	}
+	return path, path != nil
+}
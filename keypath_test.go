@@ -0,0 +1,123 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestParsePath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		key  string
+		want formenc.KeyPath
+	}{
+		"plain key": {
+			key:  "name",
+			want: formenc.KeyPath{{Key: "name"}},
+		},
+		"nested keys": {
+			key:  "user[address][city]",
+			want: formenc.KeyPath{{Key: "user"}, {Key: "address"}, {Key: "city"}},
+		},
+		"appended element": {
+			key:  "user[tags][]",
+			want: formenc.KeyPath{{Key: "user"}, {Key: "tags"}, {Index: true, Pos: -1}},
+		},
+		"numeric key": {
+			key:  "items[0][name]",
+			want: formenc.KeyPath{{Key: "items"}, {Key: "0"}, {Key: "name"}},
+		},
+		"top-level element": {
+			key:  "[][name]",
+			want: formenc.KeyPath{{Index: true, Pos: -1}, {Key: "name"}},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.ParsePath(tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if s := got.String(); s != tt.key {
+				t.Errorf("expected String to return %q, got %q", tt.key, s)
+			}
+		})
+	}
+
+	var syntaxErr *formenc.SyntaxError
+	if _, err := formenc.ParsePath("user[name"); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected SyntaxError, got: %v", err)
+	}
+}
+
+func TestKeyPath_Append(t *testing.T) {
+	t.Parallel()
+
+	base := formenc.KeyPath{{Key: "items"}}
+	got := base.AppendIndex(2).Append("name")
+	if s := got.String(); s != "items[2][name]" {
+		t.Errorf("expected %q, got %q", "items[2][name]", s)
+	}
+	if s := base.AppendIndex(-5).String(); s != "items[]" {
+		t.Errorf("expected %q, got %q", "items[]", s)
+	}
+	if len(base) != 1 {
+		t.Errorf("expected base to be unmodified, got %v", base)
+	}
+}
+
+func TestErrorPath(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithAggregateErrors())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got map[string][]int
+	err = codec.Unmarshal([]byte("ids[]=1&ids[]=x&sizes[]=y"), &got)
+
+	var multi *formenc.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, got: %v", err)
+	}
+	var paths []formenc.KeyPath
+	for _, err := range multi.Errors {
+		path, ok := formenc.ErrorPath(err)
+		if !ok {
+			t.Fatalf("expected path for error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	want := []formenc.KeyPath{
+		{{Key: "ids"}, {Index: true, Pos: -1}},
+		{{Key: "sizes"}, {Index: true, Pos: -1}},
+	}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, ok := formenc.ErrorPath(errors.New("other")); ok {
+		t.Error("expected no path for other errors")
+	}
+}
+
+// mustPath returns the path of key, which must be valid.
+func mustPath(key string) formenc.KeyPath {
+	path, err := formenc.ParsePath(key)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
@@ -0,0 +1,102 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kvSeparators returns the pair and key separators given by arg, the argument
+// of the kv flag, returning an error unless it holds two different characters.
+//
+// A field tagged with the kv flag, such as `form:"labels,kv=;:"`, holds a map
+// packed into a single value, as in "labels=env:prod;tier:web". The first
+// character of the flag separates pairs and the second separates each key
+// from its value. Neither may be a comma, which separates the flags of a tag.
+func kvSeparators(arg string) (string, string, error) {
+	r := []rune(arg)
+	if len(r) != 2 || r[0] == r[1] {
+		return "", "", fmt.Errorf("want two different separators, got %q", arg)
+	}
+	return string(r[0]), string(r[1]), nil
+}
+
+// assignKV decodes val, a list of pairs packed as described by the kv flag of
+// t, into the map field v.
+func (c *Codec) assignKV(v reflect.Value, t *tag, val string) error {
+	fv := deref(v)
+	if fv.Kind() != reflect.Map || fv.Type().Key().Kind() != reflect.String {
+		return &UnmarshalTypeError{Value: val, Type: fv.Type(), Err: fmt.Errorf("kv flag requires a map with string keys")}
+	}
+	c.planLeaf(fv.Type(), "kv")
+
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(fv.Type()))
+	}
+	if val == "" {
+		return nil
+	}
+	for _, p := range strings.Split(val, t.KVPairs) {
+		key, value, ok := strings.Cut(p, t.KVSep)
+		if !ok {
+			return &UnmarshalTypeError{Value: val, Type: fv.Type(), Err: fmt.Errorf("pair %q has no %q separator", p, t.KVSep)}
+		}
+		if err := c.assign(fv, []pathSegment{{Key: key, Map: true}}, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalKV encodes the map v as a single value at path, its pairs packed as
+// described by the kv flag of t. Keys and values containing either separator
+// cannot be packed without changing their meaning, so return an
+// [UnsupportedValueError].
+func (c *Codec) marshalKV(e *encodeState, path []pathSegment, t *tag, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("form: kv flag cannot encode %v", v.Type())
+	}
+	if v.IsNil() {
+		return nil
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		mv := v.MapIndex(k)
+		for mv.Kind() == reflect.Pointer || mv.Kind() == reflect.Interface {
+			if mv.IsNil() {
+				break
+			}
+			mv = mv.Elem()
+		}
+		s, ok, err := c.formatScalar(mv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("form: kv flag cannot encode nested %v", mv.Type())
+		}
+		for _, part := range []string{k.String(), s} {
+			if strings.Contains(part, t.KVPairs) || strings.Contains(part, t.KVSep) {
+				err := &UnsupportedValueError{Value: mv, Str: strconv.Quote(part) + " contains a kv separator", Key: c.renderer.render(path)}
+				return fmt.Errorf("form: %w", err)
+			}
+		}
+		parts = append(parts, k.String()+t.KVSep+s)
+	}
+	e.add(path, c.renderer.render(path), strings.Join(parts, t.KVPairs))
+	return nil
+}
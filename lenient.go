@@ -0,0 +1,192 @@
+package formenc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Metadata describes how form data was decoded, beyond the value produced.
+type Metadata struct {
+	// Issues lists the problems tolerated while parsing in lenient mode, or
+	// with stray separators ignored, in the order they appeared.
+	Issues []ParseIssue
+
+	// Deprecated lists the keys that named a field by a deprecated name, in
+	// the order they appeared.
+	Deprecated []DeprecatedKey
+}
+
+// DeprecatedKey describes a key naming a struct field by one of the former
+// names listed by the deprecated flag of its tag, such as
+// `form:"user_id,deprecated=uid"`.
+type DeprecatedKey struct {
+	Key  string  // the full form key, such as "user[uid]"
+	Path KeyPath // the segments of Key
+	Name string  // the current name of the field, such as "user_id"
+}
+
+// ParseIssue describes part of a pair that was not valid form data but was
+// accepted by a lenient [Codec].
+type ParseIssue struct {
+	Pair string // the pair as it appeared in the input
+	Msg  string // a description of the problem
+}
+
+// WithLenient configures a [Codec] to accept common mistakes in form data
+// rather than failing the whole decode. A key or value containing an invalid
+// escape sequence, such as a stray "%", is captured exactly as it appeared,
+// without unescaping, and semicolons are treated as part of a value rather
+// than as separators. Unescaped spaces and brackets in values, which are
+// always accepted, are reported too. Use [Codec.UnmarshalWithMetadata] to
+// retrieve the issues found.
+func WithLenient() Option {
+	return func(c *Codec) error {
+		c.lenient = true
+		return nil
+	}
+}
+
+// UnmarshalWithMetadata behaves as [Codec.Unmarshal], additionally returning
+// metadata describing the decode.
+func (c *Codec) UnmarshalWithMetadata(data []byte, v interface{}) (*Metadata, error) {
+	md := &Metadata{}
+	d := *c
+	d.metadata = md
+	return md, d.unmarshal(data, v)
+}
+
+// sanitize rewrites each pair of query that is not valid form data into a
+// valid equivalent, recording the issues found.
+func (c *Codec) sanitize(query string) string {
+	pairs := strings.Split(query, "&")
+	for i, s := range pairs {
+		key, value, hasValue := strings.Cut(s, "=")
+		key = c.sanitizePart(s, key, false)
+		if hasValue {
+			s = key + "=" + c.sanitizePart(s, value, true)
+		} else {
+			s = key
+		}
+		pairs[i] = s
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sanitizePart returns part, a key or value of pair, escaped so that it is
+// valid form data.
+func (c *Codec) sanitizePart(pair, part string, value bool) string {
+	if strings.Contains(part, " ") {
+		c.addIssue(pair, "unescaped space")
+	}
+	if value && strings.ContainsAny(part, "[]") {
+		c.addIssue(pair, "unescaped bracket in value")
+	}
+	if _, err := url.QueryUnescape(part); err != nil {
+		c.addIssue(pair, "invalid escape sequence, captured without unescaping")
+		return url.QueryEscape(part)
+	}
+	if strings.Contains(part, ";") {
+		c.addIssue(pair, "unescaped semicolon")
+		return strings.ReplaceAll(part, ";", "%3B")
+	}
+	return part
+}
+
+func (c *Codec) addIssue(pair, msg string) {
+	if c.metadata != nil {
+		c.metadata.Issues = append(c.metadata.Issues, ParseIssue{Pair: pair, Msg: msg})
+	}
+}
+
+// WithTrimNoise configures a [Codec] to remove text that commonly surrounds
+// form data before parsing it: a UTF-8 byte order mark, matching single or
+// double quotes around the whole input, and a leading '?'. A query string
+// beginning with '?', or a payload copied from a log or spreadsheet, can then be
+// decoded directly.
+func WithTrimNoise() Option {
+	return func(c *Codec) error {
+		c.trimNoise = true
+		return nil
+	}
+}
+
+// trimNoise removes a byte order mark, surrounding quotes and a leading '?'
+// from query, in that order.
+func trimNoise(query string) string {
+	query = strings.TrimPrefix(query, "\ufeff")
+	query = strings.TrimSpace(query)
+	if len(query) >= 2 && (query[0] == '"' || query[0] == '\'') && query[len(query)-1] == query[0] {
+		query = strings.TrimSpace(query[1 : len(query)-1])
+	}
+	return strings.TrimPrefix(query, "?")
+}
+
+// SeparatorPolicy determines how a [Codec] decodes stray separators: the empty
+// pairs left by repeated, leading or trailing ampersands, as in "&&a=1&", and
+// the equals signs following the one that separates a key from its value, as
+// in "a==b".
+type SeparatorPolicy int
+
+const (
+	// SeparatorsKeep skips empty pairs and keeps every equals sign after the
+	// first as part of the value, so that "a==b" decodes as "=b". This is the
+	// default, and matches [url.ParseQuery].
+	SeparatorsKeep SeparatorPolicy = iota
+
+	// SeparatorsIgnore skips empty pairs and the equals signs repeated
+	// directly after a key, so that "a==b" decodes as "b". Equals signs later
+	// in the value, such as the padding of "a=b64==", are kept. Each repeated
+	// separator is reported as a [ParseIssue].
+	SeparatorsIgnore
+
+	// SeparatorsReject rejects form data containing an empty pair, or a value
+	// containing an unescaped equals sign, with a [SyntaxError].
+	SeparatorsReject
+)
+
+// WithStraySeparators configures a [Codec] to decode stray separators by the
+// given policy. Sloppy clients that build form data by concatenation can be
+// accepted with [SeparatorsIgnore], while [SeparatorsReject] holds clients to
+// well-formed data.
+func WithStraySeparators(policy SeparatorPolicy) Option {
+	return func(c *Codec) error {
+		if policy < SeparatorsKeep || policy > SeparatorsReject {
+			return fmt.Errorf("form: unknown separator policy %d", policy)
+		}
+		c.separators = policy
+		return nil
+	}
+}
+
+// applySeparators returns query with its stray separators removed or rejected
+// by the separator policy of c.
+func (c *Codec) applySeparators(query string) (string, error) {
+	if query == "" {
+		return query, nil
+	}
+
+	pairs := strings.Split(query, "&")
+	kept := pairs[:0]
+	for _, s := range pairs {
+		if s == "" {
+			if c.separators == SeparatorsReject {
+				return "", fmt.Errorf("form: %w", &SyntaxError{Msg: "empty pair in form data"})
+			}
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(s, "=")
+		if hasValue && strings.Contains(value, "=") {
+			if c.separators == SeparatorsReject {
+				return "", fmt.Errorf("form: %w", &SyntaxError{Msg: "unescaped '=' in value", Key: key})
+			}
+			if strings.HasPrefix(value, "=") {
+				c.addIssue(s, "repeated '=' separator")
+				s = key + "=" + strings.TrimLeft(value, "=")
+			}
+		}
+		kept = append(kept, s)
+	}
+	return strings.Join(kept, "&"), nil
+}
@@ -0,0 +1,216 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithLenient(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input      string
+		want       map[string]interface{}
+		wantIssues []formenc.ParseIssue
+	}{
+		"valid input": {
+			input: "name=john+doe&discount=10%25",
+			want:  map[string]interface{}{"name": "john doe", "discount": "10%"},
+		},
+		"stray percent": {
+			input: "name=john&discount=10%&note=a+b",
+			want:  map[string]interface{}{"name": "john", "discount": "10%", "note": "a b"},
+			wantIssues: []formenc.ParseIssue{
+				{Pair: "discount=10%", Msg: "invalid escape sequence, captured without unescaping"},
+			},
+		},
+		"invalid escape captured raw": {
+			input: "q=50%+off%zz",
+			want:  map[string]interface{}{"q": "50%+off%zz"},
+			wantIssues: []formenc.ParseIssue{
+				{Pair: "q=50%+off%zz", Msg: "invalid escape sequence, captured without unescaping"},
+			},
+		},
+		"unescaped spaces and brackets": {
+			input: "name=john doe&tags=[a]",
+			want:  map[string]interface{}{"name": "john doe", "tags": "[a]"},
+			wantIssues: []formenc.ParseIssue{
+				{Pair: "name=john doe", Msg: "unescaped space"},
+				{Pair: "tags=[a]", Msg: "unescaped bracket in value"},
+			},
+		},
+		"semicolon in value": {
+			input: "name=a;b",
+			want:  map[string]interface{}{"name": "a;b"},
+			wantIssues: []formenc.ParseIssue{
+				{Pair: "name=a;b", Msg: "unescaped semicolon"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithLenient())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]interface{}
+			md, err := codec.UnmarshalWithMetadata([]byte(tt.input), &got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantIssues, md.Issues); diff != "" {
+				t.Errorf("issues (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	var got map[string]interface{}
+	if err := formenc.Unmarshal([]byte("discount=10%"), &got); err == nil {
+		t.Error("expected error without lenient mode, got nil")
+	}
+}
+
+func TestWithTrimNoise(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input string
+		want  Person
+	}{
+		"leading question mark": {
+			input: "?name=john&age=30",
+			want:  Person{Name: "john", Age: 30},
+		},
+		"byte order mark": {
+			input: "\ufeffname=john",
+			want:  Person{Name: "john"},
+		},
+		"double quotes": {
+			input: `"name=john&age=30"`,
+			want:  Person{Name: "john", Age: 30},
+		},
+		"single quotes around query string": {
+			input: "'?name=john'",
+			want:  Person{Name: "john"},
+		},
+		"unmatched quote": {
+			input: `name="john`,
+			want:  Person{Name: `"john`},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithTrimNoise())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Person
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithStraySeparators(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy     formenc.SeparatorPolicy
+		input      string
+		want       map[string]interface{}
+		wantIssues []formenc.ParseIssue
+		wantErr    bool
+	}{
+		"keep empty pairs": {
+			policy: formenc.SeparatorsKeep,
+			input:  "&&a=1&",
+			want:   map[string]interface{}{"a": "1"},
+		},
+		"keep repeated equals": {
+			policy: formenc.SeparatorsKeep,
+			input:  "a==b",
+			want:   map[string]interface{}{"a": "=b"},
+		},
+		"ignore empty pairs": {
+			policy: formenc.SeparatorsIgnore,
+			input:  "&&a=1&&b=2&",
+			want:   map[string]interface{}{"a": "1", "b": "2"},
+		},
+		"ignore repeated equals": {
+			policy: formenc.SeparatorsIgnore,
+			input:  "a==b&c=d==",
+			want:   map[string]interface{}{"a": "b", "c": "d=="},
+			wantIssues: []formenc.ParseIssue{
+				{Pair: "a==b", Msg: "repeated '=' separator"},
+			},
+		},
+		"reject empty pair": {
+			policy:  formenc.SeparatorsReject,
+			input:   "a=1&",
+			wantErr: true,
+		},
+		"reject repeated equals": {
+			policy:  formenc.SeparatorsReject,
+			input:   "a==b",
+			wantErr: true,
+		},
+		"reject accepts well-formed data": {
+			policy: formenc.SeparatorsReject,
+			input:  "a=1&b=%3D",
+			want:   map[string]interface{}{"a": "1", "b": "="},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithStraySeparators(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]interface{}{}
+			md, err := codec.UnmarshalWithMetadata([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				var syntaxErr *formenc.SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Errorf("expected SyntaxError, got: %v", err)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantIssues, md.Issues); diff != "" {
+				t.Errorf("issues (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithStraySeparators(formenc.SeparatorPolicy(-1))); err == nil {
+		t.Error("expected error for unknown policy, got nil")
+	}
+}
@@ -0,0 +1,91 @@
+package formenc
+
+import (
+	"fmt"
+)
+
+// Limits bounds the resources used to decode form data. A zero field imposes
+// no limit. Exceeding a limit returns a [LimitExceededError].
+type Limits struct {
+	// MaxBytes is the size of the largest input decoded, in bytes.
+	MaxBytes int
+
+	// MaxKeys is the most pairs decoded from a single input.
+	MaxKeys int
+
+	// MaxDepth is the most segments in a key, so that "a[b][c]" has a depth
+	// of three.
+	MaxDepth int
+
+	// MaxSliceLength is the most elements decoded into a slice, whether they
+	// are appended or addressed by position.
+	MaxSliceLength int
+
+	// MaxValueLength is the length of the longest value decoded, in bytes.
+	MaxValueLength int
+}
+
+// hardenedLimits are the limits applied by [WithHardening].
+var hardenedLimits = Limits{
+	MaxBytes:       1 << 20,
+	MaxKeys:        1000,
+	MaxDepth:       8,
+	MaxSliceLength: 1000,
+	MaxValueLength: 64 << 10,
+}
+
+// WithLimits configures a [Codec] to enforce limits when decoding. An error is
+// returned if any limit is negative.
+func WithLimits(l Limits) Option {
+	return func(c *Codec) error {
+		if l.MaxBytes < 0 || l.MaxKeys < 0 || l.MaxDepth < 0 || l.MaxSliceLength < 0 || l.MaxValueLength < 0 {
+			return fmt.Errorf("form: limits must not be negative")
+		}
+		c.limits = l
+		return nil
+	}
+}
+
+// WithHardening configures a [Codec] with conservative limits suitable for
+// decoding untrusted input: 1MiB of input, 1000 keys, a key depth of 8, 1000
+// elements in any slice and 64KiB in any value. Follow it with [WithLimits] to
+// adjust them.
+func WithHardening() Option {
+	return WithLimits(hardenedLimits)
+}
+
+// checkSize returns a [LimitExceededError] if data is larger than the codec
+// permits.
+func (c *Codec) checkSize(data []byte) error {
+	if max := c.limits.MaxBytes; max > 0 && len(data) > max {
+		return fmt.Errorf("form: %w", &LimitExceededError{Limit: "byte size", Max: max})
+	}
+	return nil
+}
+
+// checkEntries returns a [LimitExceededError] for the first of entries that
+// exceeds a limit of the codec.
+func (c *Codec) checkEntries(entries []entry) error {
+	l := c.limits
+	if l.MaxKeys > 0 && len(entries) > l.MaxKeys {
+		return fmt.Errorf("form: %w", &LimitExceededError{Limit: "key count", Max: l.MaxKeys})
+	}
+	for _, e := range entries {
+		if l.MaxDepth > 0 && len(e.path) > l.MaxDepth {
+			return fmt.Errorf("form: %w", &LimitExceededError{Limit: "nesting depth", Max: l.MaxDepth, Key: e.key, Path: keyPath(e.path)})
+		}
+		if l.MaxValueLength > 0 && len(e.value) > l.MaxValueLength {
+			return fmt.Errorf("form: %w", &LimitExceededError{Limit: "value length", Max: l.MaxValueLength, Key: e.key, Path: keyPath(e.path)})
+		}
+	}
+	return nil
+}
+
+// checkSliceLength returns a [LimitExceededError] if a slice may not hold n
+// elements.
+func (c *Codec) checkSliceLength(n int) error {
+	if max := c.limits.MaxSliceLength; max > 0 && n > max {
+		return &LimitExceededError{Limit: "slice length", Max: max}
+	}
+	return nil
+}
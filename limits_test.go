@@ -0,0 +1,143 @@
+package formenc_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithLimits(t *testing.T) {
+	t.Parallel()
+
+	type Basket struct {
+		Items []string            `form:"items"`
+		Meta  map[string][]string `form:"meta"`
+		Any   interface{}         `form:"any"`
+		Owner *User               `form:"owner"`
+	}
+
+	tests := map[string]struct {
+		limits    formenc.Limits
+		input     string
+		want      *Basket
+		wantLimit string
+		wantKey   string
+	}{
+		"within limits": {
+			limits: formenc.Limits{MaxBytes: 64, MaxKeys: 2, MaxDepth: 2, MaxSliceLength: 2, MaxValueLength: 3},
+			input:  "items[]=a&items[]=b",
+			want:   &Basket{Items: []string{"a", "b"}},
+		},
+		"byte size": {
+			limits:    formenc.Limits{MaxBytes: 8},
+			input:     "items[]=abcdef",
+			wantLimit: "byte size",
+		},
+		"key count": {
+			limits:    formenc.Limits{MaxKeys: 2},
+			input:     "items[]=a&items[]=b&items[]=c",
+			wantLimit: "key count",
+		},
+		"nesting depth": {
+			limits:    formenc.Limits{MaxDepth: 2},
+			input:     "owner[address][city]=Leeds",
+			wantLimit: "nesting depth",
+			wantKey:   "owner[address][city]",
+		},
+		"value length": {
+			limits:    formenc.Limits{MaxValueLength: 3},
+			input:     "items[]=abcd",
+			wantLimit: "value length",
+			wantKey:   "items[]",
+		},
+		"appended elements": {
+			limits:    formenc.Limits{MaxSliceLength: 2},
+			input:     "items[]=a&items[]=b&items[]=c",
+			wantLimit: "slice length",
+			wantKey:   "items[]",
+		},
+		"positional element": {
+			limits:    formenc.Limits{MaxSliceLength: 100},
+			input:     "items[1000000]=a",
+			wantLimit: "slice length",
+			wantKey:   "items[1000000]",
+		},
+		"sparse position without limits": {
+			input: "items[3]=a",
+			want:  &Basket{Items: []string{"", "", "", "a"}},
+		},
+		"map of slices": {
+			limits:    formenc.Limits{MaxSliceLength: 1},
+			input:     "meta[tags]=a&meta[tags]=b",
+			wantLimit: "slice length",
+			wantKey:   "meta[tags]",
+		},
+		"interface slice": {
+			limits:    formenc.Limits{MaxSliceLength: 1},
+			input:     "any[]=a&any[]=b",
+			wantLimit: "slice length",
+			wantKey:   "any[]",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithLimits(tt.limits), formenc.WithIndexedKeys())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := &Basket{}
+			err = codec.Unmarshal([]byte(tt.input), got)
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+
+			var limitErr *formenc.LimitExceededError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected LimitExceededError, got: %v", err)
+			}
+			if limitErr.Limit != tt.wantLimit || limitErr.Key != tt.wantKey {
+				t.Errorf("expected %s limit in key %q, got: %v", tt.wantLimit, tt.wantKey, limitErr)
+			}
+		})
+	}
+}
+
+func TestWithHardening(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithHardening())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var user User
+	if err := codec.Unmarshal([]byte("name=alice&address[city]=Leeds"), &user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var limitErr *formenc.LimitExceededError
+	err = codec.Unmarshal([]byte("name="+strings.Repeat("a", 2<<20)), &user)
+	if !errors.As(err, &limitErr) || limitErr.Limit != "byte size" {
+		t.Errorf("expected byte size limit, got: %v", err)
+	}
+
+	err = codec.NewDecoder(strings.NewReader("name=" + strings.Repeat("a", 2<<20))).Decode(&user)
+	if !errors.As(err, &limitErr) || limitErr.Limit != "byte size" {
+		t.Errorf("expected byte size limit from decoder, got: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergePolicy determines how form data is decoded into a value that already
+// holds data.
+type MergePolicy int
+
+const (
+	// MergePresent overwrites only the fields named by the form data, leaving
+	// all others untouched. Elements decoded into a slice are appended to
+	// those it already holds. This is the default.
+	MergePresent MergePolicy = iota
+
+	// MergeReplace resets the value to its zero value before decoding, so
+	// that it holds only the form data.
+	MergeReplace
+
+	// MergeKeepExisting never overwrites a field that already holds a
+	// non-zero value. Only zero fields, nil pointers, empty slices and absent
+	// map keys are filled from the form data.
+	MergeKeepExisting
+)
+
+// WithMerge configures how a [Codec] decodes into a value that already holds
+// data, such as a struct populated with defaults or loaded from storage.
+func WithMerge(policy MergePolicy) Option {
+	return func(c *Codec) error {
+		if policy < MergePresent || policy > MergeKeepExisting {
+			return fmt.Errorf("form: unknown merge policy %d", policy)
+		}
+		c.merge = policy
+		return nil
+	}
+}
+
+// mergeValue copies into dst those parts of src that dst does not already
+// hold, recursing into structs and maps so that existing fields and keys are
+// preserved.
+func (c *Codec) mergeValue(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		if c.decodesLeaf(dst) {
+			break
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				c.mergeValue(dst.Field(i), src.Field(i))
+			}
+		}
+		return
+
+	case reflect.Pointer:
+		if dst.IsNil() || src.IsNil() {
+			break
+		}
+		if dst.Elem().Kind() == reflect.Struct || dst.Elem().Kind() == reflect.Map {
+			c.mergeValue(dst.Elem(), src.Elem())
+		}
+		return
+
+	case reflect.Map:
+		if dst.IsNil() || src.IsNil() {
+			break
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			existing := dst.MapIndex(iter.Key())
+			if !existing.IsValid() {
+				dst.SetMapIndex(iter.Key(), iter.Value())
+				continue
+			}
+			a, b := unwrap(existing), unwrap(iter.Value())
+			if a.Kind() == reflect.Map && b.Kind() == reflect.Map && a.Type() == b.Type() {
+				c.mergeValue(a, b)
+			}
+		}
+		return
+	}
+
+	if isZeroOrEmpty(dst) && !isZeroOrEmpty(src) {
+		dst.Set(src)
+	}
+}
+
+// isZeroOrEmpty reports whether v is a zero value or an empty slice or map.
+func isZeroOrEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// unwrap returns the value held by the interface or pointer v, if any.
+func unwrap(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,136 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithMerge(t *testing.T) {
+	t.Parallel()
+
+	existing := func() User {
+		return User{
+			Name:    "Alice",
+			Address: Address{Street: "1 Main St", City: "Springfield"},
+		}
+	}
+
+	tests := map[string]struct {
+		policy formenc.MergePolicy
+		input  string
+		want   User
+	}{
+		"present": {
+			policy: formenc.MergePresent,
+			input:  "name=Bob&age=30&address[city]=Shelbyville",
+			want: User{
+				Name:    "Bob",
+				Age:     30,
+				Address: Address{Street: "1 Main St", City: "Shelbyville"},
+			},
+		},
+		"replace": {
+			policy: formenc.MergeReplace,
+			input:  "name=Bob&address[city]=Shelbyville",
+			want: User{
+				Name:    "Bob",
+				Address: Address{City: "Shelbyville"},
+			},
+		},
+		"keep existing": {
+			policy: formenc.MergeKeepExisting,
+			input:  "name=Bob&age=30&address[city]=Shelbyville&address[zip]=49007",
+			want: User{
+				Name:    "Alice",
+				Age:     30,
+				Address: Address{Street: "1 Main St", City: "Springfield", Zip: "49007"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithMerge(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := existing()
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithMerge(formenc.MergePolicy(9))); err == nil {
+		t.Error("expected error for unknown policy, got nil")
+	}
+}
+
+func TestWithMerge_Collections(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy   formenc.MergePolicy
+		input    string
+		existing map[string]interface{}
+		want     map[string]interface{}
+	}{
+		"present": {
+			policy:   formenc.MergePresent,
+			input:    "user[name]=Bob&tags[]=new",
+			existing: map[string]interface{}{"user": map[string]interface{}{"name": "Alice", "age": "30"}},
+			want: map[string]interface{}{
+				"user": map[string]interface{}{"name": "Bob", "age": "30"},
+				"tags": []interface{}{"new"},
+			},
+		},
+		"replace": {
+			policy:   formenc.MergeReplace,
+			input:    "user[name]=Bob",
+			existing: map[string]interface{}{"user": map[string]interface{}{"name": "Alice", "age": "30"}},
+			want: map[string]interface{}{
+				"user": map[string]interface{}{"name": "Bob"},
+			},
+		},
+		"keep existing": {
+			policy: formenc.MergeKeepExisting,
+			input:  "user[name]=Bob&user[role]=admin&tags[]=new",
+			existing: map[string]interface{}{
+				"user": map[string]interface{}{"name": "Alice"},
+				"tags": []interface{}{"old"},
+			},
+			want: map[string]interface{}{
+				"user": map[string]interface{}{"name": "Alice", "role": "admin"},
+				"tags": []interface{}{"old"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithMerge(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := tt.existing
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package multipart
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var destinationType = reflect.TypeOf(Destination{})
+
+// Destination is a field type into which the content of a file part is
+// streamed, rather than being read into memory, so that large uploads can be
+// written straight to a file, a pipe or object storage while the other fields
+// of a struct are decoded as usual:
+//
+//	type Upload struct {
+//		Title string      `form:"title"`
+//		Video Destination `form:"video"`
+//	}
+//
+//	upload := Upload{Video: Destination{Create: func(f File) (io.WriteCloser, error) {
+//		return os.Create(filepath.Join(dir, f.Filename))
+//	}}}
+//	err := reader.Decode(&upload)
+//
+// Create must be set before decoding. A Destination may be a field of the
+// value decoded or of a struct it holds, but not an element of a slice or map.
+type Destination struct {
+	// Create returns the writer to which the content of the file f is copied.
+	// The writer is closed once the content has been copied. The content of
+	// f is nil, and its size unknown.
+	Create func(f File) (io.WriteCloser, error)
+
+	// File describes the file streamed, once decoded. Its content is nil, and
+	// its size is the number of bytes copied.
+	File File
+}
+
+// destination returns the Destination of v into which the file part named
+// name is streamed, or nil if the part names a field of another type.
+func (d *decodeState) destination(name string) (*Destination, error) {
+	steps, err := d.codec.Plan([]byte(url.QueryEscape(name)+"="), reflect.TypeOf(d.v))
+	if err != nil || len(steps) != 1 || steps[0].Type != destinationType {
+		return nil, nil
+	}
+
+	missing := fmt.Errorf("multipart: no destination for file part %q", name)
+	if strings.ContainsRune(steps[0].Field, '[') {
+		return nil, missing
+	}
+	fv := reflect.ValueOf(d.v)
+	for _, field := range strings.Split(steps[0].Field, ".") {
+		if fv = indirect(fv); !fv.IsValid() || fv.Kind() != reflect.Struct {
+			return nil, missing
+		}
+		fv = fv.FieldByName(field)
+	}
+	if fv = indirect(fv); !fv.IsValid() || !fv.CanAddr() {
+		return nil, missing
+	}
+
+	dest := fv.Addr().Interface().(*Destination)
+	if dest.Create == nil {
+		return nil, missing
+	}
+	return dest, nil
+}
+
+// indirect returns the value v points to, or the zero value if v is a nil
+// pointer.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// stream copies content, the content of the file part named name, to the
+// writer created by dest, unless it is an empty part sent for a file input
+// left empty.
+func (d *decodeState) stream(name string, part *multipart.Part, content io.Reader, dest *Destination) error {
+	br := bufio.NewReader(content)
+	if part.FileName() == "" {
+		if _, err := br.Peek(1); err == io.EOF {
+			return nil
+		}
+	}
+
+	f := File{
+		Filename:    sanitizeFilename(part.FileName()),
+		ContentType: part.Header.Get("Content-Type"),
+		Header:      part.Header,
+	}
+	w, err := dest.Create(f)
+	if err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+	n, err := io.Copy(w, br)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if d.exceeded != nil {
+		return d.exceeded
+	}
+	if err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+
+	f.Size = n
+	value := d.streamTokens.token(len(d.streamed))
+	d.streamed = append(d.streamed, Destination{Create: dest.Create, File: f})
+	d.pairs = append(d.pairs, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package multipart encodes and decodes multipart/form-data using the struct
+// tags and options of formenc.
+//
+// Text parts are decoded exactly as the equivalent urlencoded pairs would be,
+// so nested keys such as "items[0][name]" behave as they do with formenc.
+// File parts are decoded into fields of type [File], *File, []File or []*File
+// named by the part's form name:
+//
+//	type Upload struct {
+//		Title       string `form:"title"`
+//		Attachments []File `form:"attachments[]"`
+//	}
+//
+// This package is separate from formenc so that programs handling only
+// urlencoded data do not depend on mime/multipart.
+package multipart
+
+import (
+	"io"
+	"net/textproto"
+	"strings"
+	"unicode"
+)
+
+// File is a file sent as a part of multipart form data.
+type File struct {
+	// Filename is the name of the file given by the client. When decoding,
+	// any directories and control characters are removed from it, but it
+	// must still not be trusted as a path on the local filesystem. Names
+	// outside ASCII are written and read as RFC 5987 extended parameters.
+	Filename string
+
+	// ContentType is the media type of the file. When encoding, an empty
+	// content type is detected from the content by
+	// [net/http.DetectContentType], or written as application/octet-stream
+	// for empty content.
+	ContentType string
+
+	// Header holds every header of the part.
+	Header textproto.MIMEHeader
+
+	// Size is the length of the decoded content in bytes.
+	Size int64
+
+	// Content is read to obtain the file's data. When decoding it holds the
+	// whole content in memory.
+	Content io.Reader
+}
+
+// sanitizeFilename returns the last element of name, a filename given by a
+// client, without control characters. Names referring to a directory, such
+// as "..", are returned empty.
+func sanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// ProgressFunc reports the progress of reading or writing form data: the form
+// name of the part being transferred, and the total bytes of part content
+// transferred so far.
+type ProgressFunc func(field string, total int64)
+
+// progressReader reports the bytes read from r, the content of the part named
+// field, to fn, adding them to total.
+type progressReader struct {
+	r     io.Reader
+	field string
+	fn    ProgressFunc
+	total *int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		*p.total += int64(n)
+		p.fn(p.field, *p.total)
+	}
+	return n, err
+}
+
+// progressWriter reports the bytes written to w, the content of the part named
+// field, to fn, adding them to total.
+type progressWriter struct {
+	w     io.Writer
+	field string
+	fn    ProgressFunc
+	total *int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		*p.total += int64(n)
+		p.fn(p.field, *p.total)
+	}
+	return n, err
+}
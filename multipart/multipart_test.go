@@ -0,0 +1,1113 @@
+package multipart_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	stdmultipart "mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+type Item struct {
+	Name string `form:"name"`
+	Qty  int    `form:"qty"`
+}
+
+type Upload struct {
+	Title       string            `form:"title"`
+	Items       []Item            `form:"items"`
+	Avatar      *multipart.File   `form:"avatar"`
+	Attachments []*multipart.File `form:"attachments"`
+}
+
+// content reads and replaces the content of each file, so that files can be
+// compared.
+func content(t *testing.T, files ...*multipart.File) []string {
+	t.Helper()
+
+	var out []string
+	for _, f := range files {
+		data, err := io.ReadAll(f.Content)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out = append(out, string(data))
+		f.Content = nil
+		f.Header = nil
+	}
+	return out
+}
+
+func TestWriterReader(t *testing.T) {
+	t.Parallel()
+
+	in := Upload{
+		Title: "report",
+		Avatar: &multipart.File{
+			Filename:    "me.png",
+			ContentType: "image/png",
+			Content:     strings.NewReader("PNG"),
+		},
+		Attachments: []*multipart.File{
+			{Filename: "a.txt", Content: strings.NewReader("first")},
+			{Filename: "b.txt", Content: strings.NewReader("second")},
+		},
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.Encode(in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/", &buf)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	reader, err := multipart.NewRequestReader(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Upload
+	if err := reader.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"PNG", "first", "second"}, content(t, append([]*multipart.File{got.Avatar}, got.Attachments...)...)); diff != "" {
+		t.Errorf("content (-want +got):\n%s", diff)
+	}
+
+	want := Upload{
+		Title:  "report",
+		Avatar: &multipart.File{Filename: "me.png", ContentType: "image/png", Size: 3},
+		Attachments: []*multipart.File{
+			{Filename: "a.txt", ContentType: "text/plain; charset=utf-8", Size: 5},
+			{Filename: "b.txt", ContentType: "text/plain; charset=utf-8", Size: 6},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestWriter_ContentType(t *testing.T) {
+	t.Parallel()
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 600)
+	tests := map[string]struct {
+		file    multipart.File
+		want    string
+		wantLen int
+	}{
+		"explicit": {
+			file:    multipart.File{Filename: "a", ContentType: "image/gif", Content: strings.NewReader(png)},
+			want:    "image/gif",
+			wantLen: len(png),
+		},
+		"sniffed": {
+			file:    multipart.File{Filename: "a", Content: strings.NewReader(png)},
+			want:    "image/png",
+			wantLen: len(png),
+		},
+		"empty": {
+			file: multipart.File{Filename: "a", Content: strings.NewReader("")},
+			want: "application/octet-stream",
+		},
+		"no content": {
+			file: multipart.File{Filename: "a"},
+			want: "application/octet-stream",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := multipart.NewWriter(&buf)
+			if err := w.Encode(Upload{Avatar: &tt.file}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			part, err := stdmultipart.NewReader(&buf, w.Boundary()).NextPart()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, part.Header.Get("Content-Type")); diff != "" {
+				t.Errorf("content type (-want +got):\n%s", diff)
+			}
+
+			// The sniffed bytes are still written.
+			data, _ := io.ReadAll(part)
+			if len(data) != tt.wantLen {
+				t.Errorf("expected %d bytes, got %d", tt.wantLen, len(data))
+			}
+		})
+	}
+}
+
+func TestReader_Decode(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="items[0][name]"`,
+		"",
+		"a",
+		"--b",
+		`Content-Disposition: form-data; name="items[0][qty]"`,
+		"",
+		"2",
+		"--b",
+		`Content-Disposition: form-data; name="avatar"; filename=""`,
+		"Content-Type: application/octet-stream",
+		"",
+		"",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var got Upload
+	if err := multipart.NewReader(strings.NewReader(body), "b", formenc.WithIndexedKeys()).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Upload{Title: "hello", Items: []Item{{Name: "a", Qty: 2}}}, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestReader_SubForm(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"order",
+		"--b",
+		`Content-Disposition: form-data; name="items"`,
+		"Content-Type: application/x-www-form-urlencoded",
+		"",
+		"0[name]=a+b&0[qty]=2",
+		"1[name]=c",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var got Upload
+	if err := multipart.NewReader(strings.NewReader(body), "b", formenc.WithIndexedKeys()).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Upload{Title: "order", Items: []Item{{Name: "a b", Qty: 2}, {Name: "c"}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	reader := multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetMaxMemory(16)
+	if err := reader.Decode(&got); !errors.Is(err, multipart.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got: %v", err)
+	}
+}
+
+func TestReader_DecodeContext(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	type Form struct {
+		Title string `form:"title"`
+		Page  int    `form:"page"`
+	}
+
+	reader := multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetQuery("page=2")
+	var got Form
+	if err := reader.DecodeContext(context.Background(), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Form{Title: "hello", Page: 2}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := multipart.NewReader(strings.NewReader(body), "b").DecodeContext(ctx, &Form{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestReader_PartHandler(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="avatar"; filename="me.png"`,
+		"Content-Type: image/png",
+		"",
+		"PNG",
+		"--b",
+		`Content-Disposition: form-data; name="attachments"; filename=""`,
+		"Content-Type: application/octet-stream",
+		"",
+		"",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var parts []string
+	reader := multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetMaxMemory(512)
+	reader.SetPartHandler(func(name string, header textproto.MIMEHeader, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, name+" "+header.Get("Content-Type")+" "+string(data))
+		return nil
+	})
+
+	var got Upload
+	if err := reader.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Upload{Title: "hello"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"avatar image/png PNG"}, parts); diff != "" {
+		t.Errorf("parts (-want +got):\n%s", diff)
+	}
+
+	errStorage := errors.New("storage unavailable")
+	reader = multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetPartHandler(func(string, textproto.MIMEHeader, io.Reader) error {
+		return errStorage
+	})
+	if err := reader.Decode(&got); !errors.Is(err, errStorage) {
+		t.Errorf("expected handler error, got: %v", err)
+	}
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestReader_Destination(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="video"; filename="../clip.mp4"`,
+		"Content-Type: video/mp4",
+		"",
+		"MP4 content",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	type Video struct {
+		Title string                `form:"title"`
+		Video multipart.Destination `form:"video"`
+	}
+
+	var buf bufferCloser
+	got := Video{Video: multipart.Destination{Create: func(f multipart.File) (io.WriteCloser, error) {
+		if f.Filename != "clip.mp4" || f.ContentType != "video/mp4" {
+			t.Errorf("unexpected file: %q %q", f.Filename, f.ContentType)
+		}
+		return &buf, nil
+	}}}
+	reader := multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetMaxMemory(512)
+	if err := reader.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Title != "hello" {
+		t.Errorf("expected title %q, got %q", "hello", got.Title)
+	}
+	if buf.String() != "MP4 content" || !buf.closed {
+		t.Errorf("expected closed destination holding content, got %q closed %v", buf.String(), buf.closed)
+	}
+	want := multipart.File{Filename: "clip.mp4", ContentType: "video/mp4", Size: 11}
+	if diff := cmp.Diff(want, got.Video.File, cmpopts.IgnoreFields(multipart.File{}, "Header")); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var missing Video
+	reader = multipart.NewReader(strings.NewReader(body), "b")
+	if err := reader.Decode(&missing); err == nil {
+		t.Error("expected error for destination without Create")
+	}
+}
+
+func TestReader_ForgedDestinationTokens(t *testing.T) {
+	t.Parallel()
+
+	type Videos struct {
+		Video  multipart.Destination `form:"video"`
+		Poster multipart.Destination `form:"poster"`
+	}
+
+	tests := map[string]string{
+		"negative index": "\x00destination:-1",
+		"existing index": "\x00destination:0",
+	}
+	for name, value := range tests {
+		value := value
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			body := strings.Join([]string{
+				"--b",
+				`Content-Disposition: form-data; name="video"; filename="clip.mp4"`,
+				"",
+				"MP4 content",
+				"--b",
+				`Content-Disposition: form-data; name="poster"`,
+				"",
+				value,
+				"--b--",
+				"",
+			}, "\r\n")
+
+			create := func(multipart.File) (io.WriteCloser, error) { return &bufferCloser{}, nil }
+			got := Videos{
+				Video:  multipart.Destination{Create: create},
+				Poster: multipart.Destination{Create: create},
+			}
+			if err := multipart.NewReader(strings.NewReader(body), "b").Decode(&got); err == nil {
+				t.Error("expected error for text part decoded as destination, got nil")
+			}
+			if got.Poster.File.Filename != "" {
+				t.Errorf("expected no poster, got %q", got.Poster.File.Filename)
+			}
+		})
+	}
+}
+
+func TestReader_DecodeWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"X-Order: 1",
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="avatar"; filename="dir/me.png"`,
+		"Content-Type: image/png",
+		"",
+		"PNG",
+		"--b",
+		`Content-Disposition: form-data; name="items[0][name]"`,
+		"",
+		"pen",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var got Upload
+	md, err := multipart.NewReader(strings.NewReader(body), "b", formenc.WithIndexedKeys()).DecodeWithMetadata(&got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parts []string
+	for _, p := range md.Parts {
+		parts = append(parts, p.Name+" "+p.Filename+" "+p.Header.Get("X-Order"))
+	}
+	want := []string{"title  1", "avatar me.png ", "items[0][name]  "}
+	if diff := cmp.Diff(want, parts); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if got.Title != "hello" || got.Avatar == nil || len(got.Items) != 1 {
+		t.Errorf("unexpected value: %+v", got)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		disposition string
+		want        string
+	}{
+		"plain": {
+			disposition: `form-data; name="avatar"; filename="me.png"`,
+			want:        "me.png",
+		},
+		"extended": {
+			disposition: `form-data; name="avatar"; filename="__.png"; filename*=UTF-8''%E2%82%AC%C3%A9.png`,
+			want:        "€é.png",
+		},
+		"windows path": {
+			disposition: `form-data; name="avatar"; filename="C:\\Users\\me\\me.png"`,
+			want:        "me.png",
+		},
+		"control characters": {
+			disposition: `form-data; name="avatar"; filename*=UTF-8''me%07%0A.png`,
+			want:        "me.png",
+		},
+		"parent directory": {
+			disposition: `form-data; name="avatar"; filename*=UTF-8''..`,
+			want:        "",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			body := strings.Join([]string{
+				"--b",
+				"Content-Disposition: " + tt.disposition,
+				"Content-Type: image/png",
+				"",
+				"PNG",
+				"--b--",
+				"",
+			}, "\r\n")
+
+			var got Upload
+			if err := multipart.NewReader(strings.NewReader(body), "b").Decode(&got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Avatar == nil {
+				t.Fatal("expected avatar, got nil")
+			}
+			if diff := cmp.Diff(tt.want, got.Avatar.Filename); diff != "" {
+				t.Errorf("filename (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Names outside ASCII survive encoding.
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.Encode(Upload{Avatar: &multipart.File{Filename: "résumé €.pdf", Content: strings.NewReader("PDF")}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `filename="r_sum_ _.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%20%E2%82%AC.pdf`) {
+		t.Errorf("expected extended filename, got:\n%s", buf.String())
+	}
+
+	var got Upload
+	if err := multipart.NewReader(&buf, w.Boundary()).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("résumé €.pdf", got.Avatar.Filename); diff != "" {
+		t.Errorf("filename (-want +got):\n%s", diff)
+	}
+}
+
+func TestMixedFiles(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--AaB03x",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"Larry",
+		"--AaB03x",
+		`Content-Disposition: form-data; name="attachments"`,
+		"Content-Type: multipart/mixed; boundary=BbC04y",
+		"",
+		"--BbC04y",
+		`Content-Disposition: file; filename="file1.txt"`,
+		"Content-Type: text/plain",
+		"",
+		"first",
+		"--BbC04y",
+		`Content-Disposition: file; filename="file2.gif"`,
+		"Content-Type: image/gif",
+		"",
+		"second",
+		"--BbC04y--",
+		"--AaB03x--",
+		"",
+	}, "\r\n")
+
+	var got Upload
+	if err := multipart.NewReader(strings.NewReader(body), "AaB03x").Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, content(t, got.Attachments...)); diff != "" {
+		t.Errorf("content (-want +got):\n%s", diff)
+	}
+	want := Upload{
+		Title: "Larry",
+		Attachments: []*multipart.File{
+			{Filename: "file1.txt", ContentType: "text/plain", Size: 5},
+			{Filename: "file2.gif", ContentType: "image/gif", Size: 6},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// The same structure is written on request.
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.SetMixedFiles(true)
+	in := Upload{
+		Avatar: &multipart.File{Filename: "me.png", Content: strings.NewReader("PNG")},
+		Attachments: []*multipart.File{
+			{Filename: "a.txt", Content: strings.NewReader("first")},
+			{Filename: "b.txt", Content: strings.NewReader("second")},
+		},
+	}
+	if err := w.Encode(in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "multipart/mixed"); n != 1 {
+		t.Errorf("expected one multipart/mixed part, got %d", n)
+	}
+
+	got = Upload{}
+	if err := multipart.NewReader(&buf, w.Boundary()).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"PNG", "first", "second"}, content(t, append([]*multipart.File{got.Avatar}, got.Attachments...)...)); diff != "" {
+		t.Errorf("content (-want +got):\n%s", diff)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	in := Upload{
+		Title:  "report",
+		Avatar: &multipart.File{Filename: "me.png", Content: strings.NewReader("PNG")},
+		Attachments: []*multipart.File{
+			{Filename: "a.txt", Content: strings.NewReader("first")},
+		},
+	}
+
+	var written []string
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.SetProgress(func(field string, total int64) {
+		written = append(written, fmt.Sprintf("%s %d", field, total))
+	})
+	if err := w.Encode(in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"attachments[] 5", "avatar 8", "title 14"}
+	if diff := cmp.Diff(want, written); diff != "" {
+		t.Errorf("written (-want +got):\n%s", diff)
+	}
+
+	var read []string
+	reader := multipart.NewReader(&buf, w.Boundary())
+	reader.SetProgress(func(field string, total int64) {
+		read = append(read, fmt.Sprintf("%s %d", field, total))
+	})
+	var got Upload
+	if err := reader.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, read); diff != "" {
+		t.Errorf("read (-want +got):\n%s", diff)
+	}
+}
+
+func TestReader_SizeLimits(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="avatar"; filename="me.png"`,
+		"Content-Type: image/png",
+		"",
+		"PNG DATA",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	tests := map[string]struct {
+		maxFile  int64
+		maxTotal int64
+		handler  bool
+		want     *multipart.SizeError
+	}{
+		"within limits": {
+			maxFile:  8,
+			maxTotal: 13,
+		},
+		"file size": {
+			maxFile: 4,
+			want:    &multipart.SizeError{Limit: "file size", Max: 4, Field: "avatar", Filename: "me.png"},
+		},
+		"total size": {
+			maxTotal: 10,
+			want:     &multipart.SizeError{Limit: "total size", Max: 10, Field: "avatar", Filename: "me.png"},
+		},
+		"text part total size": {
+			maxTotal: 4,
+			want:     &multipart.SizeError{Limit: "total size", Max: 4, Field: "title"},
+		},
+		"handler file size": {
+			maxFile: 4,
+			handler: true,
+			want:    &multipart.SizeError{Limit: "file size", Max: 4, Field: "avatar", Filename: "me.png"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reader := multipart.NewReader(strings.NewReader(body), "b")
+			reader.SetMaxFileSize(tt.maxFile)
+			reader.SetMaxTotalSize(tt.maxTotal)
+			if tt.handler {
+				// The handler ignores the error reporting the limit.
+				reader.SetPartHandler(func(_ string, _ textproto.MIMEHeader, content io.Reader) error {
+					_, _ = io.Copy(io.Discard, content)
+					return nil
+				})
+			}
+
+			var got Upload
+			err := reader.Decode(&got)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var sizeErr *multipart.SizeError
+			if !errors.As(err, &sizeErr) {
+				t.Fatalf("expected SizeError, got: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, sizeErr); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if !errors.Is(err, multipart.ErrTooLarge) {
+				t.Errorf("expected error to match ErrTooLarge, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestReader_EmptyParts(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, "--b", `Content-Disposition: form-data; name=""`, "", "")
+	}
+	body := strings.Join(append(lines, "--b--", ""), "\r\n")
+
+	tests := map[string]struct {
+		maxParts  int
+		maxMemory int64
+		wantErr   bool
+	}{
+		"within limits": {
+			maxParts:  10,
+			maxMemory: 1 << 10,
+		},
+		"too many parts": {
+			maxParts:  9,
+			maxMemory: 1 << 10,
+			wantErr:   true,
+		},
+		"headers exceed memory": {
+			maxParts:  10,
+			maxMemory: 64,
+			wantErr:   true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reader := multipart.NewReader(strings.NewReader(body), "b")
+			reader.SetMaxParts(tt.maxParts)
+			reader.SetMaxMemory(tt.maxMemory)
+
+			err := reader.Decode(&Upload{})
+			if tt.wantErr {
+				if !errors.Is(err, multipart.ErrTooLarge) {
+					t.Errorf("expected ErrTooLarge, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReader_Rewrite(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"hello",
+		"--b",
+		`Content-Disposition: form-data; name="secret"`,
+		"",
+		"hunter2",
+		"--b",
+		`Content-Disposition: form-data; name="avatar"; filename="me.png"`,
+		"Content-Type: image/png",
+		"",
+		"PNG",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	err := multipart.NewReader(strings.NewReader(body), "b").Rewrite(w, func(name, value string) (string, bool, error) {
+		switch name {
+		case "title":
+			return strings.ToUpper(value), true, nil
+		case "secret":
+			return "", false, nil
+		}
+		return value, true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Encode(map[string]string{"items[0][name]": "pen"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Error("expected removed field not to be written")
+	}
+
+	var got Upload
+	md, err := multipart.NewReader(&buf, w.Boundary(), formenc.WithIndexedKeys()).DecodeWithMetadata(&got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, p := range md.Parts {
+		names = append(names, p.Name)
+	}
+	if diff := cmp.Diff([]string{"title", "avatar", "items[0][name]"}, names); diff != "" {
+		t.Errorf("parts (-want +got):\n%s", diff)
+	}
+	want := Upload{
+		Title:  "HELLO",
+		Items:  []Item{{Name: "pen"}},
+		Avatar: &multipart.File{Filename: "me.png", ContentType: "image/png", Size: 3},
+	}
+	if diff := cmp.Diff([]string{"PNG"}, content(t, got.Avatar)); diff != "" {
+		t.Errorf("content (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	errRejected := errors.New("rejected")
+	err = multipart.NewReader(strings.NewReader(body), "b").Rewrite(multipart.NewWriter(io.Discard), func(string, string) (string, bool, error) {
+		return "", false, errRejected
+	})
+	if !errors.Is(err, errRejected) {
+		t.Errorf("expected field function error, got: %v", err)
+	}
+}
+
+func TestDecoder_Part(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="item"`,
+		"Content-Type: application/x-www-form-urlencoded",
+		"",
+		"name=widget&qty=3",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	part, err := stdmultipart.NewReader(strings.NewReader(body), "b").NextPart()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Item
+	if err := formenc.NewDecoder(part).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Item{Name: "widget", Qty: 3}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Join([]string{
+		"--b",
+		`Content-Disposition: form-data; name="avatar"`,
+		"",
+		"not a file",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	var got Upload
+	if err := multipart.NewReader(strings.NewReader(body), "b").Decode(&got); err == nil {
+		t.Error("expected error for text part decoded as file, got nil")
+	}
+
+	reader := multipart.NewReader(strings.NewReader(body), "b")
+	reader.SetMaxMemory(4)
+	if err := reader.Decode(&got); !errors.Is(err, multipart.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader("a=b"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := multipart.NewRequestReader(r); err == nil {
+		t.Error("expected error for urlencoded request, got nil")
+	}
+}
+
+func TestReader_ForgedFileTokens(t *testing.T) {
+	t.Parallel()
+
+	type Forged struct {
+		Doc    *multipart.File `form:"doc"`
+		Avatar *multipart.File `form:"avatar"`
+	}
+
+	tests := map[string]string{
+		"negative index": "\x00file:-1",
+		"existing index": "\x00file:0",
+		"large index":    "\x00file:99",
+	}
+	for name, value := range tests {
+		value := value
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			body := strings.Join([]string{
+				"--b",
+				`Content-Disposition: form-data; name="doc"; filename="secret.txt"`,
+				"",
+				"secret",
+				"--b",
+				`Content-Disposition: form-data; name="avatar"`,
+				"",
+				value,
+				"--b--",
+				"",
+			}, "\r\n")
+
+			var got Forged
+			if err := multipart.NewReader(strings.NewReader(body), "b").Decode(&got); err == nil {
+				t.Error("expected error for text part decoded as file, got nil")
+			}
+			if got.Avatar != nil && got.Avatar.Content != nil {
+				t.Errorf("expected no avatar content, got file %q", got.Avatar.Filename)
+			}
+		})
+	}
+}
+
+func TestWriter_FileTokenInText(t *testing.T) {
+	t.Parallel()
+
+	type Note struct {
+		Text string         `form:"text"`
+		Doc  multipart.File `form:"doc"`
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	err := w.Encode(Note{
+		Text: "\x00file:0",
+		Doc:  multipart.File{Filename: "a.txt", Content: strings.NewReader("a")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := stdmultipart.NewReader(&buf, w.Boundary())
+	form, err := r.ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"\x00file:0"}, form.Value["text"]); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if len(form.File["text"]) != 0 || len(form.File["doc"]) != 1 {
+		t.Errorf("expected one file part named doc, got %v", form.File)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input           Upload
+		wantContentType string
+		wantTitle       string
+		wantAvatar      string
+	}{
+		"without files": {
+			input:           Upload{Title: "report"},
+			wantContentType: "application/x-www-form-urlencoded",
+			wantTitle:       "report",
+		},
+		"with files": {
+			input: Upload{
+				Title:  "report",
+				Avatar: &multipart.File{Filename: "me.png", Content: strings.NewReader("PNG")},
+			},
+			wantContentType: "multipart/form-data",
+			wantTitle:       "report",
+			wantAvatar:      "PNG",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := multipart.NewRequest(context.Background(), http.MethodPost, "https://example.com/upload", tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ct := req.Header.Get("Content-Type"); !strings.HasPrefix(ct, tt.wantContentType) {
+				t.Errorf("expected content type %q, got %q", tt.wantContentType, ct)
+			}
+			if req.ContentLength <= 0 {
+				t.Errorf("expected a content length, got %d", req.ContentLength)
+			}
+
+			if err := req.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := req.FormValue("title"); got != tt.wantTitle {
+				t.Errorf("expected title %q, got %q", tt.wantTitle, got)
+			}
+			if tt.wantAvatar == "" {
+				return
+			}
+			f, _, err := req.FormFile("avatar")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, _ := io.ReadAll(f)
+			if string(data) != tt.wantAvatar {
+				t.Errorf("expected avatar %q, got %q", tt.wantAvatar, data)
+			}
+		})
+	}
+}
+
+// counter counts the calls to its MarshalForm method.
+type counter struct {
+	n *int
+}
+
+func (c counter) MarshalForm() (string, error) {
+	*c.n++
+	return strconv.Itoa(*c.n), nil
+}
+
+func TestNewRequest_EncodesOnce(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Count  counter         `form:"count"`
+		Avatar *multipart.File `form:"avatar"`
+	}
+
+	tests := map[string]*multipart.File{
+		"without files": nil,
+		"with files":    {Filename: "me.png", Content: strings.NewReader("PNG")},
+	}
+	for name, avatar := range tests {
+		avatar := avatar
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var n int
+			if _, err := multipart.NewRequest(context.Background(), http.MethodPost, "https://example.com", Form{Count: counter{&n}, Avatar: avatar}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != 1 {
+				t.Errorf("expected one encoding, got %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package multipart
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// FieldFunc is called by [Reader.Rewrite] with the form name and value of
+// each text part. It returns the value to write in its place, or false to
+// remove the part.
+type FieldFunc func(name, value string) (string, bool, error)
+
+// Rewrite copies every part of the form data read by r to w, which writes it
+// with a boundary of its own, so that a service can forward an upload after
+// changing its text fields. Each text part is passed to fn, if it is not nil,
+// and written as it returns. All other parts, such as files and nested forms,
+// are streamed to w unchanged, headers and all, without being held in memory.
+//
+// The parts are written in the order they were read. Fields may be added by
+// calling [Writer.Encode] once Rewrite returns, before [Writer.Close].
+//
+// The size limits of r apply as they do to [Reader.Decode], as does its
+// progress function. Its part handler is not called.
+func (r *Reader) Rewrite(w *Writer, fn FieldFunc) error {
+	d := &decodeState{r: r, remaining: r.maxMemory, total: r.maxTotal}
+	for {
+		part, err := r.r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		if err := d.rewrite(w, part, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// rewrite copies part to w, passing its value to fn if it is a text part.
+func (d *decodeState) rewrite(w *Writer, part *multipart.Part, fn FieldFunc) error {
+	name := part.FormName()
+	var content io.Reader = part
+	if d.r.maxTotal > 0 {
+		content = d.limit(content, &d.total, &SizeError{Limit: "total size", Max: d.r.maxTotal, Field: name, Filename: part.FileName()})
+	}
+	if d.r.progress != nil {
+		content = &progressReader{r: content, field: name, fn: d.r.progress, total: &d.read}
+	}
+
+	_, mixed := mixedBoundary(part.Header)
+	text := name != "" && part.FileName() == "" && !isFile(part.Header) && !isSubForm(part.Header) && !mixed
+	if !text {
+		if d.r.maxFile > 0 && (part.FileName() != "" || isFile(part.Header) || mixed) {
+			remaining := d.r.maxFile
+			content = d.limit(content, &remaining, &SizeError{Limit: "file size", Max: d.r.maxFile, Field: name, Filename: part.FileName()})
+		}
+		pw, err := w.w.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w.track(name, pw), content)
+		if d.exceeded != nil {
+			return d.exceeded
+		}
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, d.remaining+1))
+	if d.exceeded != nil {
+		return d.exceeded
+	}
+	if err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+	if d.remaining -= int64(len(data)); d.remaining < 0 {
+		return ErrTooLarge
+	}
+
+	value := string(data)
+	if fn != nil {
+		var keep bool
+		value, keep, err = fn(name, value)
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		if !keep {
+			return nil
+		}
+	}
+	pw, err := w.w.CreatePart(part.Header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.track(name, pw), value)
+	return err
+}
@@ -0,0 +1,574 @@
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// defaultMaxMemory is the default limit on the bytes read from the form data,
+// matching [net/http.Request.ParseMultipartForm] as used by FormValue.
+const defaultMaxMemory = 32 << 20
+
+// defaultMaxParts is the default limit on the parts read from the form data,
+// matching [mime/multipart.Reader.ReadForm].
+const defaultMaxParts = 1000
+
+// tokens issues the values standing in for files while a form is encoded or
+// decoded. Each set of tokens carries a nonce drawn afresh for every encode or
+// decode, so that the value of a text part or string field cannot forge one.
+type tokens struct {
+	prefix string
+}
+
+// newTokens returns tokens for the files of the given kind.
+func newTokens(kind string) (tokens, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return tokens{}, fmt.Errorf("multipart: %w", err)
+	}
+	return tokens{prefix: "\x00" + kind + ":" + hex.EncodeToString(nonce[:]) + ":"}, nil
+}
+
+// token returns the value standing in for the file at index i.
+func (t tokens) token(i int) string {
+	return t.prefix + strconv.Itoa(i)
+}
+
+// index returns the index of the file s stands in for, reporting false if s is
+// not a token issued by t for one of the n files.
+func (t tokens) index(s string, n int) (int, bool) {
+	if !strings.HasPrefix(s, t.prefix) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(s[len(t.prefix):])
+	if err != nil || i < 0 || i >= n {
+		return 0, false
+	}
+	return i, true
+}
+
+// ErrTooLarge is returned by [Reader.Decode] when the form data exceeds the
+// limit set by [Reader.SetMaxMemory] or [Reader.SetMaxParts]. A [SizeError]
+// also matches ErrTooLarge.
+var ErrTooLarge = errors.New("multipart: form data too large")
+
+// SizeError is returned by [Reader.Decode] when a part exceeds the limit set by
+// [Reader.SetMaxFileSize] or [Reader.SetMaxTotalSize]. The part is not read
+// beyond the limit.
+type SizeError struct {
+	Limit    string // the name of the limit, "file size" or "total size"
+	Max      int64  // the largest size permitted, in bytes
+	Field    string // the form name of the part exceeding the limit
+	Filename string // the filename of the part, if it is a file
+}
+
+func (e *SizeError) Error() string {
+	s := "multipart: part " + strconv.Quote(e.Field)
+	if e.Filename != "" {
+		s += " (" + strconv.Quote(e.Filename) + ")"
+	}
+	return s + " exceeds the " + e.Limit + " limit of " + strconv.FormatInt(e.Max, 10) + " bytes"
+}
+
+// Is reports whether target is [ErrTooLarge], so that every size limit can be
+// handled alike, such as by responding 413 Request Entity Too Large.
+func (e *SizeError) Is(target error) bool {
+	return target == ErrTooLarge
+}
+
+// PartHandler is called by [Reader.Decode] with each file part of the form
+// data: the form name of the part, its headers and its content. The content is
+// read directly from the form data, and is not available once the handler
+// returns.
+type PartHandler func(name string, header textproto.MIMEHeader, content io.Reader) error
+
+// Metadata describes the form data read by [Reader.DecodeWithMetadata],
+// beyond the value produced.
+type Metadata struct {
+	// Parts lists the named parts of the form data in the order they were
+	// read, which the decoded value may not preserve.
+	Parts []PartInfo
+}
+
+// PartInfo describes a part of the form data.
+type PartInfo struct {
+	Name     string               // the form name of the part
+	Filename string               // the sanitised filename of a file part
+	Header   textproto.MIMEHeader // the headers of the part
+}
+
+// Reader decodes multipart form data into Go values.
+type Reader struct {
+	r         *multipart.Reader
+	opts      []formenc.Option
+	maxMemory int64
+	maxFile   int64
+	maxTotal  int64
+	maxParts  int
+	handler   PartHandler
+	progress  ProgressFunc
+	query     string
+}
+
+// NewReader returns a [Reader] that reads form data delimited by boundary from
+// r, decoding it with a [formenc.Codec] configured by opts.
+func NewReader(r io.Reader, boundary string, opts ...formenc.Option) *Reader {
+	return &Reader{
+		r:         multipart.NewReader(r, boundary),
+		opts:      opts,
+		maxMemory: defaultMaxMemory,
+		maxParts:  defaultMaxParts,
+	}
+}
+
+// NewRequestReader returns a [Reader] for the body of req, which must have a
+// multipart/form-data content type.
+func NewRequestReader(req *http.Request, opts ...formenc.Option) (*Reader, error) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, http.ErrNotMultipart
+	}
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, http.ErrMissingBoundary
+	}
+	return NewReader(req.Body, boundary, opts...), nil
+}
+
+// SetMaxMemory limits the total bytes of text and file content read from the
+// form data to n, together with the names and headers of its parts. The
+// default is 32 MB.
+func (r *Reader) SetMaxMemory(n int64) {
+	r.maxMemory = n
+}
+
+// SetMaxParts limits the parts read from the form data to n, including the
+// files held by multipart/mixed parts and parts without a name. The default is
+// 1000. Zero or less imposes no limit.
+func (r *Reader) SetMaxParts(n int) {
+	r.maxParts = n
+}
+
+// SetMaxFileSize limits the content of each file part to n bytes, whether it
+// is decoded into a [File] or passed to a [PartHandler]. A part exceeding it
+// stops decoding with a [SizeError]. The default, zero, imposes no limit.
+func (r *Reader) SetMaxFileSize(n int64) {
+	r.maxFile = n
+}
+
+// SetMaxTotalSize limits the content of all parts to n bytes in total,
+// including the file parts passed to a [PartHandler], which do not count
+// towards the limit set by [Reader.SetMaxMemory]. A part exceeding it stops
+// decoding with a [SizeError]. The default, zero, imposes no limit.
+func (r *Reader) SetMaxTotalSize(n int64) {
+	r.maxTotal = n
+}
+
+// SetQuery causes the urlencoded pairs of query to be decoded along with the
+// form data, preceding the pairs read from its parts, as the query string of a
+// request is decoded along with its body.
+func (r *Reader) SetQuery(query string) {
+	r.query = query
+}
+
+// SetPartHandler passes the file parts of the form data to fn as they are read,
+// rather than decoding them into [File] fields, so that large files can be
+// copied elsewhere without being held in memory. Text parts are decoded as
+// before. The content of file parts does not count towards the limit set by
+// [Reader.SetMaxMemory].
+func (r *Reader) SetPartHandler(fn PartHandler) {
+	r.handler = fn
+}
+
+// SetProgress causes fn to be called as the content of each part is read,
+// with the form name of the part and the total bytes of content read by
+// [Reader.Decode]. It is called as a [PartHandler] reads the content of file
+// parts, too.
+func (r *Reader) SetProgress(fn ProgressFunc) {
+	r.progress = fn
+}
+
+// Decode reads every part of the form data and stores the result in the value
+// pointed to by v.
+//
+// A part with the content type application/x-www-form-urlencoded and no
+// filename holds a form of its own, which is decoded into the field named by
+// the part, so that a part named "address" containing "city=Leeds" assigns
+// "address[city]". The part is decoded as it is read, without being buffered.
+//
+// A part with the content type multipart/mixed and no filename holds several
+// files, each of which is decoded as a file part named by the part.
+//
+// A file part naming a field of type [Destination] is copied to the writer
+// it creates as it is read, rather than being held in memory.
+func (r *Reader) Decode(v interface{}) error {
+	return r.decode(context.Background(), v, nil)
+}
+
+// DecodeContext behaves as [Reader.Decode], stopping between parts once ctx
+// is done and passing ctx to decode functions and validation, as
+// [formenc.Codec.UnmarshalContext] does.
+func (r *Reader) DecodeContext(ctx context.Context, v interface{}) error {
+	return r.decode(ctx, v, nil)
+}
+
+// DecodeWithMetadata behaves as [Reader.Decode], additionally returning
+// metadata describing the parts read, for protocols in which their order or
+// headers matter.
+func (r *Reader) DecodeWithMetadata(v interface{}) (*Metadata, error) {
+	md := &Metadata{}
+	return md, r.decode(context.Background(), v, md)
+}
+
+func (r *Reader) decode(ctx context.Context, v interface{}, md *Metadata) error {
+	d := &decodeState{r: r, v: v, remaining: r.maxMemory, total: r.maxTotal}
+	var err error
+	if d.fileTokens, err = newTokens("file"); err != nil {
+		return err
+	}
+	if d.streamTokens, err = newTokens("destination"); err != nil {
+		return err
+	}
+	codec, err := formenc.NewCodec(append(r.opts[:len(r.opts):len(r.opts)],
+		formenc.WithDecodeFunc(File{}, func(s string) (interface{}, error) {
+			i, ok := d.fileTokens.index(s, len(d.files))
+			if !ok {
+				return nil, errors.New("value is not a file")
+			}
+			return *d.files[i], nil
+		}),
+		formenc.WithDecodeFunc(Destination{}, func(s string) (interface{}, error) {
+			i, ok := d.streamTokens.index(s, len(d.streamed))
+			if !ok {
+				return nil, errors.New("value is not a streamed file")
+			}
+			return d.streamed[i], nil
+		}),
+	)...)
+	if err != nil {
+		return err
+	}
+	d.codec = codec
+	if r.query != "" {
+		d.pairs = append(d.pairs, r.query)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		part, err := r.r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+
+		name := part.FormName()
+		if err := d.header(name, part.Header); err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if md != nil {
+			md.Parts = append(md.Parts, PartInfo{Name: name, Filename: sanitizeFilename(part.FileName()), Header: part.Header})
+		}
+		if err := d.part(name, part); err != nil {
+			return err
+		}
+	}
+
+	if len(d.pairs) == 0 {
+		return nil
+	}
+	return codec.UnmarshalContext(ctx, []byte(strings.Join(d.pairs, "&")), v)
+}
+
+// decodeState accumulates the pairs and files read from the form data by
+// [Reader.Decode].
+type decodeState struct {
+	r     *Reader
+	v     interface{}
+	codec *formenc.Codec
+
+	pairs    []string
+	files    []*File
+	streamed []Destination
+
+	// fileTokens and streamTokens issue the values standing in for files
+	// and streamed files in pairs.
+	fileTokens   tokens
+	streamTokens tokens
+
+	remaining int64
+	total     int64
+	parts     int
+	read      int64
+
+	// exceeded is the size limit a part has exceeded, if any.
+	exceeded *SizeError
+}
+
+// part reads the part named name.
+func (d *decodeState) part(name string, part *multipart.Part) error {
+	var content io.Reader = part
+	if d.r.maxTotal > 0 {
+		content = d.limit(content, &d.total, &SizeError{Limit: "total size", Max: d.r.maxTotal, Field: name, Filename: part.FileName()})
+	}
+	if d.r.progress != nil {
+		content = &progressReader{r: content, field: name, fn: d.r.progress, total: &d.read}
+	}
+
+	if part.FileName() == "" && isSubForm(part.Header) {
+		pairs, n, err := readSubForm(d.pairs, name, io.LimitReader(content, d.remaining+1))
+		d.pairs = pairs
+		if d.exceeded != nil {
+			return d.exceeded
+		}
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		if d.remaining -= n; d.remaining < 0 {
+			return ErrTooLarge
+		}
+		return nil
+	}
+	if part.FileName() == "" {
+		if boundary, ok := mixedBoundary(part.Header); ok {
+			return d.mixed(name, content, boundary)
+		}
+	}
+	return d.value(name, part, content, part.FileName() != "" || isFile(part.Header))
+}
+
+// header counts a part named name against the limit set by
+// [Reader.SetMaxParts], and charges its name and headers against the limit set
+// by [Reader.SetMaxMemory], so that parts without content are limited too.
+func (d *decodeState) header(name string, header textproto.MIMEHeader) error {
+	d.parts++
+	if d.r.maxParts > 0 && d.parts > d.r.maxParts {
+		return ErrTooLarge
+	}
+	size := int64(len(name))
+	for key, values := range header {
+		size += int64(len(key))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	if d.remaining -= size; d.remaining < 0 {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// mixed reads the files held by a multipart/mixed part named name, as sent by
+// clients for a file input accepting more than one file. Each is decoded as
+// though it were a file part of its own named name.
+func (d *decodeState) mixed(name string, content io.Reader, boundary string) error {
+	mr := multipart.NewReader(content, boundary)
+	for {
+		file, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if d.exceeded != nil {
+			return d.exceeded
+		}
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		if err := d.header(name, file.Header); err != nil {
+			return err
+		}
+		if err := d.value(name, file, file, true); err != nil {
+			return err
+		}
+	}
+}
+
+// value reads content, the content of the part named name, as a file if file
+// is set and otherwise as text.
+func (d *decodeState) value(name string, part *multipart.Part, content io.Reader, file bool) error {
+	if file && d.r.maxFile > 0 {
+		remaining := d.r.maxFile
+		content = d.limit(content, &remaining, &SizeError{Limit: "file size", Max: d.r.maxFile, Field: name, Filename: part.FileName()})
+	}
+	if file {
+		dest, err := d.destination(name)
+		if err != nil {
+			return err
+		}
+		if dest != nil {
+			return d.stream(name, part, content, dest)
+		}
+	}
+	if d.r.handler != nil && file {
+		return d.handle(name, part, content)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, d.remaining+1))
+	if d.exceeded != nil {
+		return d.exceeded
+	}
+	if err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+	d.remaining -= int64(len(data))
+	if d.remaining < 0 {
+		return ErrTooLarge
+	}
+
+	value := string(data)
+	if file {
+		// Browsers send an empty part for file inputs left empty.
+		if part.FileName() == "" && len(data) == 0 {
+			return nil
+		}
+		value = d.fileTokens.token(len(d.files))
+		d.files = append(d.files, &File{
+			Filename:    sanitizeFilename(part.FileName()),
+			ContentType: part.Header.Get("Content-Type"),
+			Header:      part.Header,
+			Size:        int64(len(data)),
+			Content:     bytes.NewReader(data),
+		})
+	}
+	d.pairs = append(d.pairs, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	return nil
+}
+
+// handle passes the file part to the handler of the reader, unless it is an empty part
+// sent for a file input left empty.
+func (d *decodeState) handle(name string, part *multipart.Part, content io.Reader) error {
+	br := bufio.NewReader(content)
+	if part.FileName() == "" {
+		if _, err := br.Peek(1); err == io.EOF {
+			return nil
+		}
+	}
+	err := d.r.handler(name, part.Header, br)
+
+	// The handler may not return the error reporting a size limit.
+	if d.exceeded != nil {
+		return d.exceeded
+	}
+	if err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+	return nil
+}
+
+// limit returns a reader of r that stops once the bytes in remaining are
+// exhausted, returning err and recording it as the limit exceeded if r holds
+// more.
+func (d *decodeState) limit(r io.Reader, remaining *int64, err *SizeError) io.Reader {
+	return &sizeLimitReader{r: r, remaining: remaining, err: err, exceeded: &d.exceeded}
+}
+
+// sizeLimitReader reads from r until the bytes in remaining are exhausted,
+// returning err, and storing it in exceeded, if r holds more.
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining *int64
+	err       *SizeError
+	exceeded  **SizeError
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if *l.exceeded != nil {
+		return 0, *l.exceeded
+	}
+	if int64(len(p)) > *l.remaining+1 {
+		p = p[:*l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > *l.remaining {
+		n = int(*l.remaining)
+		*l.remaining = 0
+		*l.exceeded = l.err
+		return n, l.err
+	}
+	*l.remaining -= int64(n)
+	return n, err
+}
+
+// readSubForm appends the pairs of the form-urlencoded data read from r to
+// pairs, with their keys nested under name, returning the bytes read.
+func readSubForm(pairs []string, name string, r io.Reader) ([]string, int64, error) {
+	cr := &countingReader{r: r}
+	dec := formenc.NewDecoder(cr)
+	for {
+		err := dec.DecodeFunc(func(key, value string) error {
+			pairs = append(pairs, url.QueryEscape(nestKey(name, key))+"="+url.QueryEscape(value))
+			return nil
+		})
+		if err == io.EOF {
+			return pairs, cr.n, nil
+		}
+		if err != nil {
+			return pairs, cr.n, err
+		}
+	}
+}
+
+// nestKey returns key nested under name, so that "tags[]" nested under
+// "post" is "post[tags][]".
+func nestKey(name, key string) string {
+	first, rest := key, ""
+	if i := strings.IndexByte(key, '['); i > 0 {
+		first, rest = key[:i], key[i:]
+	}
+	return name + "[" + first + "]" + rest
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isSubForm reports whether a part holds form-urlencoded data.
+func isSubForm(h textproto.MIMEHeader) bool {
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// mixedBoundary returns the boundary of a part holding multipart/mixed data.
+func mixedBoundary(h textproto.MIMEHeader) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// isFile reports whether a part without a filename is nonetheless a file,
+// as parts with a content type other than text are.
+func isFile(h textproto.MIMEHeader) bool {
+	ct := h.Get("Content-Type")
+	return ct != "" && !strings.HasPrefix(ct, "text/plain")
+}
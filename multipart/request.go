@@ -0,0 +1,70 @@
+package multipart
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// NewRequest returns an HTTP request for method and target, a URL, carrying v
+// encoded by a [formenc.Codec] configured by opts. If v holds any [File] the
+// body is multipart form data, with a matching Content-Type and a
+// Content-Length. Otherwise the request carries urlencoded form data, as built
+// by [formenc.Codec.NewRequest]. Either way v is encoded once.
+//
+// The content of the files is read into memory, so that the length of the
+// body is known and the request can be retried.
+func NewRequest(ctx context.Context, method, target string, v interface{}, opts ...formenc.Option) (*http.Request, error) {
+	data, files, t, err := marshal(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return newURLEncodedRequest(ctx, method, target, data)
+	}
+
+	var body bytes.Buffer
+	w := NewWriter(&body, opts...)
+	if err := w.write(data, files, t); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req, nil
+}
+
+// newURLEncodedRequest returns a request carrying data, urlencoded form data,
+// as [formenc.Codec.NewRequest] does.
+func newURLEncodedRequest(ctx context.Context, method, target string, data []byte) (*http.Request, error) {
+	if method == http.MethodGet || method == http.MethodHead {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(data) == 0:
+		case u.RawQuery == "":
+			u.RawQuery = string(data)
+		default:
+			u.RawQuery += "&" + string(data)
+		}
+		return http.NewRequestWithContext(ctx, method, u.String(), nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
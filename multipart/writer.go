@@ -0,0 +1,298 @@
+package multipart
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// Writer encodes Go values as multipart form data.
+type Writer struct {
+	w     *multipart.Writer
+	opts  []formenc.Option
+	mixed bool
+
+	progress ProgressFunc
+	written  int64
+}
+
+// NewWriter returns a [Writer] that writes form data to w, encoding values
+// with a [formenc.Codec] configured by opts. The form data is complete once
+// [Writer.Close] is called.
+func NewWriter(w io.Writer, opts ...formenc.Option) *Writer {
+	return &Writer{w: multipart.NewWriter(w), opts: opts}
+}
+
+// Boundary returns the boundary separating parts.
+func (w *Writer) Boundary() string {
+	return w.w.Boundary()
+}
+
+// FormDataContentType returns the Content-Type for an HTTP request carrying
+// the form data.
+func (w *Writer) FormDataContentType() string {
+	return w.w.FormDataContentType()
+}
+
+// SetMixedFiles causes the files of a field holding more than one file to be
+// written as a single multipart/mixed part named by the field, as the HTML 4
+// specification describes, rather than as a file part each. The default is
+// false, as few servers other than those using [Reader] understand the
+// nested parts.
+func (w *Writer) SetMixedFiles(mixed bool) {
+	w.mixed = mixed
+}
+
+// SetProgress causes fn to be called as the content of each part is written,
+// with the form name of the part and the total bytes of content written by
+// w.
+func (w *Writer) SetProgress(fn ProgressFunc) {
+	w.progress = fn
+}
+
+// track returns pw, the writer of the content of the part named key,
+// reporting the bytes written to it if a progress function is set.
+func (w *Writer) track(key string, pw io.Writer) io.Writer {
+	if w.progress == nil {
+		return pw
+	}
+	return &progressWriter{w: pw, field: key, fn: w.progress, total: &w.written}
+}
+
+// Encode writes the parts encoding v. Fields of type [File] are written as
+// file parts and all others as text parts, named by the keys formenc would
+// produce. Encode may be called more than once before [Writer.Close].
+func (w *Writer) Encode(v interface{}) error {
+	data, files, t, err := marshal(v, w.opts)
+	if err != nil {
+		return err
+	}
+	return w.write(data, files, t)
+}
+
+// write writes the parts of data, as returned by marshal with files and t.
+func (w *Writer) write(data []byte, files []File, t tokens) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	type field struct {
+		key   string
+		value string
+	}
+	var fields []field
+	for _, s := range strings.Split(string(data), "&") {
+		rawKey, rawValue, _ := strings.Cut(s, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+		fields = append(fields, field{key, value})
+	}
+
+	for i := 0; i < len(fields); i++ {
+		key, value := fields[i].key, fields[i].value
+		if _, ok := t.index(value, len(files)); !ok {
+			pw, err := w.w.CreateFormField(key)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w.track(key, pw), value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Files following one another under the same key are the files of
+		// one field.
+		var group []File
+		for ; i < len(fields) && fields[i].key == key; i++ {
+			j, ok := t.index(fields[i].value, len(files))
+			if !ok {
+				break
+			}
+			group = append(group, files[j])
+		}
+		i--
+
+		if w.mixed && len(group) > 1 {
+			if err := w.writeMixed(key, group); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, f := range group {
+			if err := w.writeFile(key, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// marshal returns the urlencoded form of v by a codec configured by opts, in
+// which the values of fields of type [File] are tokens, issued by the tokens
+// returned, referring to the files returned.
+func marshal(v interface{}, opts []formenc.Option) ([]byte, []File, tokens, error) {
+	t, err := newTokens("file")
+	if err != nil {
+		return nil, nil, tokens{}, err
+	}
+
+	var files []File
+	codec, err := formenc.NewCodec(append(opts[:len(opts):len(opts)], formenc.WithEncodeFunc(File{}, func(v interface{}) (string, error) {
+		files = append(files, v.(File))
+		return t.token(len(files) - 1), nil
+	}))...)
+	if err != nil {
+		return nil, nil, tokens{}, err
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, nil, tokens{}, err
+	}
+	return data, files, t, nil
+}
+
+// writeFile writes f as a file part named key.
+func (w *Writer) writeFile(key string, f File) error {
+	return w.writeFilePart(w.w, key, fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(key)), f)
+}
+
+// writeMixed writes files as a multipart/mixed part named key, holding a part
+// for each file.
+func (w *Writer) writeMixed(key string, files []File) error {
+	boundary := multipart.NewWriter(nil).Boundary()
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(key)))
+	h.Set("Content-Type", "multipart/mixed; boundary="+boundary)
+	pw, err := w.w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	mw := multipart.NewWriter(pw)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := w.writeFilePart(mw, key, "file", f); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeFilePart writes f, a file of the field key, to mw as a part with the
+// disposition given, to which its filename is added.
+func (w *Writer) writeFilePart(mw *multipart.Writer, key, disposition string, f File) error {
+	h := make(textproto.MIMEHeader)
+	for k, v := range f.Header {
+		h[k] = v
+	}
+	h.Set("Content-Disposition", disposition+filenameParams(f.Filename))
+	content, contentType := f.Content, f.ContentType
+	if contentType == "" {
+		var err error
+		content, contentType, err = sniff(content)
+		if err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+	}
+	h.Set("Content-Type", contentType)
+
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if content != nil {
+		if _, err := io.Copy(w.track(key, pw), content); err != nil {
+			return fmt.Errorf("multipart: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close finishes the form data by writing the trailing boundary.
+func (w *Writer) Close() error {
+	return w.w.Close()
+}
+
+// sniff returns the content type of content, detected from its first 512
+// bytes by [net/http.DetectContentType], together with a reader of the whole
+// content. Empty content is application/octet-stream.
+func sniff(content io.Reader) (io.Reader, string, error) {
+	if content == nil {
+		return nil, "application/octet-stream", nil
+	}
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, "", err
+	}
+	if n == 0 {
+		return content, "application/octet-stream", nil
+	}
+	return io.MultiReader(bytes.NewReader(buf[:n]), content), http.DetectContentType(buf[:n]), nil
+}
+
+// filenameParams returns the Content-Disposition parameters naming filename.
+// A filename outside printable ASCII is written as an RFC 5987 extended
+// parameter, following a plain parameter in which other characters are
+// replaced, for readers that do not understand it.
+func filenameParams(filename string) string {
+	plain := true
+	for i := 0; i < len(filename); i++ {
+		if filename[i] < ' ' || filename[i] > '~' {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return fmt.Sprintf(`; filename="%s"`, escapeQuotes(filename))
+	}
+
+	fallback := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`; filename="%s"; filename*=UTF-8''%s`, escapeQuotes(fallback), extendedValue(filename))
+}
+
+// extendedValue percent-encodes s as the value of an RFC 5987 extended
+// parameter, leaving only the attr-char set unescaped.
+func extendedValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
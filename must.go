@@ -0,0 +1,31 @@
+package formenc
+
+// MustMarshal is like [Marshal] but panics if v cannot be encoded. It is
+// intended for tests and for values known to be valid, such as those of
+// package-level variables, as [text/template.Must] is.
+func MustMarshal(v interface{}) []byte {
+	return defaultCodec.MustMarshal(v)
+}
+
+// MustUnmarshal is like [Unmarshal] but panics if data cannot be decoded into
+// v. It is intended for tests and for fixed input known to be valid.
+func MustUnmarshal(data []byte, v interface{}) {
+	defaultCodec.MustUnmarshal(data, v)
+}
+
+// MustMarshal is like [Codec.Marshal] but panics if v cannot be encoded.
+func (c *Codec) MustMarshal(v interface{}) []byte {
+	data, err := c.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// MustUnmarshal is like [Codec.Unmarshal] but panics if data cannot be
+// decoded into v.
+func (c *Codec) MustUnmarshal(data []byte, v interface{}) {
+	if err := c.Unmarshal(data, v); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,42 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestMustMarshal(t *testing.T) {
+	t.Parallel()
+
+	got := formenc.MustMarshal(map[string]string{"name": "john"})
+	if diff := cmp.Diff([]byte("name=john"), got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for value that cannot be encoded")
+		}
+	}()
+	formenc.MustMarshal(make(chan int))
+}
+
+func TestMustUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]string
+	formenc.MustUnmarshal([]byte("name=john"), &got)
+	if diff := cmp.Diff(map[string]string{"name": "john"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for value that cannot be decoded")
+		}
+	}()
+	formenc.MustUnmarshal([]byte("name=john"), got)
+}
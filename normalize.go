@@ -0,0 +1,134 @@
+package formenc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithNFCKeys configures a [Codec] to convert keys to Unicode Normalization
+// Form C before matching them against struct tags. Browsers on some platforms
+// submit text in decomposed form, so a key such as "café" may otherwise fail
+// to match a tag written with a precomposed "é".
+func WithNFCKeys() Option {
+	return func(c *Codec) error {
+		c.nfcKeys = true
+		return nil
+	}
+}
+
+// WithNFCValues configures a [Codec] to convert decoded values to Unicode
+// Normalization Form C, so that equal text compares equal however it was
+// submitted.
+func WithNFCValues() Option {
+	return func(c *Codec) error {
+		c.nfcValues = true
+		return nil
+	}
+}
+
+// normalize converts the keys and values of entries to NFC, as configured.
+func (c *Codec) normalize(entries []entry) {
+	for i := range entries {
+		e := &entries[i]
+		if c.nfcKeys {
+			e.key = norm.NFC.String(e.key)
+			for j := range e.path {
+				e.path[j].Key = norm.NFC.String(e.path[j].Key)
+			}
+		}
+		if c.nfcValues {
+			e.value = norm.NFC.String(e.value)
+		}
+	}
+}
+
+// WithKeyNormalizer configures a [Codec] to pass each key through fn before
+// the key is parsed into a path, so that keys can be renamed without
+// preprocessing the form data. fn receives and returns the unescaped key, for
+// example to strip a vendor prefix or to convert "firstName" into
+// "first_name". Keys that fn maps to the empty string are dropped.
+func WithKeyNormalizer(fn func(string) string) Option {
+	return func(c *Codec) error {
+		if fn == nil {
+			return fmt.Errorf("form: key normalizer must not be nil")
+		}
+		c.keyNormalizer = fn
+		return nil
+	}
+}
+
+// normalizeKeys returns query with each key replaced by the result of the key
+// normalizer. Keys that cannot be unescaped are left unchanged for the parser
+// to report.
+func (c *Codec) normalizeKeys(query string) string {
+	pairs := strings.Split(query, "&")
+	out := pairs[:0]
+	for _, s := range pairs {
+		// Spaces following a separator are significant to some parsers.
+		trimmed := strings.TrimLeft(s, " ")
+		rawKey, value, hasValue := strings.Cut(trimmed, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if trimmed == "" || err != nil {
+			out = append(out, s)
+			continue
+		}
+
+		key = c.keyNormalizer(key)
+		if key == "" {
+			continue
+		}
+		s = s[:len(s)-len(trimmed)] + url.QueryEscape(key)
+		if hasValue {
+			s += "=" + value
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, "&")
+}
+
+// WithLiteralPlusKeys configures a [Codec] to treat '+' in keys as a literal
+// plus sign rather than as an encoded space, for clients that percent-encode
+// keys but leave '+' unescaped. Spaces in encoded keys are written as "%20".
+func WithLiteralPlusKeys() Option {
+	return func(c *Codec) error {
+		c.literalPlusKeys = true
+		return nil
+	}
+}
+
+// WithLiteralPlusValues configures a [Codec] to treat '+' in values as a
+// literal plus sign rather than as an encoded space. Spaces in encoded values
+// are written as "%20".
+func WithLiteralPlusValues() Option {
+	return func(c *Codec) error {
+		c.literalPlusValues = true
+		return nil
+	}
+}
+
+// escapePlus returns query with '+' escaped in keys, values or both, as
+// configured, so that the parser does not decode it as a space.
+func (c *Codec) escapePlus(query string) string {
+	if !strings.Contains(query, "+") {
+		return query
+	}
+
+	pairs := strings.Split(query, "&")
+	for i, s := range pairs {
+		key, value, hasValue := strings.Cut(s, "=")
+		if c.literalPlusKeys {
+			key = strings.ReplaceAll(key, "+", "%2B")
+		}
+		if c.literalPlusValues {
+			value = strings.ReplaceAll(value, "+", "%2B")
+		}
+		if hasValue {
+			key += "=" + value
+		}
+		pairs[i] = key
+	}
+	return strings.Join(pairs, "&")
+}
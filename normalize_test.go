@@ -0,0 +1,209 @@
+package formenc_test
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Cafe struct {
+	Name string `form:"café"`
+}
+
+func TestWithNFC(t *testing.T) {
+	t.Parallel()
+
+	// "cafe" followed by a combining acute accent, as submitted in NFD.
+	const decomposed = "cafe\u0301"
+
+	tests := map[string]struct {
+		opts    []formenc.Option
+		input   string
+		want    Cafe
+		wantErr bool
+	}{
+		"decomposed key without normalization": {
+			input:   decomposed + "=x",
+			wantErr: true,
+		},
+		"decomposed key": {
+			opts:  []formenc.Option{formenc.WithNFCKeys()},
+			input: decomposed + "=" + decomposed,
+			want:  Cafe{Name: decomposed},
+		},
+		"decomposed key and value": {
+			opts:  []formenc.Option{formenc.WithNFCKeys(), formenc.WithNFCValues()},
+			input: decomposed + "=" + decomposed,
+			want:  Cafe{Name: "caf\u00e9"},
+		},
+		"percent-encoded decomposed key": {
+			opts:  []formenc.Option{formenc.WithNFCKeys()},
+			input: "cafe%CC%81=x",
+			want:  Cafe{Name: "x"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Cafe
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithKeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	snake := func(key string) string {
+		var b strings.Builder
+		for _, r := range key {
+			if unicode.IsUpper(r) {
+				b.WriteByte('_')
+				r = unicode.ToLower(r)
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		fn    func(string) string
+		input string
+		want  interface{}
+	}{
+		"camel case to snake case": {
+			fn:    snake,
+			input: "name=john&address[zipCode]=12345",
+			want: map[string]interface{}{
+				"name":    "john",
+				"address": map[string]interface{}{"zip_code": "12345"},
+			},
+		},
+		"strip vendor prefix": {
+			fn:    func(key string) string { return strings.TrimPrefix(key, "acme_") },
+			input: "acme_user%5Bname%5D=john&acme_user[tags][]=a&acme_user[tags][]=b",
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name": "john",
+					"tags": []interface{}{"a", "b"},
+				},
+			},
+		},
+		"drop keys mapped to empty": {
+			fn: func(key string) string {
+				if strings.HasPrefix(key, "_") {
+					return ""
+				}
+				return key
+			},
+			input: "_csrf=token&name=john&_method=put",
+			want:  map[string]interface{}{"name": "john"},
+		},
+		"rack separators": {
+			opts:  []formenc.Option{formenc.WithRackCompat()},
+			fn:    strings.ToLower,
+			input: "NAME=john& Tags[]=a",
+			want: map[string]interface{}{
+				"name": "john",
+				"tags": []interface{}{"a"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(append(tt.opts, formenc.WithKeyNormalizer(tt.fn))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, interface{}(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithKeyNormalizer(nil)); err == nil {
+		t.Error("expected error for nil normalizer, got nil")
+	}
+}
+
+func TestWithLiteralPlus(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts    []formenc.Option
+		input   string
+		want    map[string]string
+		encoded string
+	}{
+		"default": {
+			input:   "c++=a+b",
+			want:    map[string]string{"c  ": "a b"},
+			encoded: "c++=a+b",
+		},
+		"literal plus in keys": {
+			opts:    []formenc.Option{formenc.WithLiteralPlusKeys()},
+			input:   "c++=a+b",
+			want:    map[string]string{"c++": "a b"},
+			encoded: "c%2B%2B=a+b",
+		},
+		"literal plus in keys and values": {
+			opts:    []formenc.Option{formenc.WithLiteralPlusKeys(), formenc.WithLiteralPlusValues()},
+			input:   "c++=a+b%20c",
+			want:    map[string]string{"c++": "a+b c"},
+			encoded: "c%2B%2B=a%2Bb%20c",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]string
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+
+			encoded, err := codec.Marshal(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.encoded, string(encoded)); diff != "" {
+				t.Errorf("encoded (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
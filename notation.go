@@ -0,0 +1,64 @@
+package formenc
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// NotationPolicy determines how a key used with both array and map notation,
+// as in "a[]=x&a[k]=y", is decoded into an interface value, where the type of
+// the value is inferred from the keys. A [Codec] configured with [WithStrict]
+// rejects such keys with an [UnmarshalTypeError] whatever its policy.
+type NotationPolicy int
+
+const (
+	// NotationMapWins decodes the key as a map, so that the elements given
+	// with array notation become entries keyed by their position: the first
+	// unused index for an appended element, as for "a[]". "a[]=x&a[k]=y"
+	// decodes as {"0": "x", "k": "y"}, whichever key comes first. This is the
+	// default.
+	NotationMapWins NotationPolicy = iota
+
+	// NotationLastWins discards the value decoded so far whenever a key
+	// switches notation, so that "a[]=x&a[k]=y" decodes as {"k": "y"} and
+	// "a[k]=y&a[]=x" as ["x"].
+	NotationLastWins
+)
+
+// WithMixedNotation configures how a [Codec] decodes a key used with both
+// array and map notation into an interface value.
+func WithMixedNotation(policy NotationPolicy) Option {
+	return func(c *Codec) error {
+		if policy < NotationMapWins || policy > NotationLastWins {
+			return fmt.Errorf("form: unknown notation policy %d", policy)
+		}
+		c.notation = policy
+		return nil
+	}
+}
+
+// sliceToMap returns the elements of s keyed by their position, leaving out
+// the nil elements left by positions never assigned.
+func sliceToMap(s []interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(s))
+	for i, elem := range s {
+		if elem != nil {
+			m[strconv.Itoa(i)] = elem
+		}
+	}
+	return m
+}
+
+// elementKey returns the key of m under which the array element addressed by
+// seg is stored: its position, or the first unused index for an appended
+// element.
+func elementKey(m map[string]interface{}, seg pathSegment) string {
+	if seg.Pos >= 0 {
+		return strconv.Itoa(seg.Pos)
+	}
+	for i := 0; ; i++ {
+		if _, ok := m[strconv.Itoa(i)]; !ok {
+			return strconv.Itoa(i)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithMixedNotation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy formenc.NotationPolicy
+		input  string
+		want   map[string]interface{}
+	}{
+		"map wins after array": {
+			policy: formenc.NotationMapWins,
+			input:  "a[]=x&a[k]=y",
+			want:   map[string]interface{}{"a": map[string]interface{}{"0": "x", "k": "y"}},
+		},
+		"map wins before array": {
+			policy: formenc.NotationMapWins,
+			input:  "a[k]=y&a[]=x",
+			want:   map[string]interface{}{"a": map[string]interface{}{"0": "x", "k": "y"}},
+		},
+		"map wins with further elements": {
+			policy: formenc.NotationMapWins,
+			input:  "a[]=x&a[k]=y&a[]=z",
+			want:   map[string]interface{}{"a": map[string]interface{}{"0": "x", "1": "z", "k": "y"}},
+		},
+		"map wins when nested": {
+			policy: formenc.NotationMapWins,
+			input:  "u[a][]=x&u[a][k][z]=y",
+			want: map[string]interface{}{
+				"u": map[string]interface{}{
+					"a": map[string]interface{}{"0": "x", "k": map[string]interface{}{"z": "y"}},
+				},
+			},
+		},
+		"last wins with map": {
+			policy: formenc.NotationLastWins,
+			input:  "a[]=x&a[]=z&a[k]=y",
+			want:   map[string]interface{}{"a": map[string]interface{}{"k": "y"}},
+		},
+		"last wins with array": {
+			policy: formenc.NotationLastWins,
+			input:  "a[k]=y&a[]=x",
+			want:   map[string]interface{}{"a": []interface{}{"x"}},
+		},
+		"single notation": {
+			policy: formenc.NotationLastWins,
+			input:  "a[]=x&a[]=z&b[k]=y",
+			want: map[string]interface{}{
+				"a": []interface{}{"x", "z"},
+				"b": map[string]interface{}{"k": "y"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithMixedNotation(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithMixedNotation(formenc.NotationPolicy(-1))); err == nil {
+		t.Error("expected error for unknown policy, got nil")
+	}
+}
+
+func TestWithMixedNotation_Strict(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []formenc.NotationPolicy{formenc.NotationMapWins, formenc.NotationLastWins} {
+		codec, err := formenc.NewCodec(formenc.WithStrict(), formenc.WithMixedNotation(policy))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, input := range []string{"a[]=x&a[k]=y", "a[k]=y&a[]=x"} {
+			var got map[string]interface{}
+			err := codec.Unmarshal([]byte(input), &got)
+			var typeErr *formenc.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("%s: expected UnmarshalTypeError, got: %v", input, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ParameterStyle is an OpenAPI 3 query parameter serialisation style.
+type ParameterStyle string
+
+// Query parameter styles defined by OpenAPI 3.
+const (
+	// StyleForm writes arrays as repeated keys, id=3&id=4, or comma
+	// separated values when not exploded, id=3,4. Objects are written as
+	// their properties, role=admin, or as comma separated property names and
+	// values when not exploded, id=role,admin.
+	StyleForm ParameterStyle = "form"
+
+	// StyleSpaceDelimited joins array elements with spaces, id=3%204.
+	StyleSpaceDelimited ParameterStyle = "spaceDelimited"
+
+	// StylePipeDelimited joins array elements with pipes, id=3|4.
+	StylePipeDelimited ParameterStyle = "pipeDelimited"
+
+	// StyleDeepObject writes object properties in brackets, id[role]=admin.
+	StyleDeepObject ParameterStyle = "deepObject"
+)
+
+// delimiter returns the separator placed between values that are not
+// exploded.
+func (s ParameterStyle) delimiter() (string, error) {
+	switch s {
+	case StyleForm:
+		return ",", nil
+	case StyleSpaceDelimited:
+		return " ", nil
+	case StylePipeDelimited:
+		return "|", nil
+	case StyleDeepObject:
+		return "", nil
+	}
+	return "", fmt.Errorf("form: unknown parameter style %q", string(s))
+}
+
+// WithParameterStyle configures a [Codec] to serialise struct fields using the
+// given OpenAPI 3 style, unless a field selects its own style with the style
+// and explode tag flags:
+//
+//	IDs []int `form:"id,style=pipeDelimited"`
+//	Filter Filter `form:"filter,style=deepObject"`
+//	Tags []string `form:"tags,style=form,explode=false"`
+//
+// As in OpenAPI, explode defaults to true for the form style and false for all
+// others when set through a tag. Styled arrays and objects may only contain
+// scalar values.
+func WithParameterStyle(style ParameterStyle, explode bool) Option {
+	return func(c *Codec) error {
+		if _, err := style.delimiter(); err != nil {
+			return err
+		}
+		c.style = style
+		c.explode = explode
+		return nil
+	}
+}
+
+// fieldStyle returns the style used for a struct field, or an empty style if
+// the field is encoded normally.
+func (c *Codec) fieldStyle(t *tag) (ParameterStyle, bool) {
+	style, explode := c.style, c.explode
+	if t.Style != "" {
+		style, explode = t.Style, t.Style == StyleForm
+	}
+	if t.Explode != nil {
+		if style == "" {
+			style = StyleForm
+		}
+		explode = *t.Explode
+	}
+	return style, explode
+}
+
+// marshalStyled encodes the struct field name, holding v, using style.
+func (c *Codec) marshalStyled(e *encodeState, path []pathSegment, name string, v reflect.Value, style ParameterStyle, explode bool) error {
+	delim, err := style.delimiter()
+	if err != nil {
+		return err
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	fieldPath := append(path[:len(path):len(path)], pathSegment{Key: name})
+	key := c.renderer.render(fieldPath)
+	if s, ok, err := c.formatScalar(v); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		e.add(fieldPath, key, s)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s, err := c.formatStyledScalar(v.Index(i), style)
+			if err != nil {
+				return err
+			}
+			values = append(values, s)
+		}
+		if style == StyleDeepObject {
+			return fmt.Errorf("form: style %s cannot encode %v", style, v.Type())
+		}
+		if explode {
+			for _, s := range values {
+				e.add(fieldPath, key, s)
+			}
+			return nil
+		}
+		e.add(fieldPath, key, strings.Join(values, delim))
+
+	case reflect.Struct, reflect.Map:
+		props, err := c.properties(v, style)
+		if err != nil {
+			return err
+		}
+		switch {
+		case style == StyleDeepObject:
+			for _, p := range props {
+				propPath := append(fieldPath, pathSegment{Key: p.key, Map: true})
+				e.add(propPath, c.renderer.render(propPath), p.value)
+			}
+		case explode:
+			for _, p := range props {
+				propPath := append(path[:len(path):len(path)], pathSegment{Key: p.key})
+				e.add(propPath, c.renderer.render(propPath), p.value)
+			}
+		default:
+			values := make([]string, 0, len(props)*2)
+			for _, p := range props {
+				values = append(values, p.key, p.value)
+			}
+			e.add(fieldPath, key, strings.Join(values, delim))
+		}
+
+	default:
+		return fmt.Errorf("form: style %s cannot encode %v", style, v.Type())
+	}
+	return nil
+}
+
+// properties returns the names and scalar values of the fields of a struct,
+// or the entries of a map, in the order they are encoded.
+func (c *Codec) properties(v reflect.Value, style ParameterStyle) ([]pair, error) {
+	var props []pair
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, k := range keys {
+			s, err := c.formatStyledScalar(v.MapIndex(k), style)
+			if err != nil {
+				return nil, err
+			}
+			props = append(props, pair{key: k.String(), value: s})
+		}
+		return props, nil
+	}
+
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		tag := tags[i]
+		fv := v.Field(i)
+		if tag.Ignore || tag.Name == "" || tag.WriteOnly || omits(tag, fv) || !c.inGroups(tag) {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer && fv.IsNil() {
+			continue
+		}
+		s, err := c.formatStyledScalar(fv, style)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, pair{key: tag.Name, value: s})
+	}
+	return props, nil
+}
+
+// formatStyledScalar formats an element of a styled array or object, which
+// must be a scalar.
+func (c *Codec) formatStyledScalar(v reflect.Value, style ParameterStyle) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	s, ok, err := c.formatScalar(v)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("form: style %s cannot encode nested %v", style, v.Type())
+	}
+	return s, nil
+}
+
+// formatScalar formats v as a single value, reporting false if v is a
+// composite value that cannot be.
+func (c *Codec) formatScalar(v reflect.Value) (string, bool, error) {
+	if fn, ok := c.encoders[v.Type()]; ok {
+		s, err := fn(v.Interface())
+		return s, true, err
+	}
+	if m, ok := asMarshaler(v); ok {
+		s, err := m.MarshalForm()
+		return s, true, err
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface, reflect.Pointer:
+		return "", false, nil
+	}
+	s, err := getScalar(v)
+	return s, true, err
+}
+
+// assignStyled decodes val into the struct field v using style.
+func (c *Codec) assignStyled(v reflect.Value, style ParameterStyle, explode bool, val string) error {
+	delim, err := style.delimiter()
+	if err != nil {
+		return err
+	}
+
+	fv := deref(v)
+	if c.decodesLeaf(fv) || explode || delim == "" {
+		return c.assign(fv, nil, val)
+	}
+	c.planLeaf(fv.Type(), string(style))
+
+	switch fv.Kind() {
+	case reflect.Slice:
+		for _, part := range strings.Split(val, delim) {
+			if err := c.assign(fv, nil, part); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Struct, reflect.Map:
+		parts := strings.Split(val, delim)
+		if len(parts)%2 != 0 {
+			return &UnmarshalTypeError{Value: val, Type: fv.Type(), Err: fmt.Errorf("style %s expects property names and values in pairs", style)}
+		}
+		for i := 0; i < len(parts); i += 2 {
+			if err := c.assign(fv, []pathSegment{{Key: parts[i], Map: true}}, parts[i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return c.assign(fv, nil, val)
+}
+
+// assignExploded assigns key to a property of an exploded object field of the
+// struct v, reporting whether such a field was found. Fields holding structs
+// are preferred over those holding maps, which accept any key.
+func (c *Codec) assignExploded(v reflect.Value, key string, path []pathSegment, val string) (bool, error) {
+	var catchAll reflect.Value
+	var catchAllName string
+
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		tag := tags[i]
+		if tag.Ignore || tag.Name == "" || !v.Field(i).CanSet() {
+			continue
+		}
+		if style, explode := c.fieldStyle(tag); style == "" || style == StyleDeepObject || !explode {
+			continue
+		}
+
+		t := v.Field(i).Type()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			if f, _ := c.findStructField(reflect.New(t).Elem(), key); f.IsValid() {
+				c.planField(v, tag.Name)
+				return true, c.assign(v.Field(i), append([]pathSegment{{Key: key}}, path...), val)
+			}
+		case reflect.Map:
+			if !catchAll.IsValid() {
+				catchAll, catchAllName = v.Field(i), tag.Name
+			}
+		}
+	}
+
+	if catchAll.IsValid() {
+		c.planField(v, catchAllName)
+		return true, c.assign(catchAll, append([]pathSegment{{Key: key, Map: true}}, path...), val)
+	}
+	return false, nil
+}
@@ -0,0 +1,146 @@
+package formenc_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Color struct {
+	R int `form:"R"`
+	G int `form:"G"`
+	B int `form:"B"`
+}
+
+type StyledQuery struct {
+	ID     []int             `form:"id,style=form,explode=false"`
+	Space  []int             `form:"space,style=spaceDelimited"`
+	Pipe   []int             `form:"pipe,style=pipeDelimited"`
+	Tags   []string          `form:"tags,style=form"`
+	Color  Color             `form:"color,style=form,explode=false"`
+	Filter map[string]string `form:"filter,style=deepObject"`
+	Point  *Color            `form:"point,style=form"`
+}
+
+func TestCodec_ParameterStyle_Tags(t *testing.T) {
+	t.Parallel()
+
+	query := StyledQuery{
+		ID:     []int{3, 4, 5},
+		Space:  []int{1, 2},
+		Pipe:   []int{6, 7},
+		Tags:   []string{"a", "b"},
+		Color:  Color{R: 100, G: 200, B: 150},
+		Filter: map[string]string{"role": "admin", "name": "Alex"},
+		Point:  &Color{R: 1, G: 2, B: 3},
+	}
+
+	got, err := formenc.EncodeToString(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "B=3&G=2&R=1&color=R%2C100%2CG%2C200%2CB%2C150" +
+		"&filter%5Bname%5D=Alex&filter%5Brole%5D=admin" +
+		"&id=3%2C4%2C5&pipe=6%7C7&space=1+2&tags=a&tags=b"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var decoded StyledQuery
+	if err := formenc.DecodeString(got, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(query, decoded); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestCodec_ParameterStyle_Codec(t *testing.T) {
+	t.Parallel()
+
+	type Query struct {
+		IDs    []int `form:"ids"`
+		Color  Color `form:"color"`
+		Exact  []int `form:"exact,style=form"`
+		Search string
+	}
+
+	tests := map[string]struct {
+		style   formenc.ParameterStyle
+		explode bool
+		want    string
+	}{
+		"form exploded": {
+			style:   formenc.StyleForm,
+			explode: true,
+			want:    "B=3&G=2&R=1&Search=x&exact=7&exact=8&ids=1&ids=2",
+		},
+		"form": {
+			style: formenc.StyleForm,
+			want:  "Search=x&color=R%2C1%2CG%2C2%2CB%2C3&exact=7&exact=8&ids=1%2C2",
+		},
+		"pipe delimited": {
+			style: formenc.StylePipeDelimited,
+			want:  "Search=x&color=R%7C1%7CG%7C2%7CB%7C3&exact=7&exact=8&ids=1%7C2",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithParameterStyle(tt.style, tt.explode))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			query := Query{
+				IDs:    []int{1, 2},
+				Color:  Color{R: 1, G: 2, B: 3},
+				Exact:  []int{7, 8},
+				Search: "x",
+			}
+
+			got, err := codec.Marshal(query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+
+			var decoded Query
+			if err := codec.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(query, decoded); diff != "" {
+				t.Errorf("round trip (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodec_ParameterStyle_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := formenc.NewCodec(formenc.WithParameterStyle("matrix", false)); err == nil {
+		t.Error("expected error for unknown style, got nil")
+	}
+
+	type Nested struct {
+		Users []User `form:"users,style=form"`
+	}
+	if _, err := formenc.Marshal(Nested{Users: []User{{Name: "a"}}}); err == nil {
+		t.Error("expected error for nested values, got nil")
+	}
+
+	type Unpaired struct {
+		Color Color `form:"color,style=form,explode=false"`
+	}
+	if err := formenc.DecodeString("color=R,1,G", &Unpaired{}); err == nil {
+		t.Error("expected error for unpaired properties, got nil")
+	}
+}
@@ -0,0 +1,235 @@
+package formenc
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// OpenAPIParameter is an OpenAPI 3 parameter object describing a query
+// parameter. It marshals to JSON in the form expected by the specification.
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Style    ParameterStyle `json:"style,omitempty"`
+	Explode  *bool          `json:"explode,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema is an OpenAPI 3 schema object, limited to the keywords needed
+// to describe form data.
+type OpenAPISchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Default              interface{}               `json:"default,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	ReadOnly             bool                      `json:"readOnly,omitempty"`
+	WriteOnly            bool                      `json:"writeOnly,omitempty"`
+}
+
+// OpenAPIRequestBody is an OpenAPI 3 request body object for form data.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType is an OpenAPI 3 media type object.
+type OpenAPIMediaType struct {
+	Schema   *OpenAPISchema             `json:"schema"`
+	Encoding map[string]OpenAPIEncoding `json:"encoding,omitempty"`
+}
+
+// OpenAPIEncoding is an OpenAPI 3 encoding object, describing how a property
+// of a form body is serialised.
+type OpenAPIEncoding struct {
+	Style   ParameterStyle `json:"style,omitempty"`
+	Explode *bool          `json:"explode,omitempty"`
+}
+
+// OpenAPIParameters returns OpenAPI 3 query parameter definitions for the
+// fields of the struct type t, as they are decoded by [Unmarshal]. See
+// [Codec.OpenAPIParameters].
+func OpenAPIParameters(t reflect.Type) ([]OpenAPIParameter, error) {
+	return defaultCodec.OpenAPIParameters(t)
+}
+
+// OpenAPIParameters returns OpenAPI 3 query parameter definitions for the
+// fields of the struct type t, as they are decoded by c. Fields with a style
+// of their own keep it; otherwise scalars and lists use the form style and
+// structs and maps the deepObject style, matching bracketed keys.
+func (c *Codec) OpenAPIParameters(t reflect.Type) ([]OpenAPIParameter, error) {
+	fields, err := c.Describe(t)
+	if err != nil {
+		return nil, err
+	}
+	tags := c.namedTags(indirectType(t))
+
+	params := make([]OpenAPIParameter, 0, len(fields))
+	for _, f := range fields {
+		if f.ReadOnly {
+			// Read-only fields are never accepted as parameters.
+			continue
+		}
+		style, explode := c.openAPIStyle(tags[f.Name], f.Shape)
+		params = append(params, OpenAPIParameter{
+			Name:     f.Name,
+			In:       "query",
+			Required: f.Required,
+			Style:    style,
+			Explode:  &explode,
+			Schema:   c.openAPIField(f),
+		})
+	}
+	return params, nil
+}
+
+// OpenAPIBody returns an OpenAPI 3 request body definition for form
+// data decoded into the struct type t by [Unmarshal]. See
+// [Codec.OpenAPIBody].
+func OpenAPIBody(t reflect.Type) (*OpenAPIRequestBody, error) {
+	return defaultCodec.OpenAPIBody(t)
+}
+
+// OpenAPIBody returns an OpenAPI 3 request body definition for
+// application/x-www-form-urlencoded data decoded into the struct type t by c.
+// Properties holding structs or maps are encoded with the deepObject style,
+// as bracketed keys.
+func (c *Codec) OpenAPIBody(t reflect.Type) (*OpenAPIRequestBody, error) {
+	fields, err := c.Describe(t)
+	if err != nil {
+		return nil, err
+	}
+	tags := c.namedTags(indirectType(t))
+
+	encoding := make(map[string]OpenAPIEncoding)
+	for _, f := range fields {
+		if style, explode := c.openAPIStyle(tags[f.Name], f.Shape); style != StyleForm || !explode {
+			encoding[f.Name] = OpenAPIEncoding{Style: style, Explode: &explode}
+		}
+	}
+	if len(encoding) == 0 {
+		encoding = nil
+	}
+
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content: map[string]OpenAPIMediaType{
+			"application/x-www-form-urlencoded": {
+				Schema:   c.openAPIObject(fields),
+				Encoding: encoding,
+			},
+		},
+	}, nil
+}
+
+// namedTags returns the tags of the fields of the struct type t, by name.
+func (c *Codec) namedTags(t reflect.Type) map[string]*tag {
+	tags := c.tags(reflect.New(t).Elem())
+	named := make(map[string]*tag, len(tags))
+	for _, tag := range tags {
+		named[tag.Name] = tag
+	}
+	return named
+}
+
+// openAPIStyle returns the style and explode values of a field with the given
+// tag and shape.
+func (c *Codec) openAPIStyle(t *tag, shape Shape) (ParameterStyle, bool) {
+	if t != nil {
+		if style, explode := c.fieldStyle(t); style != "" {
+			return style, explode
+		}
+	}
+	if shape == ShapeStruct || shape == ShapeMap {
+		return StyleDeepObject, true
+	}
+	return StyleForm, true
+}
+
+// openAPIObject returns the schema of an object with the given fields.
+func (c *Codec) openAPIObject(fields []FieldDescriptor) *OpenAPISchema {
+	s := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema, len(fields))}
+	for _, f := range fields {
+		s.Properties[f.Name] = c.openAPIField(f)
+		if f.Required {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s
+}
+
+// openAPIField returns the schema of the field described by f.
+func (c *Codec) openAPIField(f FieldDescriptor) *OpenAPISchema {
+	s := c.openAPIType(f.Type, f.Fields)
+	if f.Default != "" {
+		s.Default = openAPIValue(s.Type, f.Default)
+	}
+	for _, e := range f.Enum {
+		s.Enum = append(s.Enum, openAPIValue(s.Type, e))
+	}
+	s.ReadOnly, s.WriteOnly = f.ReadOnly, f.WriteOnly
+	return s
+}
+
+// openAPIType returns the schema of values of type t. The fields of t, or of
+// its elements, are given by fields.
+func (c *Codec) openAPIType(t reflect.Type, fields []FieldDescriptor) *OpenAPISchema {
+	t = indirectType(t)
+	if leaf := reflect.New(t).Elem(); c.decodesLeaf(leaf) {
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &OpenAPISchema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &OpenAPISchema{Type: "array", Items: c.openAPIType(t.Elem(), fields)}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: c.openAPIType(t.Elem(), fields)}
+	case reflect.Struct:
+		if fields == nil {
+			// A recursive type, already described by an enclosing schema.
+			return &OpenAPISchema{Type: "object"}
+		}
+		return c.openAPIObject(fields)
+	}
+	return &OpenAPISchema{}
+}
+
+// openAPIValue converts a value written in a tag into the JSON type of a
+// schema, leaving it as a string if it cannot be converted.
+func openAPIValue(typ, s string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package formenc_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Listing struct {
+	Query  string   `form:"q,required"`
+	Page   uint     `form:"page,default=1"`
+	Sort   string   `form:"sort,enum=name|date"`
+	Tags   []string `form:"tags"`
+	IDs    []int    `form:"ids,style=pipeDelimited"`
+	Filter *Address `form:"filter"`
+	Placed MyDate   `form:"placed"`
+	Total  int      `form:"total,readonly"`
+}
+
+func TestOpenAPIParameters(t *testing.T) {
+	t.Parallel()
+
+	params, err := formenc.OpenAPIParameters(reflect.TypeOf(Listing{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `[` +
+		`{"name":"q","in":"query","required":true,"style":"form","explode":true,"schema":{"type":"string"}},` +
+		`{"name":"page","in":"query","style":"form","explode":true,"schema":{"type":"integer","minimum":0,"default":1}},` +
+		`{"name":"sort","in":"query","style":"form","explode":true,"schema":{"type":"string","enum":["name","date"]}},` +
+		`{"name":"tags","in":"query","style":"form","explode":true,"schema":{"type":"array","items":{"type":"string"}}},` +
+		`{"name":"ids","in":"query","style":"pipeDelimited","explode":false,"schema":{"type":"array","items":{"type":"integer","format":"int64"}}},` +
+		`{"name":"filter","in":"query","style":"deepObject","explode":true,"schema":{"type":"object","properties":{"city":{"type":"string"},"state":{"type":"string"},"street":{"type":"string"},"zip":{"type":"string"}}}},` +
+		`{"name":"placed","in":"query","style":"form","explode":true,"schema":{"type":"string"}}` +
+		`]`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOpenAPIBody(t *testing.T) {
+	t.Parallel()
+
+	body, err := formenc.OpenAPIBody(reflect.TypeOf(&Order{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	media, ok := body.Content["application/x-www-form-urlencoded"]
+	if !ok {
+		t.Fatalf("expected form content type, got: %v", body.Content)
+	}
+	if diff := cmp.Diff([]string{"id"}, media.Schema.Required); diff != "" {
+		t.Errorf("required mismatch (-want +got):\n%s", diff)
+	}
+
+	lines := media.Schema.Properties["lines"]
+	if lines.Type != "array" || lines.Items.Type != "object" {
+		t.Fatalf("expected lines to be an array of objects, got: %+v", lines)
+	}
+	if diff := cmp.Diff([]string{"sku"}, lines.Items.Required); diff != "" {
+		t.Errorf("line required mismatch (-want +got):\n%s", diff)
+	}
+	if got := lines.Items.Properties["qty"].Default; got != int64(1) {
+		t.Errorf("expected qty default 1, got: %v", got)
+	}
+	if got := media.Schema.Properties["parent"]; got.Type != "object" || got.Properties != nil {
+		t.Errorf("expected recursive parent to be an open object, got: %+v", got)
+	}
+	if got := media.Schema.Properties["extra"].AdditionalProperties; got == nil || got.Type != "string" {
+		t.Errorf("expected extra to hold strings, got: %+v", got)
+	}
+
+	encoding := make(map[string]formenc.ParameterStyle)
+	for name, enc := range media.Encoding {
+		encoding[name] = enc.Style
+	}
+	want := map[string]formenc.ParameterStyle{
+		"shipping": formenc.StyleDeepObject,
+		"extra":    formenc.StyleDeepObject,
+		"parent":   formenc.StyleDeepObject,
+	}
+	if diff := cmp.Diff(want, encoding); diff != "" {
+		t.Errorf("encoding mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOpenAPIParameters_NotStruct(t *testing.T) {
+	t.Parallel()
+
+	if _, err := formenc.OpenAPIParameters(reflect.TypeOf("")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
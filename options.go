@@ -0,0 +1,280 @@
+package formenc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithTagName configures a [Codec] to read field names and flags from the
+// struct tag with the given key instead of "form".
+func WithTagName(name string) Option {
+	return func(c *Codec) error {
+		if name == "" {
+			return fmt.Errorf("form: tag name must not be empty")
+		}
+		c.tagName = name
+		return nil
+	}
+}
+
+// WithDottedKeys configures a [Codec] to join nested keys with dots, as in
+// "address.city", and to address array elements by their position, as in
+// "items.0.name", as [WithIndexedKeys] does. Arrays of scalars are encoded as
+// repeated keys.
+func WithDottedKeys() Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithDottedKeys", true, true); err != nil {
+			return err
+		}
+		c.parser = dotParser{}
+		c.renderer = dotRenderer{}
+		c.positionalKeys = true
+		return nil
+	}
+}
+
+// WithPathDelimiters configures a [Codec] to delimit nested keys with the
+// given runes, in both directions, for systems that never adopted brackets.
+// Nested segments are wrapped in open and close, so that with '(' and ')' a
+// Codec reads and writes "user(address)(city)" and "tags()". When close is
+// zero segments are separated by open alone, as by [WithDottedKeys], so that
+// with ':' a Codec reads and writes "user:address:city". Numeric segments
+// address slice elements by position, as with [WithIndexedKeys].
+//
+// The delimiters must be distinct valid runes other than '&', ';', '=' and
+// '%', which delimit the form data itself.
+func WithPathDelimiters(open, close rune) Option {
+	return func(c *Codec) error {
+		if !validDelimiter(open) {
+			return fmt.Errorf("form: invalid path delimiter %q", open)
+		}
+		if close != 0 && !validDelimiter(close) {
+			return fmt.Errorf("form: invalid path delimiter %q", close)
+		}
+		if open == close {
+			return fmt.Errorf("form: path delimiters must differ, got %q twice", open)
+		}
+		if err := c.setSyntax("WithPathDelimiters", true, true); err != nil {
+			return err
+		}
+		if close == 0 {
+			c.parser = dotParser{sep: open}
+			c.renderer = dotRenderer{sep: open}
+		} else {
+			c.parser = bracketParser{open: open, close: close}
+			c.renderer = bracketRenderer{open: open, close: close}
+		}
+		c.positionalKeys = true
+		return nil
+	}
+}
+
+// WithIndexedKeys configures a [Codec] to decode a numeric key below a slice,
+// such as the "0" of "items[0][name]", as the position of an element, so that
+// the fields of one element can be given by several keys. A position beyond
+// the end of a slice grows it; set MaxSliceLength with [WithLimits] to bound
+// the slices a key can allocate when decoding untrusted input. Without this
+// option such keys are rejected. [WithDottedKeys], [WithPathDelimiters],
+// [WithPlaygroundCompat] and [WithStripeCompat] imply it.
+func WithIndexedKeys() Option {
+	return func(c *Codec) error {
+		c.positionalKeys = true
+		return nil
+	}
+}
+
+// validDelimiter reports whether r may delimit the segments of a key.
+func validDelimiter(r rune) bool {
+	return r != 0 && utf8.ValidRune(r) && !strings.ContainsRune("&;=%", r)
+}
+
+// setSyntax records that the option named name selects the parser of c, the
+// renderer of c, or both, returning an error if another option has already
+// selected a different one.
+func (c *Codec) setSyntax(name string, parser, renderer bool) error {
+	if parser && c.parserOption != "" && c.parserOption != name {
+		return fmt.Errorf("form: %s cannot be combined with %s, which decodes keys differently", name, c.parserOption)
+	}
+	if renderer && c.rendererOption != "" && c.rendererOption != name {
+		return fmt.Errorf("form: %s cannot be combined with %s, which encodes keys differently", name, c.rendererOption)
+	}
+	if parser {
+		c.parserOption = name
+	}
+	if renderer {
+		c.rendererOption = name
+	}
+	return nil
+}
+
+// checkOptions reports combinations of options that cannot be honoured together,
+// once all options have been applied.
+func (c *Codec) checkOptions() error {
+	if c.style != "" && c.rendererOption != "" {
+		return fmt.Errorf("form: WithParameterStyle cannot be combined with %s, which writes arrays and objects in a syntax of its own", c.rendererOption)
+	}
+	return nil
+}
+
+// WithIgnoreUnknownKeys configures a [Codec] to skip keys that do not
+// correspond to any struct field, rather than returning an
+// [UnknownFieldError].
+func WithIgnoreUnknownKeys() Option {
+	return func(c *Codec) error {
+		c.ignoreUnknownKeys = true
+		return nil
+	}
+}
+
+// WithSkipUnexportedFields configures a [Codec] to skip keys that match
+// unexported struct fields, rather than returning an [UnexportedFieldError].
+// Keys that match no field are still rejected unless [WithIgnoreUnknownKeys]
+// is also given.
+func WithSkipUnexportedFields() Option {
+	return func(c *Codec) error {
+		c.skipUnexported = true
+		return nil
+	}
+}
+
+// WithStrict configures a [Codec] to reject ambiguous input. A key that
+// assigns a struct field already assigned by an earlier key, whether it repeats
+// that key or reaches the same field by another name, causes a
+// [DuplicateFieldError]. Fields accepting repeated values, such as slices, are
+// exempt. A key used with both array and map notation, as in "a[]=x&a[k]=y",
+// is rejected with an [UnmarshalTypeError] rather than resolved by the policy
+// given to [WithMixedNotation].
+func WithStrict() Option {
+	return func(c *Codec) error {
+		c.strict = true
+		return nil
+	}
+}
+
+// WithEmptyAsNil configures a [Codec] to decode an empty value into a pointer
+// field, such as a *string or *int, as a nil pointer, clearing any value the
+// field held. By default an empty value decodes as a pointer to the zero
+// value, so that a field submitted empty can be told apart from one not
+// submitted, which is left nil; this option reverses that for clients that
+// send every field and leave the ones they do not set empty. Pointers to
+// slices and maps are unaffected.
+func WithEmptyAsNil() Option {
+	return func(c *Codec) error {
+		c.emptyAsNil = true
+		return nil
+	}
+}
+
+// WithRoundTripCheck configures a [Codec] to verify that every value it
+// encodes or decodes survives the reverse operation unchanged, returning a
+// [RoundTripError] describing the first difference when it does not. This is
+// intended for tests and data migrations, where silent loss, such as numbers
+// in an interface value decoding as strings, must be caught.
+//
+// Marshal decodes its output into a new value of the same type and compares
+// it with the original. Unmarshal encodes the decoded value and compares the
+// pairs produced with those that were decoded.
+func WithRoundTripCheck() Option {
+	return func(c *Codec) error {
+		c.roundTrip = true
+		return nil
+	}
+}
+
+// WithAggregateErrors configures a [Codec] to continue decoding after a key
+// fails to decode, so that every failing key is reported. The error returned
+// is a [*MultiError] listing the failures in the order the keys appeared.
+// Errors in the syntax of the form data still stop decoding immediately.
+func WithAggregateErrors() Option {
+	return func(c *Codec) error {
+		c.aggregateErrors = true
+		return nil
+	}
+}
+
+// WithValidation configures a [Codec] to validate values once they have been
+// decoded without error. If the value passed to Unmarshal implements
+// [Validator] its Validate method is called, followed by fn when it is not
+// nil. A failure is returned as a [ValidationError].
+func WithValidation(fn func(v interface{}) error) Option {
+	if fn == nil {
+		return WithValidationContext(nil)
+	}
+	return WithValidationContext(func(_ context.Context, v interface{}) error {
+		return fn(v)
+	})
+}
+
+// WithValidationContext configures a [Codec] to validate values once they
+// have been decoded, as [WithValidation] does. fn, and the ValidateContext
+// method of values implementing [ContextValidator], receive the context passed
+// to [Codec.UnmarshalContext].
+func WithValidationContext(fn func(ctx context.Context, v interface{}) error) Option {
+	return func(c *Codec) error {
+		c.validation = true
+		c.validator = fn
+		return nil
+	}
+}
+
+// WithDecodeFunc registers fn to decode values into the type of value. The
+// value returned by fn must be assignable or convertible to that type.
+// Registered functions take precedence over [Unmarshaler] implementations.
+func WithDecodeFunc(value interface{}, fn func(string) (interface{}, error)) Option {
+	if fn == nil {
+		return WithDecodeFuncContext(value, nil)
+	}
+	return WithDecodeFuncContext(value, func(_ context.Context, s string) (interface{}, error) {
+		return fn(s)
+	})
+}
+
+// WithDecodeFuncContext registers fn to decode values into the type of value,
+// as [WithDecodeFunc] does. fn receives the context passed to
+// [Codec.UnmarshalContext], so that it can honour deadlines while looking
+// values up.
+func WithDecodeFuncContext(value interface{}, fn func(context.Context, string) (interface{}, error)) Option {
+	return func(c *Codec) error {
+		if value == nil || fn == nil {
+			return fmt.Errorf("form: decode func requires a value and a function")
+		}
+		decoders := make(map[reflect.Type]func(context.Context, string) (interface{}, error), len(c.decoders)+1)
+		for t, f := range c.decoders {
+			decoders[t] = f
+		}
+		decoders[reflect.TypeOf(value)] = fn
+		c.decoders = decoders
+		return nil
+	}
+}
+
+// WithEncodeFunc registers fn to encode values of the type of value.
+// Registered functions take precedence over [Marshaler] implementations.
+func WithEncodeFunc(value interface{}, fn func(interface{}) (string, error)) Option {
+	return func(c *Codec) error {
+		if value == nil || fn == nil {
+			return fmt.Errorf("form: encode func requires a value and a function")
+		}
+		encoders := make(map[reflect.Type]func(interface{}) (string, error), len(c.encoders)+1)
+		for t, f := range c.encoders {
+			encoders[t] = f
+		}
+		encoders[reflect.TypeOf(value)] = fn
+		c.encoders = encoders
+		return nil
+	}
+}
+
+// WithGroups configures a [Codec] to encode only the fields belonging to one of
+// groups, as named by the groups flag of their tag, such as
+// `form:"id,groups=update;delete"`. Fields without a groups flag are always
+// encoded. Decoding is unaffected.
+func WithGroups(groups ...string) Option {
+	return func(c *Codec) error {
+		c.groups = append([]string(nil), groups...)
+		return nil
+	}
+}
@@ -0,0 +1,762 @@
+package formenc_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Celsius float64
+
+type Point struct {
+	City string `json:"city"`
+}
+
+func TestCodec_Options_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	type Reading struct {
+		Sensor string   `json:"sensor"`
+		Temp   Celsius  `json:"temp"`
+		Tags   []string `json:"tags"`
+		Points []Point  `json:"points"`
+	}
+
+	tests := map[string]struct {
+		opts    []formenc.Option
+		input   string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		"tag name": {
+			opts:   []formenc.Option{formenc.WithTagName("json")},
+			input:  "sensor=a1",
+			target: &Reading{},
+			want:   &Reading{Sensor: "a1"},
+		},
+		"dotted keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithDottedKeys(),
+			},
+			input:  "points.1.city=Leeds&points.0.city=York&tags=a&tags=b",
+			target: &Reading{},
+			want: &Reading{
+				Tags:   []string{"a", "b"},
+				Points: []Point{{City: "York"}, {City: "Leeds"}},
+			},
+		},
+		"indexed keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithIndexedKeys(),
+			},
+			input:  "points[1][city]=Leeds&points[0][city]=York",
+			target: &Reading{},
+			want: &Reading{
+				Points: []Point{{City: "York"}, {City: "Leeds"}},
+			},
+		},
+		"indexed keys without option": {
+			opts:    []formenc.Option{formenc.WithTagName("json")},
+			input:   "points[0][city]=York",
+			target:  &Reading{},
+			wantErr: true,
+		},
+		"parenthesised keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithPathDelimiters('(', ')'),
+			},
+			input:  "points(1)(city)=Leeds&points(0)(city)=York&tags()=a&tags%28%29=b",
+			target: &Reading{},
+			want: &Reading{
+				Tags:   []string{"a", "b"},
+				Points: []Point{{City: "York"}, {City: "Leeds"}},
+			},
+		},
+		"separated keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithPathDelimiters(':', 0),
+			},
+			input:  "points:1:city=Leeds&points%3A0%3Acity=York&tags=a&tags=b",
+			target: &Reading{},
+			want: &Reading{
+				Tags:   []string{"a", "b"},
+				Points: []Point{{City: "York"}, {City: "Leeds"}},
+			},
+		},
+		"brackets with path delimiters": {
+			opts:    []formenc.Option{formenc.WithPathDelimiters('(', ')')},
+			input:   "address[city]=Leeds",
+			target:  &Person{},
+			wantErr: true,
+		},
+		"unknown key": {
+			input:   "unknown=1&name=john",
+			target:  &Person{},
+			wantErr: true,
+		},
+		"unknown key ignored": {
+			opts:   []formenc.Option{formenc.WithIgnoreUnknownKeys()},
+			input:  "unknown=1&name=john",
+			target: &Person{},
+			want:   &Person{Name: "john"},
+		},
+		"decode func": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithDecodeFunc(Celsius(0), func(s string) (interface{}, error) {
+					return strconv.ParseFloat(strings.TrimSuffix(s, "C"), 64)
+				}),
+			},
+			input:  "temp=21.5C",
+			target: &Reading{},
+			want:   &Reading{Temp: 21.5},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = codec.Unmarshal([]byte(tt.input), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.target, tt.want); diff != "" {
+					t.Errorf("mismatch (-got +want):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCodec_Options_Marshal(t *testing.T) {
+	t.Parallel()
+
+	type Reading struct {
+		Temp   Celsius  `json:"temp"`
+		Tags   []string `json:"tags"`
+		Points []Point  `json:"points,omitempty"`
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input interface{}
+		want  []byte
+	}{
+		"dotted keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithDottedKeys(),
+			},
+			input: &Reading{Tags: []string{"a", "b"}, Points: []Point{{City: "York"}}},
+			want:  pathEscape("points.0.city=York&tags=a&tags=b&temp=0"),
+		},
+		"parenthesised keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithPathDelimiters('(', ')'),
+			},
+			input: &Reading{Tags: []string{"a", "b"}, Points: []Point{{City: "York"}}},
+			want:  []byte("points%28%29%28city%29=York&tags%28%29=a&tags%28%29=b&temp=0"),
+		},
+		"separated keys": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithPathDelimiters(':', 0),
+			},
+			input: &Reading{Tags: []string{"a", "b"}, Points: []Point{{City: "York"}}},
+			want:  []byte("points%3A0%3Acity=York&tags=a&tags=b&temp=0"),
+		},
+		"encode func": {
+			opts: []formenc.Option{
+				formenc.WithTagName("json"),
+				formenc.WithEncodeFunc(Celsius(0), func(v interface{}) (string, error) {
+					return strconv.FormatFloat(float64(v.(Celsius)), 'f', 1, 64) + "C", nil
+				}),
+			},
+			input: &Reading{Temp: 21.5},
+			want:  pathEscape("temp=21.5C"),
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := codec.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodec_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]formenc.Option{
+		"empty tag name":   {formenc.WithTagName("")},
+		"nil decode func":  {formenc.WithDecodeFunc(0, nil)},
+		"nil encode value": {formenc.WithEncodeFunc(nil, func(interface{}) (string, error) { return "", nil })},
+		"negative limit":   {formenc.WithLimits(formenc.Limits{MaxKeys: -1})},
+		"conflicting key syntaxes": {
+			formenc.WithRackCompat(),
+			formenc.WithQSCompat(formenc.QSOptions{}),
+		},
+		"dotted keys with compat mode": {
+			formenc.WithDottedKeys(),
+			formenc.WithStripeCompat(),
+		},
+		"equal path delimiters":   {formenc.WithPathDelimiters('|', '|')},
+		"reserved path delimiter": {formenc.WithPathDelimiters('=', 0)},
+		"path delimiters with dotted keys": {
+			formenc.WithPathDelimiters('(', ')'),
+			formenc.WithDottedKeys(),
+		},
+		"comma separated arrays with indexed keys": {
+			formenc.WithParameterStyle(formenc.StyleForm, false),
+			formenc.WithQSCompat(formenc.QSOptions{}),
+		},
+	}
+	for name, opts := range tests {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := formenc.NewCodec(opts...); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestCodec_CompatibleOptions(t *testing.T) {
+	t.Parallel()
+
+	// Rack decodes keys and jQuery encodes them, so the two may be combined.
+	if _, err := formenc.NewCodec(formenc.WithRackCompat(), formenc.WithJQueryCompat(false)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := formenc.NewCodec(formenc.WithQSCompat(formenc.QSOptions{}), formenc.WithQSCompat(formenc.QSOptions{AllowDots: true})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCodec_With(t *testing.T) {
+	t.Parallel()
+
+	base, err := formenc.NewCodec(formenc.WithTagName("json"), formenc.WithIgnoreUnknownKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	strict, err := base.With(formenc.WithStrict())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type Account struct {
+		Name string `json:"name"`
+	}
+	input := []byte("name=john&name=jane&unknown=1")
+
+	var got Account
+	if err := base.Unmarshal(input, &got); err != nil {
+		t.Errorf("unexpected error from base codec: %v", err)
+	}
+	var dup *formenc.DuplicateFieldError
+	if err := strict.Unmarshal(input, &got); !errors.As(err, &dup) {
+		t.Errorf("expected DuplicateFieldError from derived codec, got: %v", err)
+	}
+	if err := strict.Unmarshal([]byte("name=john&unknown=1"), &got); err != nil {
+		t.Errorf("expected derived codec to keep options of base, got: %v", err)
+	}
+	if diff := cmp.Diff(Account{Name: "john"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, err := base.With(formenc.WithTagName("")); err == nil {
+		t.Error("expected error for invalid option, got nil")
+	}
+}
+
+func TestUnknownFieldError(t *testing.T) {
+	t.Parallel()
+
+	err := formenc.Unmarshal([]byte("unknown=1"), &Person{})
+
+	var unknown *formenc.UnknownFieldError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownFieldError, got %T", err)
+	}
+	if unknown.Key != "unknown" {
+		t.Errorf("expected key %q, got %q", "unknown", unknown.Key)
+	}
+}
+
+func TestUnknownFieldError_Nested(t *testing.T) {
+	t.Parallel()
+
+	err := formenc.Unmarshal([]byte("name=john&address[planet]=mars"), &User{})
+
+	var unknown *formenc.UnknownFieldError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownFieldError, got %T", err)
+	}
+
+	want := formenc.UnknownFieldError{
+		Field: "planet",
+		Type:  reflect.TypeOf(Address{}),
+		Key:   "address[planet]",
+		Path:  mustPath("address[planet]"),
+		Value: "mars",
+	}
+	opts := cmp.Comparer(func(a, b reflect.Type) bool { return a == b })
+	if diff := cmp.Diff(want, *unknown, opts); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	wantMsg := `form: cannot unmarshal "mars" into key "address[planet]": unknown field "planet" in struct formenc_test.Address`
+	if diff := cmp.Diff(wantMsg, err.Error()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+type Widget struct {
+	ID      int    `form:"id,groups=update"`
+	Name    string `form:"name"`
+	Owner   string `form:"owner,groups=create"`
+	Version int    `form:"version,groups=update;delete"`
+}
+
+func TestWithGroups(t *testing.T) {
+	t.Parallel()
+
+	widget := Widget{ID: 7, Name: "gear", Owner: "alice", Version: 3}
+
+	tests := map[string]struct {
+		opts []formenc.Option
+		want string
+	}{
+		"no groups": {
+			want: "id=7&name=gear&owner=alice&version=3",
+		},
+		"create": {
+			opts: []formenc.Option{formenc.WithGroups("create")},
+			want: "name=gear&owner=alice",
+		},
+		"update": {
+			opts: []formenc.Option{formenc.WithGroups("update")},
+			want: "id=7&name=gear&version=3",
+		},
+		"several groups": {
+			opts: []formenc.Option{formenc.WithGroups("create", "delete")},
+			want: "name=gear&owner=alice&version=3",
+		},
+		"round trip check": {
+			opts: []formenc.Option{formenc.WithGroups("create"), formenc.WithRoundTripCheck()},
+			want: "name=gear&owner=alice",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := codec.Marshal(widget)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type Credentials struct {
+	ID       int    `form:"id,readonly"`
+	Username string `form:"username"`
+	Password string `form:"password,writeonly"`
+}
+
+func TestReadOnlyWriteOnly(t *testing.T) {
+	t.Parallel()
+
+	var dropped []formenc.DropReason
+	codec, err := formenc.NewCodec(
+		formenc.WithRoundTripCheck(),
+		formenc.WithHooks(formenc.Hooks{
+			OnKeyDropped: func(_ context.Context, _ string, reason formenc.DropReason) {
+				dropped = append(dropped, reason)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := codec.Marshal(Credentials{ID: 7, Username: "alice", Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("id=7&username=alice", string(data)); diff != "" {
+		t.Errorf("encode mismatch (-want +got):\n%s", diff)
+	}
+
+	var got Credentials
+	if err := codec.Unmarshal([]byte("id=9&username=bob&password=secret"), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Credentials{Username: "bob", Password: "secret"}, got); diff != "" {
+		t.Errorf("decode mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]formenc.DropReason{formenc.DropReadOnlyField}, dropped); diff != "" {
+		t.Errorf("dropped mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type Account struct {
+	Name     string `form:"name"`
+	password string
+}
+
+func TestUnexportedFieldError(t *testing.T) {
+	t.Parallel()
+
+	err := formenc.Unmarshal([]byte("name=john&password=hunter2"), &Account{})
+
+	var unexported *formenc.UnexportedFieldError
+	if !errors.As(err, &unexported) {
+		t.Fatalf("expected UnexportedFieldError, got %T", err)
+	}
+
+	wantMsg := `form: cannot unmarshal "hunter2" into key "password": cannot set unexported field "password" in struct formenc_test.Account`
+	if diff := cmp.Diff(wantMsg, err.Error()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSkipUnexportedFields(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input       string
+		want        Account
+		wantUnknown bool
+	}{
+		"unexported field": {
+			input: "name=john&password=hunter2",
+			want:  Account{Name: "john"},
+		},
+		"unknown field": {
+			input:       "name=john&email=john@example.com",
+			want:        Account{Name: "john"},
+			wantUnknown: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var dropped []formenc.DropReason
+			c, err := formenc.NewCodec(
+				formenc.WithSkipUnexportedFields(),
+				formenc.WithHooks(formenc.Hooks{
+					OnKeyDropped: func(_ context.Context, _ string, reason formenc.DropReason) {
+						dropped = append(dropped, reason)
+					},
+				}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Account
+			err = c.Unmarshal([]byte(tt.input), &got)
+			var unknown *formenc.UnknownFieldError
+			if errors.As(err, &unknown) != tt.wantUnknown {
+				t.Fatalf("expected UnknownFieldError: %v, got: %v", tt.wantUnknown, err)
+			}
+			if tt.wantUnknown {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(Account{})); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]formenc.DropReason{formenc.DropIgnoredField}, dropped); diff != "" {
+				t.Errorf("dropped mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type Signup struct {
+	Email string `form:"email"`
+	Age   int    `form:"age"`
+}
+
+func (s Signup) Validate() error {
+	if !strings.Contains(s.Email, "@") {
+		return errors.New("email is invalid")
+	}
+	return nil
+}
+
+func TestWithValidation(t *testing.T) {
+	t.Parallel()
+
+	adult := func(v interface{}) error {
+		if v.(*Signup).Age < 18 {
+			return errors.New("age must be at least 18")
+		}
+		return nil
+	}
+
+	tests := map[string]struct {
+		opt     formenc.Option
+		input   string
+		wantMsg string
+	}{
+		"valid": {
+			opt:   formenc.WithValidation(adult),
+			input: "email=a@example.com&age=30",
+		},
+		"validate method": {
+			opt:     formenc.WithValidation(adult),
+			input:   "email=example.com&age=30",
+			wantMsg: "form: validation failed: email is invalid",
+		},
+		"validator func": {
+			opt:     formenc.WithValidation(adult),
+			input:   "email=a@example.com&age=12",
+			wantMsg: "form: validation failed: age must be at least 18",
+		},
+		"method only": {
+			opt:     formenc.WithValidation(nil),
+			input:   "email=example.com&age=12",
+			wantMsg: "form: validation failed: email is invalid",
+		},
+		"decode error first": {
+			opt:     formenc.WithValidation(adult),
+			input:   "email=example.com&age=old",
+			wantMsg: `form: cannot unmarshal "old" into key "age" of type int: invalid syntax`,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Signup
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.wantMsg)
+			}
+			if diff := cmp.Diff(tt.wantMsg, err.Error()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithValidation_Disabled(t *testing.T) {
+	t.Parallel()
+
+	// Validate methods are only called when validation is enabled.
+	var got Signup
+	if err := formenc.DecodeString("email=example.com", &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWithStrict(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    User
+		wantErr *formenc.DuplicateFieldError
+	}{
+		"distinct keys": {
+			input: "name=john&address[city]=Paris&address[zip]=75001",
+			want:  User{Name: "john", Address: Address{City: "Paris", Zip: "75001"}},
+		},
+		"repeated key": {
+			input:   "name=john&name=jane",
+			want:    User{Name: "john"},
+			wantErr: &formenc.DuplicateFieldError{Key: "name", Path: mustPath("name"), Previous: "name", Field: "name"},
+		},
+		"repeated nested key": {
+			input:   "address[city]=Paris&address[city]=Lyon",
+			want:    User{Address: Address{City: "Paris"}},
+			wantErr: &formenc.DuplicateFieldError{Key: "address[city]", Path: mustPath("address[city]"), Previous: "address[city]", Field: "city"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithStrict())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got User
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				var dup *formenc.DuplicateFieldError
+				if !errors.As(err, &dup) {
+					t.Fatalf("expected DuplicateFieldError, got: %v", err)
+				}
+				opts := cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".Type" }, cmp.Ignore())
+				if diff := cmp.Diff(tt.wantErr, dup, opts); diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithStrict_RepeatedValues(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithStrict(), formenc.WithDottedKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Slices accept any number of keys, while the same element reached by two
+	// keys is still a duplicate.
+	var got Person
+	if err := codec.Unmarshal([]byte("pronouns=he&pronouns=him"), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"he", "him"}, got.Pronouns); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	type Team struct {
+		Members []User `form:"members"`
+	}
+	var team Team
+	err = codec.Unmarshal([]byte("members.0.name=a&members.0.name=b"), &team)
+	var dup *formenc.DuplicateFieldError
+	if !errors.As(err, &dup) {
+		t.Fatalf("expected DuplicateFieldError, got: %v", err)
+	}
+}
+
+func TestWithEmptyAsNil(t *testing.T) {
+	t.Parallel()
+
+	type Profile struct {
+		Nickname *string   `form:"nickname"`
+		Age      *int      `form:"age"`
+		Bio      *string   `form:"bio"`
+		Tags     *[]string `form:"tags"`
+	}
+
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input string
+		prev  Profile
+		want  Profile
+	}{
+		"empty decodes as zero": {
+			input: "nickname=&age=",
+			want:  Profile{Nickname: str(""), Age: num(0)},
+		},
+		"absent stays nil": {
+			input: "bio=hello",
+			want:  Profile{Bio: str("hello")},
+		},
+		"empty as nil": {
+			opts:  []formenc.Option{formenc.WithEmptyAsNil()},
+			input: "nickname=&age=&bio=hello",
+			want:  Profile{Bio: str("hello")},
+		},
+		"empty as nil clears value": {
+			opts:  []formenc.Option{formenc.WithEmptyAsNil()},
+			input: "nickname&age=",
+			prev:  Profile{Nickname: str("jo"), Age: num(30)},
+			want:  Profile{},
+		},
+		"empty as nil leaves slices": {
+			opts:  []formenc.Option{formenc.WithEmptyAsNil()},
+			input: "tags=",
+			want:  Profile{Tags: &[]string{""}},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := tt.prev
+			if err := codec.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package formenc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// OverflowPolicy determines how a number that does not fit in its target type
+// is decoded.
+type OverflowPolicy int
+
+const (
+	// OverflowError rejects numbers that do not fit with an
+	// [UnmarshalTypeError]. This is the default.
+	OverflowError OverflowPolicy = iota
+
+	// OverflowClamp decodes numbers that do not fit as the nearest value that
+	// does, so 300 decodes into an int8 as 127 and -1 into a uint as 0.
+	OverflowClamp
+
+	// OverflowTruncate discards the high-order bits of integers that do not
+	// fit, as a Go conversion does, so 300 decodes into an int8 as 44.
+	// Floating-point numbers are clamped, as they have no such bits.
+	OverflowTruncate
+)
+
+// WithOverflow configures how a [Codec] decodes numbers that are too large or
+// too small for their target type. Values that are not valid numbers are
+// rejected whatever the policy.
+func WithOverflow(policy OverflowPolicy) Option {
+	return func(c *Codec) error {
+		if policy < OverflowError || policy > OverflowTruncate {
+			return fmt.Errorf("form: unknown overflow policy %d", policy)
+		}
+		c.overflow = policy
+		return nil
+	}
+}
+
+// fitInt resolves s, which failed to parse as a signed integer of the given
+// size, according to the policy. It reports false if s must be rejected.
+func (p OverflowPolicy) fitInt(s string, bits int) (int64, bool) {
+	n, ok := p.integer(s)
+	if !ok {
+		return 0, false
+	}
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	hi := new(big.Int).Rsh(size, 1)
+	lo := new(big.Int).Neg(hi)
+	hi.Sub(hi, big.NewInt(1))
+
+	switch {
+	case p == OverflowTruncate:
+		n.Mod(n, size)
+		if n.Cmp(hi) > 0 {
+			n.Sub(n, size)
+		}
+	case n.Cmp(hi) > 0:
+		n = hi
+	case n.Cmp(lo) < 0:
+		n = lo
+	}
+	return n.Int64(), true
+}
+
+// fitUint resolves s, which failed to parse as an unsigned integer of the
+// given size, according to the policy. It reports false if s must be rejected.
+func (p OverflowPolicy) fitUint(s string, bits int) (uint64, bool) {
+	n, ok := p.integer(s)
+	if !ok {
+		return 0, false
+	}
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	hi := new(big.Int).Sub(size, big.NewInt(1))
+
+	switch {
+	case p == OverflowTruncate:
+		n.Mod(n, size)
+	case n.Cmp(hi) > 0:
+		n = hi
+	case n.Sign() < 0:
+		n.SetInt64(0)
+	}
+	return n.Uint64(), true
+}
+
+// integer parses s as an integer of any size, provided the policy permits
+// numbers that overflow.
+func (p OverflowPolicy) integer(s string) (*big.Int, bool) {
+	if p == OverflowError {
+		return nil, false
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// fitFloat resolves f, the result of a float parse that failed with err,
+// according to the policy. It reports false if the value must be rejected.
+func (p OverflowPolicy) fitFloat(f float64, err error, bits int) (float64, bool) {
+	if p == OverflowError || !errors.Is(err, strconv.ErrRange) || !math.IsInf(f, 0) {
+		return 0, false
+	}
+
+	hi := math.MaxFloat64
+	if bits == 32 {
+		hi = math.MaxFloat32
+	}
+	if f < 0 {
+		return -hi, true
+	}
+	return hi, true
+}
@@ -0,0 +1,94 @@
+package formenc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Sizes struct {
+	I8  int8    `form:"i8"`
+	I16 int16   `form:"i16"`
+	I64 int64   `form:"i64"`
+	U8  uint8   `form:"u8"`
+	U   uint    `form:"u"`
+	F32 float32 `form:"f32"`
+}
+
+func TestWithOverflow(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy  formenc.OverflowPolicy
+		input   string
+		want    Sizes
+		wantErr bool
+	}{
+		"error": {
+			policy:  formenc.OverflowError,
+			input:   "i8=300",
+			wantErr: true,
+		},
+		"clamp": {
+			policy: formenc.OverflowClamp,
+			input:  "i8=300&i16=-40000&i64=99999999999999999999&u8=256&u=-1&f32=1e40",
+			want: Sizes{
+				I8:  math.MaxInt8,
+				I16: math.MinInt16,
+				I64: math.MaxInt64,
+				U8:  math.MaxUint8,
+				U:   0,
+				F32: math.MaxFloat32,
+			},
+		},
+		"truncate": {
+			policy: formenc.OverflowTruncate,
+			input:  "i8=300&i16=-40000&i64=18446744073709551617&u8=256&u=-1&f32=-1e40",
+			want: Sizes{
+				I8:  44,
+				I16: 25536,
+				I64: 1,
+				U8:  0,
+				U:   math.MaxUint,
+				F32: -math.MaxFloat32,
+			},
+		},
+		"values that fit": {
+			policy: formenc.OverflowClamp,
+			input:  "i8=-128&u8=255",
+			want:   Sizes{I8: -128, U8: 255},
+		},
+		"invalid numbers": {
+			policy:  formenc.OverflowClamp,
+			input:   "i8=3.5",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithOverflow(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Sizes
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := formenc.NewCodec(formenc.WithOverflow(formenc.OverflowPolicy(9))); err == nil {
+		t.Error("expected error for unknown policy, got nil")
+	}
+}
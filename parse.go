@@ -0,0 +1,234 @@
+package formenc
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// pair is a single unescaped key/value pair in the order it appeared in the
+// input.
+type pair struct {
+	key   string
+	value string
+
+	// rawKey is the key as it appeared in the input, before unescaping. It is
+	// only set by splitPairs.
+	rawKey string
+
+	// static is set for encoded pairs whose key names only struct fields, and
+	// so is the same for every value of a type.
+	static bool
+}
+
+// entry is a value together with the path it should be assigned to within the
+// decode target.
+type entry struct {
+	key   string
+	path  []pathSegment
+	value string
+}
+
+// parser converts raw form data into the entries assigned to a decode target.
+// Implementations define the key syntax understood by a [Codec].
+type parser interface {
+	parse(query string) ([]entry, error)
+}
+
+// typedParser is implemented by parsers whose key syntax cannot be interpreted
+// without knowing the type being decoded into, such as when the same syntax
+// addresses both list members and map entries. When a [Codec] has a
+// typedParser, parseType is used in place of parse.
+type typedParser interface {
+	parser
+	parseType(c *Codec, query string, t reflect.Type) ([]entry, error)
+}
+
+// bracketParser understands the default bracketed key syntax, where each key is
+// parsed independently of every other key. When open and close are set they
+// delimit nested segments in place of brackets.
+type bracketParser struct {
+	open, close rune
+}
+
+func (p bracketParser) parse(query string) ([]entry, error) {
+	pairs, err := splitPairs(query)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+	}
+	return p.entries(pairs)
+}
+
+// delimiters returns the runes opening and closing the nested segments of a
+// key.
+func (p bracketParser) delimiters() (open, close rune) {
+	if p.open == 0 {
+		return '[', ']'
+	}
+	return p.open, p.close
+}
+
+// entries converts pairs into entries with bracketed key syntax.
+func (bp bracketParser) entries(pairs []pair) ([]entry, error) {
+	open, close := bp.delimiters()
+
+	// Bulk submissions repeat the same nested keys, such as "items[][sku]",
+	// for every element, so each distinct key is parsed once and its path
+	// shared by the entries that repeat it.
+	var seen map[string][]pathSegment
+
+	entries := make([]entry, 0, len(pairs))
+	for _, p := range pairs {
+		if path, ok := seen[p.rawKey]; ok {
+			entries = append(entries, entry{key: p.key, path: path, value: p.value})
+			continue
+		}
+
+		path, ok := parseRawKey(p.rawKey, open, close)
+		if !ok {
+			var err error
+			if path, err = parseDelimitedKey(p.key, open, close); err != nil {
+				return nil, err
+			}
+		}
+		if len(path) > 1 {
+			if seen == nil {
+				seen = make(map[string][]pathSegment)
+			}
+			seen[p.rawKey] = path
+		}
+		entries = append(entries, entry{key: p.key, path: path, value: p.value})
+	}
+	return entries, nil
+}
+
+// parseRawKey parses a key before it is unescaped, so that escaped brackets,
+// as in "data[a%5Db]", form part of a segment rather than delimiting one. It
+// reports false for keys in which every bracket is escaped, as browsers submit
+// them, and for keys that cannot be parsed this way; these are parsed once
+// unescaped instead.
+func parseRawKey(key string, open, close rune) ([]pathSegment, bool) {
+	if !strings.ContainsRune(key, open) {
+		return nil, false
+	}
+
+	path, err := parseDelimitedKey(key, open, close)
+	if err != nil {
+		return nil, false
+	}
+	for i := range path {
+		if path[i].Key, err = url.QueryUnescape(path[i].Key); err != nil {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// splitPairs splits a query into its key/value pairs, preserving their order.
+// The accepted syntax matches [url.ParseQuery].
+func splitPairs(query string) ([]pair, error) {
+	var pairs []pair
+
+	// Escaped keys are unescaped once each, so that keys repeated by bulk
+	// submissions share a single string.
+	var keys map[string]string
+
+	for query != "" {
+		var s string
+		s, query, _ = strings.Cut(query, "&")
+		if strings.Contains(s, ";") {
+			return nil, fmt.Errorf("invalid semicolon separator in query")
+		}
+		if s == "" {
+			continue
+		}
+
+		rawKey, value, _ := strings.Cut(s, "=")
+		key, ok := keys[rawKey]
+		if !ok {
+			var err error
+			if key, err = url.QueryUnescape(rawKey); err != nil {
+				return nil, err
+			}
+			if key != rawKey {
+				if keys == nil {
+					keys = make(map[string]string)
+				}
+				keys[rawKey] = key
+			}
+		}
+		value, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{key: key, value: value, rawKey: rawKey})
+	}
+	return pairs, nil
+}
+
+// splitPairBytes splits data into its key/value pairs as splitPairs does,
+// without first copying data into a string. Each distinct key is allocated
+// once, and each value once, or twice if it must be unescaped.
+func splitPairBytes(data []byte) ([]pair, error) {
+	var pairs []pair
+
+	// Nested and escaped keys, which bulk submissions repeat for every
+	// element, are interned: nested maps each raw key to itself and keys maps
+	// it to its unescaped form, so that each is allocated once.
+	var nested, keys map[string]string
+
+	for len(data) > 0 {
+		var s []byte
+		s, data, _ = bytes.Cut(data, []byte("&"))
+		if bytes.IndexByte(s, ';') >= 0 {
+			return nil, fmt.Errorf("invalid semicolon separator in query")
+		}
+		if len(s) == 0 {
+			continue
+		}
+
+		rawKeyBytes, valueBytes, _ := bytes.Cut(s, []byte("="))
+		if !bytes.ContainsAny(rawKeyBytes, "[%+") {
+			key := string(rawKeyBytes)
+			value, err := unescapeBytes(valueBytes)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair{key: key, value: value, rawKey: key})
+			continue
+		}
+
+		rawKey, ok := nested[string(rawKeyBytes)]
+		if !ok {
+			if nested == nil {
+				nested, keys = make(map[string]string), make(map[string]string)
+			}
+			rawKey = string(rawKeyBytes)
+			nested[rawKey] = rawKey
+
+			key, err := url.QueryUnescape(rawKey)
+			if err != nil {
+				return nil, err
+			}
+			keys[rawKey] = key
+		}
+
+		value, err := unescapeBytes(valueBytes)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{key: keys[rawKey], value: value, rawKey: rawKey})
+	}
+	return pairs, nil
+}
+
+// unescapeBytes returns the unescaped form of b as a string, allocating only
+// the result where nothing is escaped.
+func unescapeBytes(b []byte) (string, error) {
+	if !bytes.ContainsAny(b, "%+") {
+		return string(b), nil
+	}
+	return url.QueryUnescape(string(b))
+}
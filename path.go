@@ -2,18 +2,32 @@ package formenc
 
 import (
 	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type pathSegment struct {
 	Key   string
-	Index bool // true for []
+	Index bool // true for [] and positional array segments
+	Pos   int  // position within the array, or -1 to append; the sorted position of map keys when encoding
+	Map   bool // true when Key is a map key rather than a field name
 }
 
 func parseKey(key string) ([]pathSegment, error) {
+	return parseDelimitedKey(key, '[', ']')
+}
+
+// parseDelimitedKey parses key as parseKey does, with nested segments opened
+// and closed by the given runes rather than by brackets.
+func parseDelimitedKey(key string, open, close rune) ([]pathSegment, error) {
+	full := key
+	openLen, closeLen := utf8.RuneLen(open), utf8.RuneLen(close)
+
 	var path []pathSegment
 	for len(key) > 0 {
-		i := strings.IndexByte(key, '[')
+		i := strings.IndexRune(key, open)
 		if i == -1 {
 			path = append(path, pathSegment{Key: key})
 			break
@@ -23,19 +37,194 @@ func parseKey(key string) ([]pathSegment, error) {
 			path = append(path, pathSegment{Key: key[:i]})
 		}
 
-		key = key[i+1:]
-		j := strings.IndexByte(key, ']')
+		key = key[i+openLen:]
+		j := strings.IndexRune(key, close)
 		if j == -1 {
-			return nil, fmt.Errorf("form: invalid key syntax")
+			return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid key syntax", Key: full})
 		}
 
 		part := key[:j]
 		if part == "" {
-			path = append(path, pathSegment{Index: true})
+			path = append(path, pathSegment{Index: true, Pos: -1})
 		} else {
 			path = append(path, pathSegment{Key: part})
 		}
-		key = key[j+1:]
+		key = key[j+closeLen:]
 	}
 	return path, nil
 }
+
+// renderSegments renders path using the bracketed key syntax understood by
+// parseKey, with positional array segments rendered as numeric indices.
+func renderSegments(path []pathSegment) string {
+	var b strings.Builder
+	for i, seg := range path {
+		switch {
+		case i == 0 && !seg.Index:
+			b.WriteString(seg.Key)
+		case seg.Index && seg.Pos < 0:
+			b.WriteString("[]")
+		case seg.Index:
+			b.WriteString("[" + strconv.Itoa(seg.Pos) + "]")
+		default:
+			b.WriteString("[" + seg.Key + "]")
+		}
+	}
+	return b.String()
+}
+
+// renderer converts a path into the key written to encoded output.
+// Implementations define the key syntax produced by a [Codec].
+type renderer interface {
+	render(path []pathSegment) string
+}
+
+// collapser is implemented by renderers whose key syntax cannot express some
+// nested values. Those values are collapsed into a single encoded value.
+type collapser interface {
+	// collapse returns the value written in place of v, or false if v should
+	// be encoded normally.
+	collapse(path []pathSegment, v reflect.Value) (string, bool, error)
+}
+
+// entryRenderer is implemented by renderers that write each map entry as a
+// numbered pair of key and value members, rather than naming the entry by its
+// key. The map key is written to the key returned by renderEntryKey.
+type entryRenderer interface {
+	renderEntryKey(path []pathSegment) string
+}
+
+// bracketRenderer produces the default bracketed key syntax. Array elements
+// are rendered as [] unless indices is set, in which case their position is
+// rendered. When dots is set nested keys are joined with dots instead of being
+// wrapped in brackets. When open and close are set they wrap nested segments
+// in place of brackets.
+type bracketRenderer struct {
+	indices bool
+	dots    bool
+
+	open, close rune
+}
+
+func (r bracketRenderer) render(path []pathSegment) string {
+	// Keys of top-level fields are the field names themselves.
+	if len(path) == 1 {
+		return path[0].Key
+	}
+
+	open, close := r.open, r.close
+	if open == 0 {
+		open, close = '[', ']'
+	}
+	delims := utf8.RuneLen(open) + utf8.RuneLen(close)
+
+	// Size the key up front so that it is built with a single allocation.
+	n := len(path[0].Key)
+	for _, seg := range path[1:] {
+		switch {
+		case seg.Index && !r.indices:
+			n += delims
+		case seg.Index:
+			n += delims + digits(seg.Pos)
+		default:
+			n += delims + len(seg.Key)
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(n)
+	b.WriteString(path[0].Key)
+	for _, seg := range path[1:] {
+		switch {
+		case seg.Index && !r.indices:
+			b.WriteRune(open)
+			b.WriteRune(close)
+		case seg.Index:
+			var buf [20]byte
+			b.WriteRune(open)
+			b.Write(strconv.AppendInt(buf[:0], int64(seg.Pos), 10))
+			b.WriteRune(close)
+		case r.dots:
+			b.WriteByte('.')
+			b.WriteString(seg.Key)
+		default:
+			b.WriteRune(open)
+			b.WriteString(seg.Key)
+			b.WriteRune(close)
+		}
+	}
+	return b.String()
+}
+
+// digits returns the number of characters in the decimal form of n.
+func digits(n int) int {
+	d := 1
+	if n < 0 {
+		d++
+		n = -n
+	}
+	for ; n >= 10; n /= 10 {
+		d++
+	}
+	return d
+}
+
+// dotParser understands dotted keys such as "items.0.name". Every segment is a
+// key; numeric keys address array elements by position when the target is a
+// slice. When sep is set it separates segments in place of a dot.
+type dotParser struct {
+	sep rune
+}
+
+func (d dotParser) parse(query string) ([]entry, error) {
+	pairs, err := splitPairs(query)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+	}
+
+	sep := "."
+	if d.sep != 0 {
+		sep = string(d.sep)
+	}
+
+	entries := make([]entry, 0, len(pairs))
+	for _, p := range pairs {
+		parts := strings.Split(p.key, sep)
+		path := make([]pathSegment, len(parts))
+		for i, part := range parts {
+			path[i] = pathSegment{Key: part}
+		}
+		entries = append(entries, entry{key: p.key, path: path, value: p.value})
+	}
+	return entries, nil
+}
+
+// dotRenderer produces dotted keys. Arrays of scalars are rendered as repeated
+// keys, while other array elements are addressed by position. When sep is set
+// it separates segments in place of a dot.
+type dotRenderer struct {
+	sep rune
+}
+
+func (d dotRenderer) render(path []pathSegment) string {
+	sep := "."
+	if d.sep != 0 {
+		sep = string(d.sep)
+	}
+
+	var b strings.Builder
+	b.WriteString(path[0].Key)
+	for i, seg := range path[1:] {
+		switch {
+		case seg.Index && i == len(path)-2:
+			// Scalar elements repeat the key.
+		case seg.Index:
+			b.WriteString(sep)
+			b.WriteString(strconv.Itoa(seg.Pos))
+		default:
+			b.WriteString(sep)
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PlanStep describes how a single key of form data would be decoded.
+type PlanStep struct {
+	Key   string // the form key, such as "user[name]"
+	Value string // the value of the key
+
+	// Field is the path of the Go value assigned, such as "Address.City",
+	// "Tags[]" or "Labels[env]", or empty if the key matches no field.
+	Field string
+
+	// Type is the type the value is converted to, and Conversion how it is
+	// converted: "string", "int", "uint", "float" or "bool" for values
+	// parsed as that kind, "DecodeFunc" or "UnmarshalForm" for values
+	// decoded by a registered function or the type itself, "interface" for
+	// values stored in an interface without a declared type, "kv" for maps
+	// packed into a single value by the kv tag flag, or the name of the
+	// parameter style splitting the value. Both are empty if the key
+	// matches no field.
+	Type       reflect.Type
+	Conversion string
+
+	// Err is the error decoding the key would cause, if any.
+	Err error
+}
+
+// Matched reports whether the key matches a field.
+func (s PlanStep) Matched() bool {
+	return s.Field != ""
+}
+
+// String returns a description of the step, such as
+// "user[age] → User.Age (int)" or "colour → unmatched".
+func (s PlanStep) String() string {
+	if !s.Matched() {
+		return s.Key + " → unmatched"
+	}
+	str := s.Key + " → " + s.Field + " (" + s.Conversion + ")"
+	if s.Err != nil {
+		str += ": " + s.Err.Error()
+	}
+	return str
+}
+
+// Plan reports how the form data would be decoded into a value of type t by
+// [Unmarshal], without decoding it. See [Codec.Plan].
+func Plan(data []byte, t reflect.Type) ([]PlanStep, error) {
+	return defaultCodec.Plan(data, t)
+}
+
+// Plan reports how c would decode the form data into a value of type t, which
+// may be a struct, map or slice, or a pointer to one. It returns a step for
+// each key, in the order the keys appear, naming the field the key assigns and
+// how its value is converted, or that the key matches no field. Errors
+// decoding individual keys are recorded in their steps; an error is only
+// returned if the data cannot be parsed.
+//
+// The keys are decoded into a new value of type t, so nothing is modified and
+// no validation is run.
+func (c *Codec) Plan(data []byte, t reflect.Type) ([]PlanStep, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || (t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Slice) {
+		return nil, fmt.Errorf("form: cannot plan decoding into %v, which is not a struct, map or slice", t)
+	}
+
+	entries, err := c.parse(strings.TrimSpace(string(data)), t)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.New(t).Elem()
+	steps := make([]PlanStep, 0, len(entries))
+	for _, e := range entries {
+		p := *c
+		p.plan = &planState{step: PlanStep{Key: e.key, Value: e.value}}
+		if err := p.assign(v, e.path, e.value); err != nil {
+			p.plan.step.Err = redactError(annotate(err, e.key, e.path, e.value))
+		}
+		if p.plan.secret {
+			p.plan.step.Value = redacted
+		}
+		p.plan.step.Field = strings.TrimPrefix(p.plan.field.String(), ".")
+		if p.plan.unmatched {
+			p.plan.step.Field, p.plan.step.Type, p.plan.step.Conversion = "", nil, ""
+		}
+		steps = append(steps, p.plan.step)
+	}
+	return steps, nil
+}
+
+// planState records the decoding of a single key by [Codec.Plan].
+type planState struct {
+	step  PlanStep
+	field strings.Builder
+
+	// unmatched is set when the key matches no field, and sealed once the
+	// conversion is known, so that no further steps are recorded.
+	unmatched bool
+	sealed    bool
+
+	// secret is set when the key assigns a field tagged secret.
+	secret bool
+}
+
+// planField records that the field of the struct v named key is assigned.
+func (c *Codec) planField(v reflect.Value, key string) {
+	if c.plan == nil || c.plan.sealed {
+		return
+	}
+	tags := c.tags(v)
+	for i := 0; i < v.NumField(); i++ {
+		if !tags[i].Ignore && tags[i].Name == key {
+			c.plan.field.WriteString("." + v.Type().Field(i).Name)
+			return
+		}
+	}
+}
+
+// planElem records that the element of a slice or map identified by seg is
+// assigned.
+func (c *Codec) planElem(seg pathSegment) {
+	if c.plan == nil || c.plan.sealed {
+		return
+	}
+	switch {
+	case seg.Index && seg.Pos >= 0:
+		c.plan.field.WriteString("[" + strconv.Itoa(seg.Pos) + "]")
+	case seg.Index:
+		c.plan.field.WriteString("[]")
+	default:
+		c.plan.field.WriteString("[" + seg.Key + "]")
+	}
+}
+
+// planLeaf records that the value is converted to t as described by
+// conversion.
+func (c *Codec) planLeaf(t reflect.Type, conversion string) {
+	if c.plan == nil || c.plan.sealed {
+		return
+	}
+	c.plan.step.Type, c.plan.step.Conversion = t, conversion
+	c.plan.sealed = true
+}
+
+// planUnmatched records that the key matches no field.
+func (c *Codec) planUnmatched() {
+	if c.plan != nil && !c.plan.sealed {
+		c.plan.unmatched = true
+		c.plan.sealed = true
+	}
+}
+
+// conversion describes how a value is converted to the type of v, which does
+// not decode the value itself.
+func conversion(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	}
+	return v.Kind().String()
+}
@@ -0,0 +1,97 @@
+package formenc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	type Import struct {
+		User   User              `form:"user"`
+		Tags   []string          `form:"tags"`
+		Labels map[string]string `form:"labels"`
+		Placed MyDate            `form:"placed"`
+		IDs    []int             `form:"ids,style=pipeDelimited"`
+		Extra  interface{}       `form:"extra"`
+	}
+
+	tests := map[string]struct {
+		input string
+		want  []string
+	}{
+		"nested fields": {
+			input: "user[name]=alice&user[age]=30&user[address][city]=Leeds",
+			want: []string{
+				"user[name] → User.Name (string)",
+				"user[age] → User.Age (int)",
+				"user[address][city] → User.Address.City (string)",
+			},
+		},
+		"collections": {
+			input: "tags[]=a&tags[]=b&labels[env]=prod&extra[a]=1",
+			want: []string{
+				"tags[] → Tags[] (string)",
+				"tags[] → Tags[] (string)",
+				"labels[env] → Labels[env] (string)",
+				"extra[a] → Extra (interface)",
+			},
+		},
+		"conversions": {
+			input: "placed=2024.01.02&ids=1|2",
+			want: []string{
+				"placed → Placed (UnmarshalForm)",
+				"ids → IDs (pipeDelimited)",
+			},
+		},
+		"unmatched and failing keys": {
+			input: "colour=red&user[nickname]=al&user[age]=old",
+			want: []string{
+				"colour → unmatched",
+				"user[nickname] → unmatched",
+				`user[age] → User.Age (int): cannot unmarshal "old" into key "user[age]" of type int: invalid syntax`,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			steps, err := formenc.Plan([]byte(tt.input), reflect.TypeOf(&Import{}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := make([]string, len(steps))
+			for i, s := range steps {
+				got[i] = s.String()
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlan_DoesNotDecode(t *testing.T) {
+	t.Parallel()
+
+	steps, err := formenc.Plan([]byte("name=alice&age=30"), reflect.TypeOf(Person{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 2 || steps[1].Type != reflect.TypeOf(0) || !steps[1].Matched() {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+
+	if _, err := formenc.Plan([]byte("a=1"), reflect.TypeOf("")); err == nil {
+		t.Error("expected error for a string target, got nil")
+	}
+}
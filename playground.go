@@ -0,0 +1,141 @@
+package formenc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithPlaygroundCompat configures a [Codec] to use the key syntax of
+// github.com/go-playground/form, so payloads produced by its encoder can be
+// decoded, and vice versa.
+//
+// In this syntax nested struct fields are joined with dots, array elements are
+// addressed by index and map keys are bracketed, as in
+// "Address[0].Phone=123&Labels[env]=prod". Values of type [time.Time] are
+// encoded and decoded in RFC 3339 format, and booleans additionally accept
+// the "on", "off", "yes" and "no" values recognised by that library.
+func WithPlaygroundCompat() Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithPlaygroundCompat", true, true); err != nil {
+			return err
+		}
+		c.parser = playgroundParser{}
+		c.renderer = playgroundRenderer{}
+		c.positionalKeys = true
+
+		opts := []Option{
+			WithDecodeFunc(time.Time{}, playgroundParseTime),
+			WithEncodeFunc(time.Time{}, playgroundFormatTime),
+			WithDecodeFunc(false, playgroundParseBool),
+		}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// playgroundParser understands keys such as "Address[0].Phone", where dots
+// separate struct fields and brackets enclose array indices and map keys.
+type playgroundParser struct{}
+
+func (playgroundParser) parse(query string) ([]entry, error) {
+	pairs, err := splitPairs(query)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+	}
+
+	entries := make([]entry, 0, len(pairs))
+	for _, p := range pairs {
+		path, err := parsePlaygroundKey(p.key)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: p.key, path: path, value: p.value})
+	}
+	return entries, nil
+}
+
+func parsePlaygroundKey(key string) ([]pathSegment, error) {
+	full := key
+
+	var path []pathSegment
+	for len(key) > 0 {
+		if key[0] == '[' {
+			j := strings.IndexByte(key, ']')
+			if j == -1 {
+				return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid key syntax", Key: full})
+			}
+
+			if part := key[1:j]; part == "" {
+				path = append(path, pathSegment{Index: true, Pos: -1})
+			} else {
+				path = append(path, pathSegment{Key: part, Map: true})
+			}
+			key = key[j+1:]
+			continue
+		}
+
+		// Field names follow a dot, except at the start of the key.
+		if key[0] == '.' && len(path) > 0 {
+			key = key[1:]
+		}
+
+		i := strings.IndexAny(key, ".[")
+		if i == -1 {
+			i = len(key)
+		}
+		path = append(path, pathSegment{Key: key[:i]})
+		key = key[i:]
+	}
+	return path, nil
+}
+
+// playgroundRenderer produces keys such as "Address[0].Phone".
+type playgroundRenderer struct{}
+
+func (playgroundRenderer) render(path []pathSegment) string {
+	var b strings.Builder
+	b.WriteString(path[0].Key)
+	for _, seg := range path[1:] {
+		switch {
+		case seg.Index:
+			b.WriteString("[")
+			b.WriteString(strconv.Itoa(seg.Pos))
+			b.WriteString("]")
+		case seg.Map:
+			b.WriteString("[")
+			b.WriteString(seg.Key)
+			b.WriteString("]")
+		default:
+			b.WriteString(".")
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
+
+func playgroundParseTime(s string) (interface{}, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func playgroundFormatTime(v interface{}) (string, error) {
+	return v.(time.Time).Format(time.RFC3339), nil
+}
+
+func playgroundParseBool(s string) (interface{}, error) {
+	switch s {
+	case "on", "yes", "ok":
+		return true, nil
+	case "", "off", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
@@ -0,0 +1,134 @@
+package formenc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type PlaygroundAddress struct {
+	Name  string
+	Phone string
+}
+
+type PlaygroundUser struct {
+	Name        string
+	Age         uint8
+	Address     []PlaygroundAddress
+	Active      bool `form:"active"`
+	MapExample  map[string]string
+	NestedMap   map[string]map[string]string
+	NestedArray [][]string
+	CreatedAt   time.Time
+}
+
+func TestCodec_PlaygroundCompat_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    PlaygroundUser
+		wantErr bool
+	}{
+		"library example": {
+			input: "Name=joeybloggs&Age=3" +
+				"&Address[0].Name=26+Here+Blvd.&Address[0].Phone=9(999)999-9999" +
+				"&Address[1].Name=26+There+Blvd.&Address[1].Phone=1(111)111-1111" +
+				"&active=on&MapExample[key]=value&NestedMap[key][key]=value" +
+				"&NestedArray[0][0]=value&NestedArray[0][1]=value" +
+				"&NestedArray[1][0]=value&NestedArray[1][1]=value" +
+				"&CreatedAt=2025-02-08T10:00:00Z",
+			want: PlaygroundUser{
+				Name: "joeybloggs",
+				Age:  3,
+				Address: []PlaygroundAddress{
+					{Name: "26 Here Blvd.", Phone: "9(999)999-9999"},
+					{Name: "26 There Blvd.", Phone: "1(111)111-1111"},
+				},
+				Active:      true,
+				MapExample:  map[string]string{"key": "value"},
+				NestedMap:   map[string]map[string]string{"key": {"key": "value"}},
+				NestedArray: [][]string{{"value", "value"}, {"value", "value"}},
+				CreatedAt:   time.Date(2025, 2, 8, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		"map keys containing dots": {
+			input: "MapExample[a.b]=c",
+			want: PlaygroundUser{
+				MapExample: map[string]string{"a.b": "c"},
+			},
+		},
+		"invalid time": {
+			input:   "CreatedAt=yesterday",
+			wantErr: true,
+		},
+		"unterminated bracket": {
+			input:   "Address[0.Name=x",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithPlaygroundCompat())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got PlaygroundUser
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCodec_PlaygroundCompat_Marshal(t *testing.T) {
+	t.Parallel()
+
+	user := PlaygroundUser{
+		Name:        "joeybloggs",
+		Address:     []PlaygroundAddress{{Name: "26 Here Blvd.", Phone: "999"}},
+		Active:      true,
+		NestedMap:   map[string]map[string]string{"key": {"key": "value"}},
+		NestedArray: [][]string{{"a", "b"}},
+		CreatedAt:   time.Date(2025, 2, 8, 10, 0, 0, 0, time.UTC),
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithPlaygroundCompat())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := codec.Marshal(user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Address%5B0%5D.Name=26+Here+Blvd.&Address%5B0%5D.Phone=999&Age=0" +
+		"&CreatedAt=2025-02-08T10%3A00%3A00Z&Name=joeybloggs" +
+		"&NestedArray%5B0%5D%5B0%5D=a&NestedArray%5B0%5D%5B1%5D=b" +
+		"&NestedMap%5Bkey%5D%5Bkey%5D=value&active=true"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var decoded PlaygroundUser
+	if err := codec.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(user, decoded); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
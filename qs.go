@@ -0,0 +1,354 @@
+package formenc
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Defaults used by the qs library when parsing.
+const (
+	qsDefaultArrayLimit     = 20
+	qsDefaultDepth          = 5
+	qsDefaultParameterLimit = 1000
+)
+
+var (
+	// qsDots matches dotted segments, which are rewritten as brackets when
+	// AllowDots is set.
+	qsDots = regexp.MustCompile(`\.([^.\[]+)`)
+
+	// qsChild matches a single bracketed segment of a key.
+	qsChild = regexp.MustCompile(`\[[^\[\]]*\]`)
+)
+
+// qsHole marks an unset element of a sparse array, the equivalent of an
+// undefined element in JavaScript. Holes are removed once parsing completes.
+var qsHole interface{} = qsUndefined{}
+
+type qsUndefined struct{}
+
+// QSOptions configures compatibility with the node qs library. Zero values
+// select the defaults used by qs.
+type QSOptions struct {
+	// AllowDots enables dot notation, so "a.b=c" is equivalent to "a[b]=c".
+	// Encoded keys use dot notation for nested maps and structs.
+	AllowDots bool
+
+	// ArrayLimit is the largest array index that is decoded as an array
+	// element. Larger indices are decoded as map keys instead. Defaults to 20
+	// when nil. A limit of zero decodes only "a[0]" and "a[]" as array
+	// elements, as arrayLimit: 0 does in qs.
+	ArrayLimit *int
+
+	// Depth is the maximum number of bracketed segments parsed from a key. Any
+	// remainder is treated as a single literal segment. Defaults to 5 when
+	// nil. A depth of zero leaves every key literal, as depth: 0 does in qs.
+	Depth *int
+
+	// ParameterLimit is the maximum number of pairs parsed from the input.
+	// Additional pairs are ignored. Defaults to 1000.
+	ParameterLimit int
+}
+
+// WithQSCompat configures a [Codec] to encode and decode form data the same
+// way as the qs library for node, which many frontends use to serialise
+// request bodies. Arrays are encoded with explicit indices, such as a[0]=b,
+// and decoded following the merging and compaction rules of qs.parse.
+//
+// An error is returned if any limit in opts is negative.
+func WithQSCompat(opts QSOptions) Option {
+	return func(c *Codec) error {
+		p := qsParser{opts: opts, arrayLimit: qsDefaultArrayLimit, depth: qsDefaultDepth}
+		if opts.ArrayLimit != nil {
+			p.arrayLimit = *opts.ArrayLimit
+		}
+		if opts.Depth != nil {
+			p.depth = *opts.Depth
+		}
+		if p.arrayLimit < 0 || p.depth < 0 || opts.ParameterLimit < 0 {
+			return fmt.Errorf("form: qs limits must not be negative")
+		}
+		if opts.ParameterLimit == 0 {
+			p.opts.ParameterLimit = qsDefaultParameterLimit
+		}
+		if err := c.setSyntax("WithQSCompat", true, true); err != nil {
+			return err
+		}
+		c.parser = p
+		c.renderer = bracketRenderer{indices: true, dots: opts.AllowDots}
+		return nil
+	}
+}
+
+// qsParser reproduces the behaviour of qs.parse. Each key is parsed into a
+// standalone object which is then merged into the result, so the parser builds
+// the complete tree before flattening it into entries.
+type qsParser struct {
+	opts QSOptions
+
+	// arrayLimit and depth hold the limits of opts, or their defaults.
+	arrayLimit int
+	depth      int
+}
+
+// parseType parses query as parse does, first reporting the keys of pairs
+// beyond the parameter limit, which are discarded, to the OnKeyDropped hook of
+// c.
+func (p qsParser) parseType(c *Codec, query string, _ reflect.Type) ([]entry, error) {
+	if c.hooks.OnKeyDropped != nil && query != "" {
+		parts := strings.Split(query, "&")
+		for i := p.opts.ParameterLimit; i < len(parts); i++ {
+			key, _, _ := strings.Cut(parts[i], "=")
+			c.dropKey(qsDecode(key), DropLimitExceeded)
+		}
+	}
+	return p.parse(query)
+}
+
+func (p qsParser) parse(query string) ([]entry, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	// Collect values by key, combining repeated keys into arrays, exactly as
+	// qs does before interpreting any brackets.
+	var keys []string
+	values := make(map[string]interface{})
+	for i, part := range strings.Split(query, "&") {
+		if i >= p.opts.ParameterLimit {
+			break
+		}
+
+		pos := strings.Index(part, "]=")
+		if pos == -1 {
+			pos = strings.IndexByte(part, '=')
+		} else {
+			pos++
+		}
+
+		var key, val string
+		if pos == -1 {
+			key = qsDecode(part)
+		} else {
+			key, val = qsDecode(part[:pos]), qsDecode(part[pos+1:])
+		}
+		if key == "" {
+			continue
+		}
+
+		switch existing := values[key].(type) {
+		case nil:
+			keys = append(keys, key)
+			values[key] = val
+		case []interface{}:
+			values[key] = append(existing, val)
+		default:
+			values[key] = []interface{}{existing, val}
+		}
+	}
+
+	var obj interface{} = make(map[string]interface{})
+	for _, key := range keys {
+		obj = qsMerge(obj, p.parseObject(p.splitKey(key), values[key]))
+	}
+
+	return flattenTree(nil, qsCompact(obj), nil), nil
+}
+
+// splitKey splits a key into its parent and bracketed children, honouring the
+// configured depth.
+func (p qsParser) splitKey(key string) []string {
+	if p.opts.AllowDots {
+		key = qsDots.ReplaceAllString(key, "[$1]")
+	}
+
+	var chain []string
+	matches := qsChild.FindAllStringIndex(key, -1)
+	if len(matches) == 0 || p.depth == 0 {
+		return []string{key}
+	}
+
+	if parent := key[:matches[0][0]]; parent != "" {
+		chain = append(chain, parent)
+	}
+
+	for i, m := range matches {
+		if i == p.depth {
+			// Anything beyond the depth limit is kept as a single segment.
+			return append(chain, "["+key[m[0]:]+"]")
+		}
+		chain = append(chain, key[m[0]:m[1]])
+	}
+	return chain
+}
+
+// parseObject builds the object described by chain, working outwards from the
+// leaf value.
+func (p qsParser) parseObject(chain []string, val interface{}) interface{} {
+	leaf := val
+	for i := len(chain) - 1; i >= 0; i-- {
+		root := chain[i]
+		if root == "[]" {
+			leaf = qsConcat(nil, leaf)
+			continue
+		}
+
+		clean := root
+		if strings.HasPrefix(root, "[") && strings.HasSuffix(root, "]") {
+			clean = root[1 : len(root)-1]
+		}
+
+		index, err := strconv.Atoi(clean)
+		if err == nil && root != clean && strconv.Itoa(index) == clean && index >= 0 && index <= p.arrayLimit {
+			arr := make([]interface{}, index+1)
+			for j := range arr {
+				arr[j] = qsHole
+			}
+			arr[index] = leaf
+			leaf = arr
+			continue
+		}
+
+		leaf = map[string]interface{}{clean: leaf}
+	}
+	return leaf
+}
+
+// qsDecode unescapes s, falling back to the input with '+' replaced when it is
+// not validly percent-encoded.
+func qsDecode(s string) string {
+	s = strings.ReplaceAll(s, "+", " ")
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// qsConcat mirrors JavaScript's [].concat, appending the elements of v when it
+// is an array and v itself otherwise.
+func qsConcat(arr []interface{}, v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return append(arr, a...)
+	}
+	return append(arr, v)
+}
+
+// qsMerge merges source into target following the rules of qs' utils.merge.
+func qsMerge(target, source interface{}) interface{} {
+	if source == nil || source == "" {
+		return target
+	}
+
+	switch src := source.(type) {
+	case string:
+		switch t := target.(type) {
+		case []interface{}:
+			return append(t, src)
+		case map[string]interface{}:
+			t[src] = "true"
+			return t
+		default:
+			return []interface{}{target, src}
+		}
+
+	case []interface{}:
+		switch t := target.(type) {
+		case []interface{}:
+			for i, item := range src {
+				if item == qsHole {
+					continue
+				}
+				if i >= len(t) || t[i] == qsHole {
+					for len(t) <= i {
+						t = append(t, qsHole)
+					}
+					t[i] = item
+					continue
+				}
+				if qsIsObject(t[i]) && qsIsObject(item) {
+					t[i] = qsMerge(t[i], item)
+				} else {
+					t = append(t, item)
+				}
+			}
+			return t
+		case map[string]interface{}:
+			for i, item := range src {
+				if item != qsHole {
+					qsMergeKey(t, strconv.Itoa(i), item)
+				}
+			}
+			return t
+		default:
+			return qsConcat([]interface{}{target}, src)
+		}
+
+	case map[string]interface{}:
+		switch t := target.(type) {
+		case []interface{}:
+			obj := qsArrayToObject(t)
+			for k, v := range src {
+				qsMergeKey(obj, k, v)
+			}
+			return obj
+		case map[string]interface{}:
+			for k, v := range src {
+				qsMergeKey(t, k, v)
+			}
+			return t
+		default:
+			return []interface{}{target, src}
+		}
+	}
+
+	return target
+}
+
+func qsMergeKey(obj map[string]interface{}, key string, value interface{}) {
+	if existing, ok := obj[key]; ok {
+		obj[key] = qsMerge(existing, value)
+		return
+	}
+	obj[key] = value
+}
+
+func qsArrayToObject(arr []interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(arr))
+	for i, v := range arr {
+		if v != qsHole {
+			obj[strconv.Itoa(i)] = v
+		}
+	}
+	return obj
+}
+
+func qsIsObject(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// qsCompact removes holes from every array in the tree.
+func qsCompact(v interface{}) interface{} {
+	switch n := v.(type) {
+	case []interface{}:
+		out := n[:0]
+		for _, elem := range n {
+			if elem != qsHole {
+				out = append(out, qsCompact(elem))
+			}
+		}
+		return out
+	case map[string]interface{}:
+		for k, elem := range n {
+			n[k] = qsCompact(elem)
+		}
+	}
+	return v
+}
@@ -0,0 +1,253 @@
+package formenc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestCodec_QSCompat_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		SKU string `form:"sku"`
+	}
+
+	type Order struct {
+		Items []Item `form:"items"`
+	}
+
+	tests := map[string]struct {
+		opts    formenc.QSOptions
+		input   string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		"nested objects": {
+			input:  "a[b][c]=d",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": map[string]interface{}{"c": "d"},
+				},
+			},
+		},
+		"dot notation": {
+			opts:   formenc.QSOptions{AllowDots: true},
+			input:  "a.b.c=d&a.e[f]=g",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": map[string]interface{}{"c": "d"},
+					"e": map[string]interface{}{"f": "g"},
+				},
+			},
+		},
+		"dots ignored by default": {
+			input:  "a.b=c",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a.b": "c"},
+		},
+		"sparse indices are compacted": {
+			input:  "a[1]=b&a[15]=c",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": []interface{}{"b", "c"},
+			},
+		},
+		"indices beyond array limit": {
+			input:  "a[100]=b",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{"100": "b"},
+			},
+		},
+		"custom array limit": {
+			opts:   formenc.QSOptions{ArrayLimit: intPtr(1)},
+			input:  "a[2]=b",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{"2": "b"},
+			},
+		},
+		"zero array limit": {
+			opts:   formenc.QSOptions{ArrayLimit: intPtr(0)},
+			input:  "a[0]=b&c[1]=d",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": []interface{}{"b"},
+				"c": map[string]interface{}{"1": "d"},
+			},
+		},
+		"zero depth": {
+			opts:   formenc.QSOptions{Depth: intPtr(0)},
+			input:  "a[b][c]=d&e[]=f",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a[b][c]": "d",
+				"e[]":     "f",
+			},
+		},
+		"zero depth with dots": {
+			opts:   formenc.QSOptions{AllowDots: true, Depth: intPtr(0)},
+			input:  "a.b=c",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a[b]": "c"},
+		},
+		"segments beyond depth": {
+			input:  "a[b][c][d][e][f][g][h]=i",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": map[string]interface{}{
+						"c": map[string]interface{}{
+							"d": map[string]interface{}{
+								"e": map[string]interface{}{
+									"f": map[string]interface{}{"[g][h]": "i"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"repeated keys combine": {
+			input:  "a=b&a=c",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": []interface{}{"b", "c"},
+			},
+		},
+		"array merged into object": {
+			input:  "a[]=b&a[t]=c",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{"0": "b", "t": "c"},
+			},
+		},
+		"parameter limit": {
+			opts:   formenc.QSOptions{ParameterLimit: 2},
+			input:  "a=1&b=2&c=3",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a": "1", "b": "2"},
+		},
+		"malformed escape kept verbatim": {
+			input:  "a=%zz+b",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a": "%zz b"},
+		},
+		"indexed struct slice": {
+			input:  "items[1][sku]=b&items[0][sku]=a",
+			target: &Order{},
+			want: &Order{
+				Items: []Item{{SKU: "a"}, {SKU: "b"}},
+			},
+		},
+		"depth larger than default": {
+			opts:   formenc.QSOptions{Depth: intPtr(10)},
+			input:  "a" + strings.Repeat("[a]", 6) + "=b",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{
+					"a": map[string]interface{}{
+						"a": map[string]interface{}{
+							"a": map[string]interface{}{
+								"a": map[string]interface{}{
+									"a": map[string]interface{}{"a": "b"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithQSCompat(tt.opts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = codec.Unmarshal([]byte(tt.input), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.target, tt.want); diff != "" {
+					t.Errorf("mismatch (-got +want):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCodec_QSCompat_Marshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts  formenc.QSOptions
+		input interface{}
+		want  []byte
+	}{
+		"indexed arrays": {
+			input: &Person{Name: "john", Pronouns: []string{"he", "him"}},
+			want:  pathEscape("name=john&pronouns[0]=he&pronouns[1]=him"),
+		},
+		"nested objects": {
+			input: &User{Name: "jane", Address: Address{City: "Leeds"}},
+			want:  pathEscape("address[city]=Leeds&address[state]=&address[street]=&address[zip]=&name=jane"),
+		},
+		"dot notation": {
+			opts:  formenc.QSOptions{AllowDots: true},
+			input: &User{Name: "jane", Address: Address{City: "Leeds"}},
+			want:  pathEscape("address.city=Leeds&address.state=&address.street=&address.zip=&name=jane"),
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithQSCompat(tt.opts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := codec.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodec_QSCompat_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]formenc.QSOptions{
+		"negative array limit":     {ArrayLimit: intPtr(-1)},
+		"negative depth":           {Depth: intPtr(-1)},
+		"negative parameter limit": {ParameterLimit: -1},
+	}
+	for name, opts := range tests {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := formenc.NewCodec(formenc.WithQSCompat(opts)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
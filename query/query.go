@@ -0,0 +1,100 @@
+// Package query binds URL query parameters to Go values using formenc, with
+// defaults suited to query strings rather than request bodies.
+//
+// Unlike form bodies, query strings are typed by hand, shared as links and
+// extended by proxies and analytics tools. The defaults therefore differ from
+// those of formenc:
+//
+//   - Lists may be written as comma separated values, ids=1,2,3, as well as
+//     repeated keys, ids=1&ids=2.
+//   - Booleans accept the spellings common in spreadsheets and command lines,
+//     such as yes, no, on, off, 1 and 0, and a key without a value, as in
+//     "?verbose", is true.
+//   - Keys that match no field, such as utm_source, are ignored.
+//   - A request without a query string leaves the value untouched.
+//
+// [Unmarshal] additionally reads '+' as a plus sign, as RFC 3986 does, rather
+// than as a space as [net/url.ParseQuery] and formenc do.
+package query
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// defaultCodec backs [BindQuery] and [UnmarshalString], and urlCodec backs
+// [Unmarshal].
+var (
+	defaultCodec = mustCodec(NewCodec())
+	urlCodec     = mustCodec(NewCodec(formenc.WithLiteralPlusKeys(), formenc.WithLiteralPlusValues()))
+)
+
+// NewCodec returns a [formenc.Codec] with the query defaults described in the
+// package documentation, followed by opts.
+func NewCodec(opts ...formenc.Option) (*formenc.Codec, error) {
+	defaults := []formenc.Option{
+		formenc.WithParameterStyle(formenc.StyleForm, false),
+		formenc.WithIgnoreUnknownKeys(),
+		formenc.WithDecodeFunc(false, parseBool),
+	}
+	return formenc.NewCodec(append(defaults, opts...)...)
+}
+
+// BindQuery decodes the query string of r into the value pointed to by v.
+func BindQuery(r *http.Request, v interface{}) error {
+	return UnmarshalString(r.URL.RawQuery, v)
+}
+
+// UnmarshalString decodes the query string into the value pointed to by v. A
+// leading '?' is ignored, and a '+' is a space, as in a request body.
+func UnmarshalString(query string, v interface{}) error {
+	query = strings.TrimPrefix(query, "?")
+	if query == "" {
+		return nil
+	}
+	return defaultCodec.Unmarshal([]byte(query), v)
+}
+
+// Unmarshal decodes the query string of u into the value pointed to by v, for
+// GET handlers and clients holding a parsed URL. A nil URL, or one without a
+// query string, leaves v untouched.
+//
+// Unlike [net/url.ParseQuery], [UnmarshalString] and the rest of formenc,
+// Unmarshal reads a '+' as a plus sign, as in "tz=+01:00" or
+// "phone=+441234", so that only "%20" is a space.
+func Unmarshal(u *url.URL, v interface{}) error {
+	if u == nil || u.RawQuery == "" {
+		return nil
+	}
+	return urlCodec.Unmarshal([]byte(u.RawQuery), v)
+}
+
+// Marshal returns the query string encoding of v. Lists, and the properties of
+// structs and maps, are written as comma separated values, as in the OpenAPI
+// form style without explode.
+func Marshal(v interface{}) (string, error) {
+	data, err := defaultCodec.Marshal(v)
+	return string(data), err
+}
+
+// parseBool parses the boolean spellings accepted in query strings.
+func parseBool(s string) (interface{}, error) {
+	switch strings.ToLower(s) {
+	case "", "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return nil, fmt.Errorf("invalid boolean %q", s)
+}
+
+func mustCodec(c *formenc.Codec, err error) *formenc.Codec {
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
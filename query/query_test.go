@@ -0,0 +1,141 @@
+package query_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc/query"
+)
+
+type Search struct {
+	Term     string   `form:"q"`
+	IDs      []int    `form:"ids"`
+	Tags     []string `form:"tags"`
+	Verbose  bool     `form:"verbose"`
+	Archived bool     `form:"archived"`
+	Page     int      `form:"page,omitempty"`
+	Filter   Filter   `form:"filter"`
+}
+
+type Filter struct {
+	Owner string `form:"owner"`
+}
+
+func TestBindQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		url     string
+		want    Search
+		wantErr bool
+	}{
+		"no query string": {
+			url:  "/search",
+			want: Search{},
+		},
+		"comma separated lists": {
+			url:  "/search?q=go&ids=1,2,3&tags=a,b",
+			want: Search{Term: "go", IDs: []int{1, 2, 3}, Tags: []string{"a", "b"}},
+		},
+		"repeated keys": {
+			url:  "/search?ids=1&ids=2,3",
+			want: Search{IDs: []int{1, 2, 3}},
+		},
+		"boolean spellings": {
+			url:  "/search?verbose&archived=no",
+			want: Search{Verbose: true, Archived: false},
+		},
+		"boolean yes": {
+			url:  "/search?verbose=YES&archived=on",
+			want: Search{Verbose: true, Archived: true},
+		},
+		"unknown keys ignored": {
+			url:  "/search?q=go&utm_source=newsletter",
+			want: Search{Term: "go"},
+		},
+		"nested keys": {
+			url:  "/search?filter[owner]=alice&page=2",
+			want: Search{Page: 2, Filter: Filter{Owner: "alice"}},
+		},
+		"invalid boolean": {
+			url:     "/search?verbose=maybe",
+			wantErr: true,
+		},
+		"invalid number": {
+			url:     "/search?ids=1,x",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("GET", tt.url, nil)
+
+			var got Search
+			err := query.BindQuery(r, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("https://example.com/search?q=c++%20go&tags=a+b,c&verbose&utm_source=mail")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Search
+	if err := query.Unmarshal(u, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Search{Term: "c++ go", Tags: []string{"a+b", "c"}, Verbose: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if err := query.Unmarshal(nil, &got); err != nil {
+		t.Errorf("unexpected error for nil URL: %v", err)
+	}
+}
+
+func TestUnmarshalString(t *testing.T) {
+	t.Parallel()
+
+	var got Search
+	if err := query.UnmarshalString("?q=c++%20go&tags=a+b,c", &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Search{Term: "c   go", Tags: []string{"a b", "c"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	got, err := query.Marshal(Search{Term: "go", IDs: []int{1, 2}, Verbose: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "archived=false&filter=owner%2C&ids=1%2C2&q=go&tags=&verbose=true"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
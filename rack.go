@@ -0,0 +1,255 @@
+package formenc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rackDepthLimit is the default maximum nesting depth accepted by Rack.
+const rackDepthLimit = 32
+
+// rackSeparator matches the pair separator used by Rack, which permits spaces
+// following each ampersand.
+var rackSeparator = regexp.MustCompile(`& *`)
+
+// WithRackCompat configures a [Codec] to decode form data exactly as
+// Rack::Utils.parse_nested_query does, so that payloads produced for Ruby on
+// Rails applications decode identically in Go.
+//
+// In this mode "x[][y]" keys group into the last hash of the array until a key
+// repeats, semicolons are not treated as separators, and mixing array and hash
+// notation for the same key is an error. Keys without a value, which Rack
+// decodes as nil, decode as empty strings.
+func WithRackCompat() Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithRackCompat", true, false); err != nil {
+			return err
+		}
+		c.parser = rackParser{depthLimit: rackDepthLimit}
+		return nil
+	}
+}
+
+// rackParser reproduces the nesting rules of Rack's query parser. Unlike
+// [bracketParser] the meaning of a key depends on the keys that precede it,
+// so the parser builds the complete parameter tree before flattening it into
+// entries with explicit array positions.
+type rackParser struct {
+	depthLimit int
+}
+
+func (p rackParser) parse(query string) ([]entry, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	params := make(map[string]interface{})
+
+	for _, s := range rackSeparator.Split(query, -1) {
+		if s == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(s, "=")
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+		}
+
+		var v interface{}
+		if ok {
+			if v, err = url.QueryUnescape(value); err != nil {
+				return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: err})
+			}
+		}
+
+		if _, err := p.normalize(params, key, v, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return flattenTree(nil, params, nil), nil
+}
+
+// normalize inserts v into params at the location described by name. It is a
+// direct translation of Rack's normalize_params, returning either params or,
+// for a trailing "[]" below the root, a single element array.
+func (p rackParser) normalize(params map[string]interface{}, name string, v interface{}, depth int) (interface{}, error) {
+	if depth >= p.depthLimit {
+		return nil, fmt.Errorf("form: %w", &LimitExceededError{Limit: "nesting depth", Max: p.depthLimit})
+	}
+
+	var k, after string
+	switch {
+	case depth == 0:
+		// Start of parsing, don't treat [] or [ at the start of the string
+		// specially.
+		if start := strings.IndexByte(name[min(1, len(name)):], '['); start >= 0 {
+			k, after = name[:start+1], name[start+1:]
+		} else {
+			k = name
+		}
+	case strings.HasPrefix(name, "[]"):
+		k, after = "[]", name[2:]
+	case strings.HasPrefix(name, "[") && strings.IndexByte(name[1:], ']') >= 0:
+		end := strings.IndexByte(name[1:], ']') + 1
+		k, after = name[1:end], name[end+1:]
+	default:
+		// Probably malformed input, nested but not starting with [. Treat the
+		// full name as the key.
+		k = name
+	}
+
+	if k == "" {
+		return nil, nil
+	}
+
+	switch {
+	case after == "":
+		if k == "[]" && depth != 0 {
+			return []interface{}{v}, nil
+		}
+		params[k] = v
+
+	case after == "[":
+		params[name] = v
+
+	case after == "[]":
+		arr, err := rackArray(params, k)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = append(arr, v)
+
+	case strings.HasPrefix(after, "[]"):
+		// Recognise x[][y] (hash inside array) parameters, otherwise handle
+		// other nested array parameters.
+		childKey := after[2:]
+		if len(after) > 3 && after[2] == '[' && strings.HasSuffix(after, "]") {
+			if ck := after[3 : len(after)-1]; ck != "" && !strings.ContainsAny(ck, "[]") {
+				childKey = ck
+			}
+		}
+
+		arr, err := rackArray(params, k)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(arr) > 0 {
+			if last, ok := arr[len(arr)-1].(map[string]interface{}); ok && !rackHasKey(last, childKey) {
+				if _, err := p.normalize(last, childKey, v, depth+1); err != nil {
+					return nil, err
+				}
+				params[k] = arr
+				break
+			}
+		}
+
+		child, err := p.normalize(make(map[string]interface{}), childKey, v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = append(arr, child)
+
+	default:
+		if params[k] == nil {
+			params[k] = make(map[string]interface{})
+		}
+		hash, ok := params[k].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: fmt.Sprintf("expected Hash (got %s) for param `%s'", rackClass(params[k]), k)})
+		}
+		child, err := p.normalize(hash, after, v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = child
+	}
+
+	return params, nil
+}
+
+// rackArray returns the array held by params[k], treating a missing or nil
+// value as an empty array.
+func rackArray(params map[string]interface{}, k string) ([]interface{}, error) {
+	if params[k] == nil {
+		return nil, nil
+	}
+	arr, ok := params[k].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("form: %w", &SyntaxError{Msg: fmt.Sprintf("expected Array (got %s) for param `%s'", rackClass(params[k]), k)})
+	}
+	return arr, nil
+}
+
+// rackHasKey reports whether the bracketed key is already present in hash,
+// mirroring Rack's params_hash_has_key?.
+func rackHasKey(hash map[string]interface{}, key string) bool {
+	if strings.Contains(key, "[]") {
+		return false
+	}
+
+	var h interface{} = hash
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return r == '[' || r == ']' }) {
+		m, ok := h.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if h, ok = m[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// rackClass names the Ruby class Rack would report for v.
+func rackClass(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "Hash"
+	case []interface{}:
+		return "Array"
+	case nil:
+		return "NilClass"
+	default:
+		return "String"
+	}
+}
+
+// flattenTree converts a tree of nested maps and slices into entries, giving
+// array elements explicit positions so the grouping established while
+// building the tree is preserved during assignment.
+func flattenTree(entries []entry, node interface{}, path []pathSegment) []entry {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			entries = flattenTree(entries, n[k], appendSegment(path, pathSegment{Key: k}))
+		}
+	case []interface{}:
+		for i, elem := range n {
+			entries = flattenTree(entries, elem, appendSegment(path, pathSegment{Index: true, Pos: i}))
+		}
+	case string:
+		entries = append(entries, entry{key: renderSegments(path), path: path, value: n})
+	case nil:
+		entries = append(entries, entry{key: renderSegments(path), path: path})
+	}
+	return entries
+}
+
+// appendSegment returns a copy of path with seg appended, so sibling paths
+// never share a backing array.
+func appendSegment(path []pathSegment, seg pathSegment) []pathSegment {
+	out := make([]pathSegment, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, seg)
+}
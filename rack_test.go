@@ -0,0 +1,141 @@
+package formenc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestCodec_RackCompat(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		SKU      string `form:"sku"`
+		Quantity int    `form:"quantity"`
+	}
+
+	type Order struct {
+		Name  string `form:"name"`
+		Items []Item `form:"items"`
+	}
+
+	tests := map[string]struct {
+		input   string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		"hashes grouped within array": {
+			input:  "items[][a]=1&items[][b]=2&items[][a]=3",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"a": "1", "b": "2"},
+					map[string]interface{}{"a": "3"},
+				},
+			},
+		},
+		"nested hashes grouped within array": {
+			input:  "x[][y][z]=1&x[][y][w]=2&x[][y][z]=3",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"x": []interface{}{
+					map[string]interface{}{"y": map[string]interface{}{"z": "1", "w": "2"}},
+					map[string]interface{}{"y": map[string]interface{}{"z": "3"}},
+				},
+			},
+		},
+		"array of arrays": {
+			input:  "grid[][]=1&grid[][]=2",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"grid": []interface{}{
+					[]interface{}{"1"},
+					[]interface{}{"2"},
+				},
+			},
+		},
+		"grouped hashes into struct slice": {
+			input:  "name=bob&items[][sku]=a1&items[][quantity]=2&items[][sku]=b2&items[][quantity]=5",
+			target: &Order{},
+			want: &Order{
+				Name: "bob",
+				Items: []Item{
+					{SKU: "a1", Quantity: 2},
+					{SKU: "b2", Quantity: 5},
+				},
+			},
+		},
+		"semicolon is not a separator": {
+			input:  "a=1;b=2",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a": "1;b=2"},
+		},
+		"spaces following separator": {
+			input:  "a=1&  b=2",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a": "1", "b": "2"},
+		},
+		"leading bracket is part of the key": {
+			input:  "[a]=1",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"[a]": "1"},
+		},
+		"unterminated bracket": {
+			input:  "a[b=1",
+			target: new(map[string]interface{}),
+			want: &map[string]interface{}{
+				"a": map[string]interface{}{"[b": "1"},
+			},
+		},
+		"key without value": {
+			input:  "a&b=2",
+			target: new(map[string]interface{}),
+			want:   &map[string]interface{}{"a": "", "b": "2"},
+		},
+		"hash after array": {
+			input:   "a[]=1&a[b]=2",
+			target:  new(map[string]interface{}),
+			wantErr: true,
+		},
+		"array after hash": {
+			input:   "a[b]=1&a[]=2",
+			target:  new(map[string]interface{}),
+			wantErr: true,
+		},
+		"hash after string": {
+			input:   "a=1&a[b]=2",
+			target:  new(map[string]interface{}),
+			wantErr: true,
+		},
+		"exceeds depth limit": {
+			input:   "a" + strings.Repeat("[a]", 32) + "=1",
+			target:  new(map[string]interface{}),
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithRackCompat())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = codec.Unmarshal([]byte(tt.input), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.target, tt.want); diff != "" {
+					t.Errorf("mismatch (-got +want):\n%s", diff)
+				}
+			}
+		})
+	}
+}
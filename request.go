@@ -0,0 +1,86 @@
+package formenc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// multipartPath is the import path of the multipart subpackage, whose File
+// type cannot be urlencoded.
+const multipartPath = "github.com/tomasbasham/formenc/multipart"
+
+// NewRequest returns an HTTP request carrying the form encoding of v. See
+// [Codec.NewRequest].
+func NewRequest(ctx context.Context, method, target string, v interface{}) (*http.Request, error) {
+	return defaultCodec.NewRequest(ctx, method, target, v)
+}
+
+// NewRequest returns an HTTP request for method and target, a URL, carrying
+// the form encoding of v by c. For GET and HEAD requests the encoding is
+// appended to the query string of target. For other methods it is the body of
+// the request, with the Content-Type application/x-www-form-urlencoded and a
+// Content-Length, and the request can be retried as its body can be read
+// again.
+//
+// Values holding files must be sent as multipart form data, for which the
+// multipart subpackage provides a NewRequest of its own. A value whose type
+// holds a multipart File returns an error saying so.
+func (c *Codec) NewRequest(ctx context.Context, method, target string, v interface{}) (*http.Request, error) {
+	if t := reflect.TypeOf(v); holdsFile(t, map[reflect.Type]bool{}) {
+		return nil, fmt.Errorf("form: %s holds files, which must be sent with multipart.NewRequest", t)
+	}
+	data, err := c.MarshalContext(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == http.MethodGet || method == http.MethodHead {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(data) == 0:
+		case u.RawQuery == "":
+			u.RawQuery = string(data)
+		default:
+			u.RawQuery += "&" + string(data)
+		}
+		return http.NewRequestWithContext(ctx, method, u.String(), nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// holdsFile reports whether values of type t can hold a File of the multipart
+// subpackage, through fields, pointers and elements.
+func holdsFile(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == nil || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsFile(t.Elem(), seen)
+	case reflect.Struct:
+		if t.PkgPath() == multipartPath && t.Name() == "File" {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && holdsFile(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
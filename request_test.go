@@ -0,0 +1,89 @@
+package formenc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+	"github.com/tomasbasham/formenc/multipart"
+)
+
+func TestNewRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		method          string
+		url             string
+		wantURL         string
+		wantBody        string
+		wantContentType string
+	}{
+		"post body": {
+			method:          http.MethodPost,
+			url:             "https://example.com/people",
+			wantURL:         "https://example.com/people",
+			wantBody:        "age=20&name=john",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		"get query": {
+			method:  http.MethodGet,
+			url:     "https://example.com/people",
+			wantURL: "https://example.com/people?age=20&name=john",
+		},
+		"get existing query": {
+			method:  http.MethodGet,
+			url:     "https://example.com/people?page=2#top",
+			wantURL: "https://example.com/people?page=2&age=20&name=john#top",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := formenc.NewRequest(context.Background(), tt.method, tt.url, Person{Name: "john", Age: 20})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantURL, req.URL.String()); diff != "" {
+				t.Errorf("url mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantContentType, req.Header.Get("Content-Type")); diff != "" {
+				t.Errorf("content type mismatch (-want +got):\n%s", diff)
+			}
+			if req.ContentLength != int64(len(tt.wantBody)) {
+				t.Errorf("expected content length %d, got %d", len(tt.wantBody), req.ContentLength)
+			}
+
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			if diff := cmp.Diff(tt.wantBody, string(body)); diff != "" {
+				t.Errorf("body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewRequest_Error(t *testing.T) {
+	t.Parallel()
+
+	if _, err := formenc.NewRequest(context.Background(), http.MethodPost, "https://example.com", 42); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	type Upload struct {
+		Title  string            `form:"title"`
+		Avatar []*multipart.File `form:"avatar"`
+	}
+	_, err := formenc.NewRequest(context.Background(), http.MethodPost, "https://example.com", Upload{Title: "a"})
+	if err == nil || !strings.Contains(err.Error(), "multipart.NewRequest") {
+		t.Errorf("expected error pointing to multipart.NewRequest, got: %v", err)
+	}
+}
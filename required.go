@@ -0,0 +1,147 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithRequired configures a [Codec] to reject form data that omits a struct
+// field tagged with the required flag, as in `form:"email,required"`, with a
+// [MissingFieldError]. The fields of the value decoded are always checked, and
+// those of a nested struct, or of an element of a slice or map, only if the
+// form data assigns some part of it, so that an optional address need not be
+// sent but a partial one is rejected.
+func WithRequired() Option {
+	return func(c *Codec) error {
+		c.required = true
+		return nil
+	}
+}
+
+// UnmarshalStrict parses the form data and stores the result in the value
+// pointed to by v, as [Unmarshal] does, with all of the strictness options
+// enabled. See [Codec.UnmarshalStrict].
+func UnmarshalStrict(data []byte, v interface{}) error {
+	return defaultCodec.UnmarshalStrict(data, v)
+}
+
+// UnmarshalStrict behaves as [Codec.Unmarshal] with unknown keys rejected, as
+// they are unless [WithIgnoreUnknownKeys] is given, with fields assigned by
+// more than one key rejected, as by [WithStrict], and with required fields
+// enforced, as by [WithRequired].
+func (c *Codec) UnmarshalStrict(data []byte, v interface{}) error {
+	s := *c
+	s.ignoreUnknownKeys = false
+	s.strict = true
+	s.required = true
+	return s.unmarshal(data, v)
+}
+
+// presence records the parts of a value assigned by form data, keyed by
+// "."+name for the struct field named name and "["+key+"]" for the element
+// of a slice or map at key.
+type presence struct {
+	children map[string]*presence
+}
+
+// child returns the presence of the part of p named key, adding it if needed.
+func (p *presence) child(key string) *presence {
+	if ch, ok := p.children[key]; ok {
+		return ch
+	}
+	if p.children == nil {
+		p.children = make(map[string]*presence)
+	}
+	ch := &presence{}
+	p.children[key] = ch
+	return ch
+}
+
+// enter records that the part of the value being assigned named key is
+// assigned, returning a function restoring the part assigned before. It must
+// only be called while c records presence.
+func (c *Codec) enter(key string) func() {
+	prev := c.present
+	c.present = prev.child(key)
+	return func() { c.present = prev }
+}
+
+// checkRequired returns a [MissingFieldError] for each required field of v,
+// and of the values it holds, that p records as unassigned. path is the path
+// of v.
+func (c *Codec) checkRequired(v reflect.Value, p *presence, path []pathSegment) []error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var errs []error
+	switch v.Kind() {
+	case reflect.Struct:
+		tags := c.tags(v)
+		for i := 0; i < v.NumField(); i++ {
+			tag := tags[i]
+			if tag.Ignore || tag.ReadOnly {
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], tag.segments()...)
+			child := p.children["."+tag.Name]
+			if child == nil {
+				if tag.Required {
+					errs = append(errs, &MissingFieldError{Key: c.renderer.render(fieldPath), Path: keyPath(fieldPath), Field: tag.Name, Type: v.Type()})
+				}
+				continue
+			}
+			errs = append(errs, c.checkRequired(v.Field(i), child, fieldPath)...)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if child := p.children["["+strconv.Itoa(i)+"]"]; child != nil {
+				errs = append(errs, c.checkRequired(v.Index(i), child, append(path[:len(path):len(path)], pathSegment{Key: strconv.Itoa(i)}))...)
+			}
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := make([]string, 0, len(p.children))
+		for key := range p.children {
+			if strings.HasPrefix(key, "[") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			name := key[1 : len(key)-1]
+			elem := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if elem.IsValid() {
+				errs = append(errs, c.checkRequired(elem, p.children[key], append(path[:len(path):len(path)], pathSegment{Key: name}))...)
+			}
+		}
+	}
+	return errs
+}
+
+// missingFields reports the required fields of v left unassigned by the form
+// data decoded with the presence root, adding them to multi when errors are
+// aggregated.
+func (c *Codec) missingFields(v reflect.Value, root *presence, multi *MultiError) error {
+	errs := c.checkRequired(v, root, nil)
+	if len(errs) == 0 {
+		return nil
+	}
+	if !c.aggregateErrors {
+		return fmt.Errorf("form: %w", errs[0])
+	}
+	for _, err := range errs {
+		multi.add(err.(*MissingFieldError).Key, err)
+	}
+	return nil
+}
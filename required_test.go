@@ -0,0 +1,160 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Enrolment struct {
+	Email    string          `form:"email,required"`
+	Name     string          `form:"name"`
+	Address  *PostalAddress  `form:"address"`
+	Contacts []ContactNumber `form:"contacts"`
+}
+
+type PostalAddress struct {
+	City string `form:"city,required"`
+	Zip  string `form:"zip"`
+}
+
+type ContactNumber struct {
+	Phone string `form:"phone,required"`
+	Label string `form:"label"`
+}
+
+func TestWithRequired(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		wantErr *formenc.MissingFieldError
+	}{
+		"all required fields": {
+			input: "email=a@example.com&address[city]=Leeds&contacts[0][phone]=123",
+		},
+		"optional struct absent": {
+			input: "email=a@example.com&name=Ann",
+		},
+		"missing top-level field": {
+			input:   "name=Ann",
+			wantErr: &formenc.MissingFieldError{Key: "email", Path: mustPath("email"), Field: "email"},
+		},
+		"missing field of partial struct": {
+			input:   "email=a@example.com&address[zip]=LS1",
+			wantErr: &formenc.MissingFieldError{Key: "address[city]", Path: mustPath("address[city]"), Field: "city"},
+		},
+		"missing field of slice element": {
+			input:   "email=a@example.com&contacts[0][phone]=1&contacts[1][label]=work",
+			wantErr: &formenc.MissingFieldError{Key: "contacts[1][phone]", Path: mustPath("contacts[1][phone]"), Field: "phone"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithRequired(), formenc.WithIndexedKeys())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Enrolment
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var missing *formenc.MissingFieldError
+			if !errors.As(err, &missing) {
+				t.Fatalf("expected MissingFieldError, got: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantErr, missing, cmpopts.IgnoreFields(formenc.MissingFieldError{}, "Type")); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithRequired_AggregateErrors(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithRequired(), formenc.WithAggregateErrors(), formenc.WithIndexedKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Enrolment
+	err = codec.Unmarshal([]byte("address[zip]=LS1&contacts[0][label]=home"), &got)
+
+	var multi *formenc.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, got: %v", err)
+	}
+	if len(multi.Errors) != 3 {
+		t.Errorf("expected 3 errors, got: %v", multi)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	t.Parallel()
+
+	lenient, err := formenc.NewCodec(formenc.WithIgnoreUnknownKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		input string
+		check func(error) bool
+	}{
+		"unknown key": {
+			input: "email=a@example.com&nickname=ann",
+			check: func(err error) bool {
+				var target *formenc.UnknownFieldError
+				return errors.As(err, &target)
+			},
+		},
+		"duplicate key": {
+			input: "email=a@example.com&email=b@example.com",
+			check: func(err error) bool {
+				var target *formenc.DuplicateFieldError
+				return errors.As(err, &target)
+			},
+		},
+		"missing required key": {
+			input: "name=Ann",
+			check: func(err error) bool {
+				var target *formenc.MissingFieldError
+				return errors.As(err, &target)
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Enrolment
+			if err := formenc.UnmarshalStrict([]byte(tt.input), &got); !tt.check(err) {
+				t.Errorf("unexpected error from UnmarshalStrict: %v", err)
+			}
+			if err := lenient.UnmarshalStrict([]byte(tt.input), &got); !tt.check(err) {
+				t.Errorf("unexpected error from Codec.UnmarshalStrict: %v", err)
+			}
+		})
+	}
+
+	// The codec is unchanged.
+	var got Enrolment
+	if err := lenient.Unmarshal([]byte("nickname=ann"), &got); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// plain returns a copy of c that performs no checks of its own, used to
+// perform the reverse operation when checking a round trip.
+func (c *Codec) plain() *Codec {
+	p := *c
+	p.roundTrip = false
+	p.validation = false
+	p.validator = nil
+	p.hooks = Hooks{}
+	return &p
+}
+
+// checkMarshal verifies that data, the encoding of v, decodes back to v.
+func (c *Codec) checkMarshal(v reflect.Value, data []byte) error {
+	decoded := reflect.New(v.Type())
+	if len(data) > 0 {
+		if err := c.plain().unmarshal(data, decoded.Interface()); err != nil {
+			return fmt.Errorf("form: round trip failed: %w", err)
+		}
+	}
+
+	if path, ok := c.difference(v, decoded.Elem(), nil); ok {
+		err := &RoundTripError{
+			Key:  renderSegments(path),
+			Want: describe(c.lookup(v, path)),
+			Got:  describe(c.lookup(decoded.Elem(), path)),
+		}
+		if c.isSecret(v.Type(), path) {
+			err.Want, err.Got = redacted, redacted
+		}
+		return fmt.Errorf("form: %w", err)
+	}
+	return nil
+}
+
+// checkUnmarshal verifies that v, decoded from entries, encodes back to the
+// same pairs.
+func (c *Codec) checkUnmarshal(entries []entry, v reflect.Value) error {
+	p := c.plain()
+	data, err := p.marshal(v.Interface())
+	if err != nil {
+		return fmt.Errorf("form: round trip failed: %w", err)
+	}
+	encoded, err := p.parse(string(data), v.Type())
+	if err != nil {
+		return fmt.Errorf("form: round trip failed: %w", err)
+	}
+
+	want, got := c.groupEntries(entries, v.Type()), c.groupEntries(encoded, v.Type())
+	keys := make([]string, 0, len(want)+len(got))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !reflect.DeepEqual(want[k], got[k]) {
+			err := &RoundTripError{
+				Key:  k,
+				Want: describeValues(want[k]),
+				Got:  describeValues(got[k]),
+			}
+			if path, perr := parseKey(k); perr == nil && c.isSecret(v.Type(), path) {
+				err.Want, err.Got = redacted, redacted
+			}
+			return fmt.Errorf("form: %w", err)
+		}
+	}
+	return nil
+}
+
+// groupEntries collects the values of entries, decoded into a value of type t,
+// by their canonical key, with the values of each key sorted. Entries for
+// fields that are only encoded or only decoded are left out.
+func (c *Codec) groupEntries(entries []entry, t reflect.Type) map[string][]string {
+	groups := make(map[string][]string)
+	for _, e := range entries {
+		if c.oneWay(t, e.path) {
+			continue
+		}
+		k := renderSegments(e.path)
+		groups[k] = append(groups[k], e.value)
+	}
+	for _, values := range groups {
+		sort.Strings(values)
+	}
+	return groups
+}
+
+// oneWay reports whether path addresses a field of a value of type t that is
+// tagged readonly or writeonly.
+func (c *Codec) oneWay(t reflect.Type, path []pathSegment) bool {
+	for _, tag := range c.fieldTags(t, path) {
+		if tag.ReadOnly || tag.WriteOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecret reports whether path addresses a field of a value of type t that
+// is tagged secret, or a value within one.
+func (c *Codec) isSecret(t reflect.Type, path []pathSegment) bool {
+	for _, tag := range c.fieldTags(t, path) {
+		if tag.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldTags returns the tags of the struct fields that path passes through
+// within a value of type t, outermost first.
+func (c *Codec) fieldTags(t reflect.Type, path []pathSegment) []*tag {
+	var fields []*tag
+	for len(path) > 0 {
+		seg := path[0]
+		path = path[1:]
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Struct:
+			tags := c.tags(reflect.New(t).Elem())
+			i := 0
+			for ; i < len(tags); i++ {
+				if tags[i].matchesPath(seg.Key, path) {
+					path = path[len(tags[i].Path)-1:]
+					break
+				}
+				if !tags[i].Ignore && tags[i].Name == seg.Key {
+					break
+				}
+			}
+			if i == len(tags) {
+				return fields
+			}
+			fields = append(fields, tags[i])
+			t = t.Field(i).Type
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return fields
+		}
+	}
+	return fields
+}
+
+// difference returns the path of the first difference between a and b,
+// visiting only what an encoding of a would contain. Leaf values are equal if
+// they have the same type and encoding.
+func (c *Codec) difference(a, b reflect.Value, path []pathSegment) ([]pathSegment, bool) {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		if isAbsent(a) && isAbsent(b) {
+			return nil, false
+		}
+		return path, true
+	}
+	if a.Type() != b.Type() {
+		return path, true
+	}
+
+	if c.encodesLeaf(a) {
+		sa, errA := c.leafString(a)
+		sb, errB := c.leafString(b)
+		if errA != nil || errB != nil || sa != sb {
+			return path, true
+		}
+		return nil, false
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		tags := c.tags(a)
+		for i := 0; i < a.NumField(); i++ {
+			if tags[i].Ignore || tags[i].Name == "" || !a.Type().Field(i).IsExported() || !c.inGroups(tags[i]) {
+				continue
+			}
+			// Fields that are only encoded or only decoded do not survive a
+			// round trip.
+			if tags[i].ReadOnly || tags[i].WriteOnly {
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], tags[i].segments()...)
+			if p, ok := c.difference(a.Field(i), b.Field(i), fieldPath); ok {
+				return p, true
+			}
+		}
+
+	case reflect.Map:
+		for _, k := range unionKeys(a, b) {
+			if p, ok := c.difference(a.MapIndex(k), b.MapIndex(k), appendSegment(path, pathSegment{Key: k.String(), Map: true})); ok {
+				return p, true
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			var ea, eb reflect.Value
+			if i < a.Len() {
+				ea = a.Index(i)
+			}
+			if i < b.Len() {
+				eb = b.Index(i)
+			}
+			if p, ok := c.difference(ea, eb, appendSegment(path, pathSegment{Index: true, Pos: i})); ok {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookup returns the value found by following path from v, or the zero Value
+// if there is none.
+func (c *Codec) lookup(v reflect.Value, path []pathSegment) reflect.Value {
+	for _, seg := range path {
+		v = indirect(v)
+		if !v.IsValid() {
+			return v
+		}
+		switch {
+		case v.Kind() == reflect.Struct:
+			v, _ = c.findStructField(v, seg.Key)
+		case v.Kind() == reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(seg.Key).Convert(v.Type().Key()))
+		case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && seg.Pos < v.Len():
+			v = v.Index(seg.Pos)
+		default:
+			return reflect.Value{}
+		}
+	}
+	return indirect(v)
+}
+
+// encodesLeaf reports whether v is encoded as a single value.
+func (c *Codec) encodesLeaf(v reflect.Value) bool {
+	if _, ok := c.encoders[v.Type()]; ok {
+		return true
+	}
+	if _, ok := asMarshaler(v); ok {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// leafString returns the encoding of the single value v.
+func (c *Codec) leafString(v reflect.Value) (string, error) {
+	if fn, ok := c.encoders[v.Type()]; ok {
+		return fn(v.Interface())
+	}
+	if m, ok := asMarshaler(v); ok {
+		return m.MarshalForm()
+	}
+	return getScalar(v)
+}
+
+// indirect follows pointers and interfaces to the value they hold, returning
+// the zero Value if any is nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isAbsent reports whether v contributes nothing to an encoding, so that a
+// missing value and an empty one compare equal.
+func isAbsent(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// unionKeys returns the keys of the maps a and b in sorted order.
+func unionKeys(a, b reflect.Value) []reflect.Value {
+	seen := make(map[string]reflect.Value)
+	for _, m := range []reflect.Value{a, b} {
+		for _, k := range m.MapKeys() {
+			seen[k.String()] = k
+		}
+	}
+	keys := make([]reflect.Value, 0, len(seen))
+	for _, k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// describe formats v for a [RoundTripError].
+func describe(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nothing"
+	}
+	return fmt.Sprintf("%#v (%s)", v.Interface(), v.Type())
+}
+
+// describeValues formats the values of a key for a [RoundTripError].
+func describeValues(values []string) string {
+	switch len(values) {
+	case 0:
+		return "nothing"
+	case 1:
+		return fmt.Sprintf("%q", values[0])
+	}
+	quoted := make([]string, len(values))
+	for i, s := range values {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return "[" + strings.Join(quoted, " ") + "]"
+}
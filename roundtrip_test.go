@@ -0,0 +1,109 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWithRoundTripCheck_Marshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   interface{}
+		wantErr *formenc.RoundTripError
+	}{
+		"lossless struct": {
+			input: ComplexPerson{ID: 1, Name: "john", Pronouns: []string{"he", "him"}},
+		},
+		"lossless strings in interfaces": {
+			input: map[string]interface{}{"user": map[string]interface{}{"tags": []interface{}{"a", "b"}}},
+		},
+		"number in interface": {
+			input:   map[string]interface{}{"user": map[string]interface{}{"age": 30}},
+			wantErr: &formenc.RoundTripError{Key: "user[age]", Want: "30 (int)", Got: `"30" (string)`},
+		},
+		"ignored field": {
+			input: IgnoredFieldsForm{Public: "a", Private: "secret"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithRoundTripCheck())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = codec.Marshal(tt.input)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				var rtErr *formenc.RoundTripError
+				if !errors.As(err, &rtErr) {
+					t.Fatalf("expected RoundTripError, got: %v", err)
+				}
+				if diff := cmp.Diff(tt.wantErr, rtErr); diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRoundTripCheck_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		wantErr *formenc.RoundTripError
+	}{
+		"lossless": {
+			input: "name=john&age=30&address[city]=Paris&address[state]=&address[street]=&address[zip]=",
+		},
+		"leading zeros": {
+			input:   "name=john&age=030&address[city]=&address[state]=&address[street]=&address[zip]=",
+			wantErr: &formenc.RoundTripError{Key: "age", Want: `"030"`, Got: `"30"`},
+		},
+		"omitted zero": {
+			input:   "name=john&age=0&address[city]=&address[state]=&address[street]=&address[zip]=",
+			wantErr: &formenc.RoundTripError{Key: "age", Want: `"0"`, Got: "nothing"},
+		},
+		"repeated scalar": {
+			input:   "name=john&name=jane&address[city]=&address[state]=&address[street]=&address[zip]=",
+			wantErr: &formenc.RoundTripError{Key: "name", Want: `["jane" "john"]`, Got: `"jane"`},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithRoundTripCheck())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got User
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				var rtErr *formenc.RoundTripError
+				if !errors.As(err, &rtErr) {
+					t.Fatalf("expected RoundTripError, got: %v", err)
+				}
+				if diff := cmp.Diff(tt.wantErr, rtErr); diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
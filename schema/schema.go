@@ -0,0 +1,290 @@
+// Package schema provides an API-compatible replacement for the decoder and
+// encoder of github.com/gorilla/schema, implemented on top of formenc.
+//
+// Projects can switch away from gorilla/schema by changing the import path.
+// Keys use the same dotted syntax, such as "address.city" and "items.0.name",
+// and fields are named by the "schema" struct tag unless another is set with
+// SetAliasTag.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// defaultMaxSize is the default maximum length of a slice decoded by a
+// [Decoder], as in gorilla/schema.
+const defaultMaxSize = 16000
+
+// Converter converts a string into a value. It returns an invalid
+// [reflect.Value] if the string cannot be converted.
+type Converter func(string) reflect.Value
+
+// Decoder decodes values from a map[string][]string into a struct.
+type Decoder struct {
+	aliasTag          string
+	ignoreUnknownKeys bool
+	zeroEmpty         bool
+	maxSize           int
+	converters        map[reflect.Type]Converter
+
+	// mu guards cache, the codec built from the settings above, which is
+	// discarded whenever they change.
+	mu    sync.Mutex
+	cache *formenc.Codec
+}
+
+// NewDecoder returns a new [Decoder].
+func NewDecoder() *Decoder {
+	return &Decoder{
+		aliasTag:   "schema",
+		maxSize:    defaultMaxSize,
+		converters: make(map[reflect.Type]Converter),
+	}
+}
+
+// SetAliasTag changes the tag used to locate custom field aliases. The default
+// tag is "schema".
+func (d *Decoder) SetAliasTag(tag string) {
+	d.aliasTag = tag
+	d.reset()
+}
+
+// ZeroEmpty controls the behaviour when the decoder encounters empty values in
+// a map. If z is true and a key in the map has the empty string as a value
+// then the corresponding struct field is set to the zero value. If z is false
+// then empty strings are ignored.
+func (d *Decoder) ZeroEmpty(z bool) {
+	d.zeroEmpty = z
+}
+
+// IgnoreUnknownKeys controls the behaviour when the decoder encounters unknown
+// keys in the map. If i is true and an unknown field is encountered, it is
+// ignored. Otherwise an [UnknownKeyError] is reported.
+func (d *Decoder) IgnoreUnknownKeys(i bool) {
+	d.ignoreUnknownKeys = i
+	d.reset()
+}
+
+// MaxSize limits the size of slices decoded from keys addressing their
+// elements by index, such as "items.50000.name", so that a single key cannot
+// allocate a huge slice. The default size is 16000.
+func (d *Decoder) MaxSize(size int) {
+	d.maxSize = size
+	d.reset()
+}
+
+// RegisterConverter registers a converter function for the type of value.
+func (d *Decoder) RegisterConverter(value interface{}, converterFunc Converter) {
+	d.converters[reflect.TypeOf(value)] = converterFunc
+	d.reset()
+}
+
+// Decode decodes a map[string][]string to a struct.
+//
+// The first parameter must be a pointer to a struct. The second parameter is
+// a map, typically url.Values from an HTTP request. Keys are paths in dotted
+// notation to the struct fields and nested structs. Errors are reported per
+// key in a [MultiError].
+func (d *Decoder) Decode(dst interface{}, src map[string][]string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("schema: interface must be a pointer to struct")
+	}
+
+	codec, err := d.codec()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Decode each key separately so that errors can be attributed to the key
+	// that caused them.
+	errs := MultiError{}
+	for _, key := range keys {
+		values := src[key]
+		if !d.zeroEmpty {
+			values = nonEmpty(values)
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		data := url.Values{key: values}.Encode()
+		if err := codec.Unmarshal([]byte(data), dst); err != nil {
+			var unknown *formenc.UnknownFieldError
+			if errors.As(err, &unknown) {
+				errs[key] = UnknownKeyError{Key: key}
+			} else {
+				errs[key] = d.conversionError(key, src[key], err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// codec returns the codec decoding with the settings of d, building it on
+// first use.
+func (d *Decoder) codec() (*formenc.Codec, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache != nil {
+		return d.cache, nil
+	}
+
+	opts := []formenc.Option{
+		formenc.WithDottedKeys(),
+		formenc.WithTagName(d.aliasTag),
+		formenc.WithLimits(formenc.Limits{MaxSliceLength: d.maxSize}),
+	}
+	if d.ignoreUnknownKeys {
+		opts = append(opts, formenc.WithIgnoreUnknownKeys())
+	}
+	for t, conv := range d.converters {
+		conv := conv
+		opts = append(opts, formenc.WithDecodeFunc(reflect.Zero(t).Interface(), func(s string) (interface{}, error) {
+			v := conv(s)
+			if !v.IsValid() {
+				return nil, fmt.Errorf("schema: invalid value %q", s)
+			}
+			return v.Interface(), nil
+		}))
+	}
+	codec, err := formenc.NewCodec(opts...)
+	if err != nil {
+		return nil, err
+	}
+	d.cache = codec
+	return codec, nil
+}
+
+// reset discards the cached codec, so that the next Decode uses the current
+// settings.
+func (d *Decoder) reset() {
+	d.mu.Lock()
+	d.cache = nil
+	d.mu.Unlock()
+}
+
+// conversionError describes err, the failure to decode values into key.
+// Index is the position among values of the value that failed, when key
+// names a field accepting multiple values, such as a slice.
+func (d *Decoder) conversionError(key string, values []string, err error) ConversionError {
+	e := ConversionError{Key: key, Index: -1, Err: err}
+
+	var typeErr *formenc.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return e
+	}
+	e.Type = typeErr.Type
+
+	// Values appended to a slice are given their position in the final
+	// segment of the path. Empty values were not decoded unless zeroEmpty
+	// is set, so are skipped when counting.
+	n := len(typeErr.Path)
+	if n == 0 || !typeErr.Path[n-1].Index || typeErr.Path[n-1].Pos < 0 {
+		return e
+	}
+	pos := typeErr.Path[n-1].Pos
+	for i, value := range values {
+		if value == "" && !d.zeroEmpty {
+			continue
+		}
+		if pos == 0 {
+			e.Index = i
+			break
+		}
+		pos--
+	}
+	return e
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Encoder encodes values from a struct into url.Values.
+type Encoder struct {
+	aliasTag string
+	encoders map[reflect.Type]func(reflect.Value) string
+}
+
+// NewEncoder returns a new [Encoder] with defaults.
+func NewEncoder() *Encoder {
+	return &Encoder{
+		aliasTag: "schema",
+		encoders: make(map[reflect.Type]func(reflect.Value) string),
+	}
+}
+
+// SetAliasTag changes the tag used to locate custom field aliases. The default
+// tag is "schema".
+func (e *Encoder) SetAliasTag(tag string) {
+	e.aliasTag = tag
+}
+
+// RegisterEncoder registers a converter for encoding a custom type.
+func (e *Encoder) RegisterEncoder(value interface{}, encoder func(reflect.Value) string) {
+	e.encoders[reflect.TypeOf(value)] = encoder
+}
+
+// Encode encodes a struct into map[string][]string.
+//
+// Intended for use with url.Values.
+func (e *Encoder) Encode(src interface{}, dst map[string][]string) error {
+	v := reflect.Indirect(reflect.ValueOf(src))
+	if v.Kind() != reflect.Struct {
+		return errors.New("schema: interface must be a struct")
+	}
+
+	opts := []formenc.Option{
+		formenc.WithDottedKeys(),
+		formenc.WithTagName(e.aliasTag),
+	}
+	for t, enc := range e.encoders {
+		enc := enc
+		opts = append(opts, formenc.WithEncodeFunc(reflect.Zero(t).Interface(), func(v interface{}) (string, error) {
+			return enc(reflect.ValueOf(v)), nil
+		}))
+	}
+
+	codec, err := formenc.NewCodec(opts...)
+	if err != nil {
+		return err
+	}
+
+	data, err := codec.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	for key, vals := range values {
+		dst[key] = append(dst[key], vals...)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package schema_test
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc/schema"
+)
+
+type Phone struct {
+	Label  string `schema:"label"`
+	Number string `schema:"number"`
+}
+
+type Profile struct {
+	Name    string   `schema:"name"`
+	Age     int      `schema:"age"`
+	Tags    []string `schema:"tags"`
+	Phones  []Phone  `schema:"phones"`
+	Address struct {
+		City string `schema:"city"`
+	} `schema:"address"`
+	Nickname string `schema:"nickname,omitempty"`
+}
+
+type upper string
+
+func TestDecoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configure func(*schema.Decoder)
+		src       map[string][]string
+		want      Profile
+		wantErr   bool
+	}{
+		"dotted keys and indexed structs": {
+			src: map[string][]string{
+				"name":            {"Ada"},
+				"age":             {"36"},
+				"tags":            {"maths", "engines"},
+				"phones.0.label":  {"home"},
+				"phones.0.number": {"123"},
+				"phones.1.label":  {"work"},
+				"address.city":    {"London"},
+			},
+			want: Profile{
+				Name: "Ada",
+				Age:  36,
+				Tags: []string{"maths", "engines"},
+				Phones: []Phone{
+					{Label: "home", Number: "123"},
+					{Label: "work"},
+				},
+				Address: struct {
+					City string `schema:"city"`
+				}{City: "London"},
+			},
+		},
+		"empty values ignored": {
+			src:  map[string][]string{"name": {"Ada"}, "age": {""}},
+			want: Profile{Name: "Ada"},
+		},
+		"empty values zeroed": {
+			configure: func(d *schema.Decoder) { d.ZeroEmpty(true) },
+			src:       map[string][]string{"name": {""}},
+			want:      Profile{},
+		},
+		"unknown key": {
+			src:     map[string][]string{"unknown": {"x"}},
+			wantErr: true,
+		},
+		"unknown key ignored": {
+			configure: func(d *schema.Decoder) { d.IgnoreUnknownKeys(true) },
+			src:       map[string][]string{"unknown": {"x"}, "name": {"Ada"}},
+			want:      Profile{Name: "Ada"},
+		},
+		"conversion failure": {
+			src:     map[string][]string{"age": {"old"}},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			decoder := schema.NewDecoder()
+			if tt.configure != nil {
+				tt.configure(decoder)
+			}
+
+			var got Profile
+			err := decoder.Decode(&got, tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	t.Parallel()
+
+	decoder := schema.NewDecoder()
+	err := decoder.Decode(&Profile{}, map[string][]string{
+		"unknown": {"x"},
+		"age":     {"old"},
+	})
+
+	var multi schema.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, got %T", err)
+	}
+
+	var unknown schema.UnknownKeyError
+	if !errors.As(multi["unknown"], &unknown) {
+		t.Errorf("expected UnknownKeyError for unknown, got %T", multi["unknown"])
+	}
+
+	var conversion schema.ConversionError
+	if !errors.As(multi["age"], &conversion) {
+		t.Errorf("expected ConversionError for age, got %T", multi["age"])
+	}
+}
+
+func TestDecoder_ConversionError(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Age    int   `schema:"age"`
+		Scores []int `schema:"scores"`
+	}
+
+	tests := map[string]struct {
+		src  map[string][]string
+		key  string
+		want schema.ConversionError
+	}{
+		"single value": {
+			src:  map[string][]string{"age": {"old"}},
+			key:  "age",
+			want: schema.ConversionError{Key: "age", Type: reflect.TypeOf(0), Index: -1},
+		},
+		"multiple values": {
+			src:  map[string][]string{"scores": {"1", "2", "x"}},
+			key:  "scores",
+			want: schema.ConversionError{Key: "scores", Type: reflect.TypeOf(0), Index: 2},
+		},
+		"duplicate failing values": {
+			src:  map[string][]string{"scores": {"1", "x", "2", "x"}},
+			key:  "scores",
+			want: schema.ConversionError{Key: "scores", Type: reflect.TypeOf(0), Index: 1},
+		},
+		"failing value after an empty one": {
+			src:  map[string][]string{"scores": {"1", "", "x"}},
+			key:  "scores",
+			want: schema.ConversionError{Key: "scores", Type: reflect.TypeOf(0), Index: 2},
+		},
+		"multiple values with empty": {
+			src:  map[string][]string{"scores": {"", "x"}},
+			key:  "scores",
+			want: schema.ConversionError{Key: "scores", Type: reflect.TypeOf(0), Index: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := schema.NewDecoder().Decode(&Form{}, tt.src)
+
+			var multi schema.MultiError
+			if !errors.As(err, &multi) {
+				t.Fatalf("expected MultiError, got %T", err)
+			}
+			var got schema.ConversionError
+			if !errors.As(multi[tt.key], &got) {
+				t.Fatalf("expected ConversionError for %s, got %T", tt.key, multi[tt.key])
+			}
+			if got.Err == nil {
+				t.Errorf("expected an underlying error")
+			}
+			got.Err = nil
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(a, b reflect.Type) bool { return a == b })); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_SettingsAfterDecode(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Name string `json:"name"`
+	}
+
+	decoder := schema.NewDecoder()
+	if err := decoder.Decode(&Form{}, map[string][]string{"name": {"a"}}); err == nil {
+		t.Fatal("expected an error for a key without a schema tag")
+	}
+
+	decoder.SetAliasTag("json")
+	var got Form
+	if err := decoder.Decode(&got, map[string][]string{"name": {"a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Form{Name: "a"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_RegisterConverter(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Code upper `schema:"code"`
+	}
+
+	decoder := schema.NewDecoder()
+	decoder.RegisterConverter(upper(""), func(s string) reflect.Value {
+		return reflect.ValueOf(upper(strings.ToUpper(s)))
+	})
+
+	var got Form
+	if err := decoder.Decode(&got, map[string][]string{"code": {"abc"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Code != "ABC" {
+		t.Errorf("expected ABC, got %q", got.Code)
+	}
+}
+
+func TestDecoder_SetAliasTag(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Name string `json:"full_name"`
+	}
+
+	decoder := schema.NewDecoder()
+	decoder.SetAliasTag("json")
+
+	var got Form
+	if err := decoder.Decode(&got, map[string][]string{"full_name": {"Ada"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "Ada" {
+		t.Errorf("expected Ada, got %q", got.Name)
+	}
+}
+
+func TestDecoder_MaxSize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		maxSize int
+		key     string
+		wantErr bool
+	}{
+		"within default size": {
+			key: "phones.1.number",
+		},
+		"beyond default size": {
+			key:     "phones.50000000.number",
+			wantErr: true,
+		},
+		"within custom size": {
+			maxSize: 2,
+			key:     "phones.1.number",
+		},
+		"beyond custom size": {
+			maxSize: 2,
+			key:     "phones.2.number",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			decoder := schema.NewDecoder()
+			if tt.maxSize > 0 {
+				decoder.MaxSize(tt.maxSize)
+			}
+
+			err := decoder.Decode(&Profile{}, map[string][]string{tt.key: {"123"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDecoder_InvalidTarget(t *testing.T) {
+	t.Parallel()
+
+	decoder := schema.NewDecoder()
+	if err := decoder.Decode(Profile{}, nil); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	profile := Profile{
+		Name:   "Ada",
+		Age:    36,
+		Tags:   []string{"maths", "engines"},
+		Phones: []Phone{{Label: "home", Number: "123"}},
+	}
+	profile.Address.City = "London"
+
+	encoder := schema.NewEncoder()
+
+	got := url.Values{}
+	if err := encoder.Encode(profile, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := url.Values{
+		"name":            {"Ada"},
+		"age":             {"36"},
+		"tags":            {"maths", "engines"},
+		"phones.0.label":  {"home"},
+		"phones.0.number": {"123"},
+		"address.city":    {"London"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// The encoded values decode back into the same struct.
+	var decoded Profile
+	if err := schema.NewDecoder().Decode(&decoded, want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(profile, decoded); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_RegisterEncoder(t *testing.T) {
+	t.Parallel()
+
+	type Form struct {
+		Code upper `schema:"code"`
+	}
+
+	encoder := schema.NewEncoder()
+	encoder.RegisterEncoder(upper(""), func(v reflect.Value) string {
+		return strings.ToLower(v.String())
+	})
+
+	got := url.Values{}
+	if err := encoder.Encode(Form{Code: "ABC"}, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(url.Values{"code": {"abc"}}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
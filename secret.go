@@ -0,0 +1,98 @@
+package formenc
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the values of fields tagged secret wherever they would be
+// reported.
+const redacted = "[REDACTED]"
+
+// secretError marks an error decoding a field tagged secret, so that the
+// values it holds are redacted once the error has been annotated with the key
+// and value that caused it.
+type secretError struct {
+	err   error
+	value string
+}
+
+func (e *secretError) Error() string {
+	return e.err.Error()
+}
+
+func (e *secretError) Unwrap() error {
+	return e.err
+}
+
+// redactError replaces the values held by err with [redacted] if it decodes a
+// field tagged secret.
+func redactError(err error) error {
+	var secret *secretError
+	if !errors.As(err, &secret) {
+		return err
+	}
+
+	values := []string{secret.value}
+	var typeErr *UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		values = append(values, typeErr.Value)
+		typeErr.Value = redacted
+		if typeErr.Err != nil {
+			typeErr.Err = &redactedError{err: typeErr.Err, values: values}
+		}
+	}
+	var unknownErr *UnknownFieldError
+	if errors.As(err, &unknownErr) {
+		unknownErr.Value = redacted
+	}
+	var unexportedErr *UnexportedFieldError
+	if errors.As(err, &unexportedErr) {
+		unexportedErr.Value = redacted
+	}
+	return err
+}
+
+// redactedError is the cause of an error decoding a field tagged secret, whose
+// message has the values of the field replaced with [redacted].
+type redactedError struct {
+	err    error
+	values []string
+}
+
+func (e *redactedError) Error() string {
+	s := e.err.Error()
+	for _, v := range e.values {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, redacted)
+		}
+	}
+	return s
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactIssues replaces the values of the pairs of the issues recorded in the
+// metadata of c whose key is key, the key of a field tagged secret.
+func (c *Codec) redactIssues(key string) {
+	if c.metadata == nil {
+		return
+	}
+	for i, issue := range c.metadata.Issues {
+		rawKey, _, hasValue := strings.Cut(issue.Pair, "=")
+		if !hasValue {
+			continue
+		}
+		// Keys with invalid escapes are captured without unescaping.
+		issueKey, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			issueKey = rawKey
+		}
+		if issueKey == key {
+			c.metadata.Issues[i].Pair = rawKey + "=" + redacted
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package formenc_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type Login struct {
+	Username string `form:"username"`
+	PIN      int    `form:"pin,secret"`
+	Token    MyDate `form:"token,secret"`
+}
+
+func TestSecret_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		wantMsg string
+	}{
+		"conversion error": {
+			input:   "username=alice&pin=hunter2",
+			wantMsg: `form: cannot unmarshal "[REDACTED]" into key "pin" of type int: invalid syntax`,
+		},
+		"unmarshaler error": {
+			input:   "token=swordfish",
+			wantMsg: `form: cannot unmarshal "[REDACTED]" into key "token" of type formenc_test.MyDate: parsing time "[REDACTED]" as "2006.01.02": cannot parse "[REDACTED]" as "2006"`,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var hooked error
+			codec, err := formenc.NewCodec(formenc.WithHooks(formenc.Hooks{
+				OnFieldError: func(_ context.Context, _ string, err error) {
+					hooked = err
+				},
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = codec.Unmarshal([]byte(tt.input), &Login{})
+			if diff := cmp.Diff(tt.wantMsg, err.Error()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if hooked == nil || strings.Contains(hooked.Error(), "hunter2") || strings.Contains(hooked.Error(), "swordfish") {
+				t.Errorf("expected redacted hook error, got: %v", hooked)
+			}
+
+			var typeErr *formenc.UnmarshalTypeError
+			if !errors.As(err, &typeErr) || typeErr.Value != "[REDACTED]" {
+				t.Errorf("expected redacted UnmarshalTypeError, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSecret_Metadata(t *testing.T) {
+	t.Parallel()
+
+	type Credentials struct {
+		User     string `form:"user"`
+		Password string `form:"password,secret"`
+	}
+
+	tests := map[string]struct {
+		input string
+		want  []formenc.ParseIssue
+	}{
+		"invalid escape": {
+			input: "user=alice&password=100%off",
+			want:  []formenc.ParseIssue{{Pair: "password=[REDACTED]", Msg: "invalid escape sequence, captured without unescaping"}},
+		},
+		"secret value held by another field": {
+			input: "user=100%off&password=100%off",
+			want: []formenc.ParseIssue{
+				{Pair: "user=100%off", Msg: "invalid escape sequence, captured without unescaping"},
+				{Pair: "password=[REDACTED]", Msg: "invalid escape sequence, captured without unescaping"},
+			},
+		},
+		"escaped value": {
+			input: "user=alice&password=a b%20c",
+			want:  []formenc.ParseIssue{{Pair: "password=[REDACTED]", Msg: "unescaped space"}},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithLenient())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Credentials
+			md, err := codec.UnmarshalWithMetadata([]byte(tt.input), &got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, md.Issues); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSecret_Plan(t *testing.T) {
+	t.Parallel()
+
+	steps, err := formenc.Plan([]byte("username=alice&pin=1234"), reflect.TypeOf(Login{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []string{steps[0].Value, steps[1].Value}
+	if diff := cmp.Diff([]string{"alice", "[REDACTED]"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestSecret_RenderHTML(t *testing.T) {
+	t.Parallel()
+
+	type Credentials struct {
+		Password string `form:"password,secret"`
+	}
+
+	got, err := formenc.RenderHTML(Credentials{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<label>password <input type=\"password\" name=\"password\"></label>\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
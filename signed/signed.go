@@ -0,0 +1,195 @@
+// Package signed wraps formenc encoding with HMAC signing, and optionally
+// encryption, so that form data handed to a client, such as a redirect
+// parameter or a cookie, can be trusted when it is returned.
+//
+// An encoded value is the form encoding of a Go value, encrypted with AES-GCM
+// if configured, followed by the time it was encoded and an HMAC-SHA256 of the
+// whole, written in unpadded URL-safe base64:
+//
+//	s, err := signed.New(hashKey, signed.WithMaxAge(time.Hour))
+//	...
+//	token, err := s.Encode("state", State{ReturnTo: "/orders/42"})
+//	...
+//	var state State
+//	err = s.Decode("state", r.FormValue("state"), &state)
+//
+// The name passed to [Signer.Encode] and [Signer.Decode] is signed too, so a
+// value encoded for one purpose cannot be replayed for another.
+package signed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/tomasbasham/formenc"
+)
+
+// minKeySize is the smallest hash key accepted, matching the output of
+// SHA-256.
+const minKeySize = 32
+
+// timestampSize is the number of bytes holding the time a value was encoded.
+const timestampSize = 8
+
+var (
+	// ErrInvalidSignature is returned by [Signer.Decode] for a value that was
+	// not encoded by a [Signer] with the same keys and name, or that has been
+	// modified since.
+	ErrInvalidSignature = errors.New("signed: invalid signature")
+
+	// ErrExpired is returned by [Signer.Decode] for a value encoded longer ago
+	// than the maximum age set by [WithMaxAge].
+	ErrExpired = errors.New("signed: value expired")
+)
+
+// Signer encodes values as signed form data and decodes them again. A Signer
+// is safe for concurrent use.
+type Signer struct {
+	hashKey []byte
+	aead    cipher.AEAD
+	codec   *formenc.Codec
+	maxAge  time.Duration
+}
+
+// Option configures a [Signer], returning an error if the option cannot be
+// applied.
+type Option func(*Signer) error
+
+// New returns a [Signer] authenticating values with hashKey, which must be at
+// least 32 bytes of random data, and configured by opts.
+func New(hashKey []byte, opts ...Option) (*Signer, error) {
+	if len(hashKey) < minKeySize {
+		return nil, fmt.Errorf("signed: hash key must be at least %d bytes", minKeySize)
+	}
+
+	codec, err := formenc.NewCodec()
+	if err != nil {
+		return nil, err
+	}
+	s := &Signer{hashKey: hashKey, codec: codec}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// WithCodec configures a [Signer] to encode and decode values with codec
+// rather than the formenc defaults.
+func WithCodec(codec *formenc.Codec) Option {
+	return func(s *Signer) error {
+		if codec == nil {
+			return fmt.Errorf("signed: nil codec")
+		}
+		s.codec = codec
+		return nil
+	}
+}
+
+// WithEncryption configures a [Signer] to encrypt values with AES-GCM before
+// signing them, so that clients cannot read them. The key must be 16, 24 or
+// 32 bytes, selecting AES-128, AES-192 or AES-256, and should differ from the
+// hash key.
+func WithEncryption(key []byte) Option {
+	return func(s *Signer) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("signed: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("signed: %w", err)
+		}
+		s.aead = aead
+		return nil
+	}
+}
+
+// WithMaxAge configures a [Signer] to reject values encoded longer ago than
+// d with [ErrExpired]. By default values do not expire.
+func WithMaxAge(d time.Duration) Option {
+	return func(s *Signer) error {
+		if d <= 0 {
+			return fmt.Errorf("signed: max age must be positive")
+		}
+		s.maxAge = d
+		return nil
+	}
+}
+
+// Encode returns the signed form encoding of v, for the purpose identified by
+// name.
+func (s *Signer) Encode(name string, v interface{}) (string, error) {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("signed: %w", err)
+		}
+		data = s.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+
+	b := make([]byte, 0, len(data)+timestampSize+sha256.Size)
+	b = append(b, data...)
+	b = binary.BigEndian.AppendUint64(b, uint64(time.Now().UnixNano()))
+	b = s.mac(name, b).Sum(b)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode verifies value, as returned by [Signer.Encode] for name, and decodes
+// its form data into the value pointed to by v. [ErrInvalidSignature] is
+// returned if value cannot be verified, and [ErrExpired] if it is older than
+// the maximum age.
+func (s *Signer) Decode(name, value string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < timestampSize+sha256.Size {
+		return ErrInvalidSignature
+	}
+
+	signed, sum := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if !hmac.Equal(s.mac(name, signed).Sum(nil), sum) {
+		return ErrInvalidSignature
+	}
+
+	data, ts := signed[:len(signed)-timestampSize], signed[len(signed)-timestampSize:]
+	if s.maxAge > 0 {
+		encoded := time.Unix(0, int64(binary.BigEndian.Uint64(ts)))
+		if time.Since(encoded) > s.maxAge {
+			return ErrExpired
+		}
+	}
+
+	if s.aead != nil {
+		n := s.aead.NonceSize()
+		if len(data) < n {
+			return ErrInvalidSignature
+		}
+		if data, err = s.aead.Open(nil, data[:n], data[n:], []byte(name)); err != nil {
+			return ErrInvalidSignature
+		}
+	}
+	return s.codec.Unmarshal(data, v)
+}
+
+// mac returns an HMAC of name followed by b.
+func (s *Signer) mac(name string, b []byte) hash.Hash {
+	h := hmac.New(sha256.New, s.hashKey)
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(name))))
+	h.Write([]byte(name))
+	h.Write(b)
+	return h
+}
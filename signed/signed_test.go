@@ -0,0 +1,152 @@
+package signed_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc/signed"
+)
+
+type State struct {
+	ReturnTo string `form:"return_to"`
+	Nonce    int    `form:"nonce"`
+}
+
+var (
+	hashKey = bytes.Repeat([]byte("h"), 32)
+	encKey  = bytes.Repeat([]byte("e"), 32)
+)
+
+func TestSigner(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts     []signed.Option
+		readable bool
+	}{
+		"signed": {
+			readable: true,
+		},
+		"encrypted": {
+			opts: []signed.Option{signed.WithEncryption(encKey)},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := signed.New(hashKey, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := State{ReturnTo: "/orders/42", Nonce: 7}
+			token, err := s.Encode("state", want)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			raw, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := bytes.Contains(raw, []byte("return_to=")); got != tt.readable {
+				t.Errorf("expected readable %t, got %t", tt.readable, got)
+			}
+
+			var got State
+			if err := s.Decode("state", token, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSigner_Invalid(t *testing.T) {
+	t.Parallel()
+
+	s, err := signed.New(hashKey, signed.WithEncryption(encKey))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, err := s.Encode("state", State{ReturnTo: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	other, err := signed.New(bytes.Repeat([]byte("x"), 32), signed.WithEncryption(encKey))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tampered := []byte(token)
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+
+	tests := map[string]struct {
+		signer *signed.Signer
+		name   string
+		value  string
+	}{
+		"tampered":       {signer: s, name: "state", value: string(tampered)},
+		"other name":     {signer: s, name: "redirect", value: token},
+		"other key":      {signer: other, name: "state", value: token},
+		"truncated":      {signer: s, name: "state", value: token[:10]},
+		"invalid base64": {signer: s, name: "state", value: "not base64!"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got State
+			err := tt.signer.Decode(tt.name, tt.value, &got)
+			if !errors.Is(err, signed.ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithMaxAge(t *testing.T) {
+	t.Parallel()
+
+	s, err := signed.New(hashKey, signed.WithMaxAge(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, err := s.Encode("state", State{ReturnTo: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	var got State
+	if err := s.Decode("state", token, &got); !errors.Is(err, signed.ErrExpired) {
+		t.Errorf("expected ErrExpired, got: %v", err)
+	}
+}
+
+func TestNew_ShortKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := signed.New([]byte("short"))
+	if err == nil || !strings.Contains(err.Error(), "at least 32 bytes") {
+		t.Errorf("expected short key error, got: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package formenc
+
+import "strconv"
+
+// presizeEntries is the fewest entries for which decoding counts the values
+// addressed to each slice and map, so that they are allocated at their final
+// size. Smaller inputs are decoded without counting.
+const presizeEntries = 32
+
+// sizeHints counts the elements addressed to each slice and map while
+// decoding, keyed by the path at which they are found. Each distinct path is
+// a node, with the root, node zero, being the decode target itself.
+type sizeHints struct {
+	ids   map[sizeKey]int
+	nodes []sizeNode
+}
+
+// sizeKey identifies the child of the node parent addressed by seg.
+type sizeKey struct {
+	parent int
+	seg    pathSegment
+}
+
+// sizeNode holds the number of elements addressed at a path, and one more
+// than the highest position addressed, if any.
+type sizeNode struct {
+	n   int
+	end int
+}
+
+// countSizes returns the number of elements addressed to each path of
+// entries.
+func countSizes(entries []entry) *sizeHints {
+	h := &sizeHints{ids: make(map[sizeKey]int), nodes: []sizeNode{{}}}
+	for _, e := range entries {
+		h.count(e.path)
+	}
+	return h
+}
+
+// count records the elements addressed by path. Each segment adds an element
+// to its parent the first time it appears, and each segment appending to an
+// array adds another.
+//
+// The last segment of a path, and a segment addressing an array position, is
+// not tracked itself, as that would cost as much as the growth it saves.
+// Instead it adds an element each time it appears, so that a repeated key
+// overstates the size of its parent, and positions bound the size by the
+// highest of them. Elements of appended or positional values are not counted.
+func (h *sizeHints) count(path []pathSegment) {
+	id := 0
+	for i, seg := range path {
+		if seg.Index && seg.Pos < 0 {
+			h.nodes[id].n++
+			return
+		}
+		if pos, ok := position(seg); ok {
+			h.nodes[id].n++
+			h.nodes[id].end = max(h.nodes[id].end, pos+1)
+			return
+		}
+		if i == len(path)-1 {
+			h.nodes[id].n++
+			return
+		}
+
+		k := sizeKey{parent: id, seg: seg}
+		child, ok := h.ids[k]
+		if !ok {
+			child = len(h.nodes)
+			h.nodes = append(h.nodes, sizeNode{})
+			h.ids[k] = child
+			h.nodes[id].n++
+		}
+		id = child
+	}
+}
+
+// position returns the array position addressed by seg, if it has one.
+func position(seg pathSegment) (int, bool) {
+	if seg.Index {
+		return seg.Pos, true
+	}
+	if seg.Key == "" || seg.Key[0] < '0' || seg.Key[0] > '9' {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(seg.Key)
+	return pos, err == nil
+}
+
+// size returns the number of elements counted for path, or zero if it is
+// unknown.
+func (h *sizeHints) size(path []pathSegment) int {
+	id := 0
+	for _, seg := range path {
+		child, ok := h.ids[sizeKey{parent: id, seg: seg}]
+		if !ok {
+			return 0
+		}
+		id = child
+	}
+	node := h.nodes[id]
+	if node.end > 0 {
+		return min(node.n, node.end)
+	}
+	return node.n
+}
+
+// sizeHint returns the number of elements expected for the slice or map
+// addressed by the first depth segments of the entry being decoded, or zero
+// if it is unknown.
+func (c *Codec) sizeHint(depth int) int {
+	if c.sizes == nil || depth < 0 || depth > len(c.path) {
+		return 0
+	}
+	n := c.sizes.size(c.path[:depth])
+	if max := c.limits.MaxSliceLength; max > 0 && n > max {
+		n = max
+	}
+	return n
+}
@@ -1,50 +1,438 @@
 package formenc
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Decoder reads form-urlencoded data from an [io.Reader] and decodes it into a
 // Go value.
+//
+// The input may hold several records, one per line, so that a file of
+// form-encoded rows can be decoded one row at a time. Blank lines are skipped.
 type Decoder struct {
-	r io.Reader
+	r     *bufio.Reader
+	codec *Codec
+
+	// maxPair, if positive, is the size of the largest pair read by
+	// DecodeFunc.
+	maxPair int
+
+	// read is set once a record has been read.
+	read bool
 }
 
+// ErrTooLarge is returned by a [Decoder] created with [NewDecoderLimit] when
+// its input is larger than the limit.
+var ErrTooLarge = errors.New("form: input too large")
+
 // NewDecoder creates a new [Decoder] that reads from r.
 func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r: r}
+	return defaultCodec.NewDecoder(r)
+}
+
+// NewDecoderLimit creates a new [Decoder] that reads at most n bytes from r.
+// See [Codec.NewDecoderLimit].
+func NewDecoderLimit(r io.Reader, n int64) *Decoder {
+	return defaultCodec.NewDecoderLimit(r, n)
+}
+
+// UseNumber causes the Decoder to unmarshal a numeric value into an interface
+// value as an [encoding/json.Number] instead of as a string.
+func (d *Decoder) UseNumber() {
+	c := *d.codec
+	c.useNumber = true
+	d.codec = &c
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the
+// destination is a struct and the input contains keys which do not match any
+// non-ignored, exported fields in the destination. This overrides
+// [WithIgnoreUnknownKeys] for the Decoder.
+func (d *Decoder) DisallowUnknownFields() {
+	c := *d.codec
+	c.ignoreUnknownKeys = false
+	d.codec = &c
 }
 
-// Decode reads the form-urlencoded data from the underlying [io.Reader] and
-// decodes it into v.
+// SetKeyNormalizer causes the Decoder to pass each key through fn before it is
+// parsed, as [WithKeyNormalizer] does. A nil fn removes any normalizer.
+func (d *Decoder) SetKeyNormalizer(fn func(string) string) {
+	c := *d.codec
+	c.keyNormalizer = fn
+	d.codec = &c
+}
+
+// SetMaxPairSize bounds the memory used by [Decoder.DecodeFunc] to the size of
+// a single pair, rather than the record, so that input of any length can be
+// decoded in constant memory. A pair, its key, value and '=' as they appear in
+// the input, longer than n bytes is rejected with a [LimitExceededError] for
+// the "pair size" limit, and the rest of its record is skipped so that decoding
+// can continue with the next record. A size of zero or less removes the bound.
+func (d *Decoder) SetMaxPairSize(n int) {
+	d.maxPair = n
+}
+
+// Decode reads the next record of form-urlencoded data from the underlying
+// [io.Reader] and decodes it into v. A record ends at a newline or at the end
+// of the input. Decode returns [io.EOF] once there are no more records, but an
+// error reporting empty input, as [Unmarshal] does, if the input holds none.
+//
+// If the codec limits the size of its input, a record larger than the limit is
+// rejected without being held in memory, and the rest of its line is skipped so
+// that decoding can continue with the next record.
 func (d *Decoder) Decode(v interface{}) error {
-	body, err := io.ReadAll(d.r)
+	return d.decode(d.codec, v)
+}
+
+// decode reads the next record and decodes it into v using c.
+func (d *Decoder) decode(c *Codec, v interface{}) error {
+	record, err := d.readRecord()
+	if err == io.EOF && !d.read {
+		return fmt.Errorf("form: empty input")
+	}
 	if err != nil {
-		return fmt.Errorf("form: failed to read body: %w", err)
+		return err
 	}
+	return c.unmarshal(record, v)
+}
 
-	return Unmarshal(body, v)
+// Buffered returns a reader of the data remaining in the Decoder's buffer,
+// which has been read from the underlying [io.Reader] but not yet decoded. To
+// read the rest of the input, such as a signature following the form data,
+// combine it with the underlying reader using [io.MultiReader]. The reader is
+// valid until the next call to Decode or More.
+func (d *Decoder) Buffered() io.Reader {
+	b, _ := d.r.Peek(d.r.Buffered())
+	return bytes.NewReader(b)
+}
+
+// DecodeFunc reads the next record of form-urlencoded data from the underlying
+// [io.Reader] and calls fn with the unescaped key and value of each pair, in
+// the order they appear, without decoding them into a Go value. Pairs are read
+// one at a time, so a record need not fit in memory. Keys are not parsed, so
+// "user[name]" is passed to fn as it is, but options rewriting keys and
+// values, such as [WithKeyNormalizer] and [WithNFCValues], are applied.
+//
+// If fn returns an error DecodeFunc stops, leaving the rest of the record
+// unread, and returns the error. A record larger than the codec permits is
+// rejected with a [LimitExceededError], and the rest of it is skipped so that
+// decoding can continue with the next record, as it is by [Decoder.Decode].
+// DecodeFunc returns [io.EOF] once there are no more records.
+func (d *Decoder) DecodeFunc(fn func(key, value string) error) error {
+	if !d.More() {
+		return io.EOF
+	}
+	d.read = true
+
+	l := d.codec.limits
+	var size, keys int
+	for {
+		raw, last, err := d.readPair()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return readError(err)
+		}
+		if d.maxPair > 0 && len(raw) > d.maxPair {
+			return d.pairTooLarge(raw, last)
+		}
+		if size += len(raw); l.MaxBytes > 0 && size > l.MaxBytes {
+			d.skipRecord(last)
+			return fmt.Errorf("form: %w", &LimitExceededError{Limit: "byte size", Max: l.MaxBytes})
+		}
+
+		pairs, perr := splitPairs(d.codec.rewrite(strings.TrimSpace(string(raw))))
+		if perr != nil {
+			return fmt.Errorf("form: %w", &SyntaxError{Msg: "invalid form data", Err: perr})
+		}
+		for _, p := range pairs {
+			if keys++; l.MaxKeys > 0 && keys > l.MaxKeys {
+				return fmt.Errorf("form: %w", &LimitExceededError{Limit: "key count", Max: l.MaxKeys})
+			}
+			if l.MaxValueLength > 0 && len(p.value) > l.MaxValueLength {
+				return fmt.Errorf("form: %w", &LimitExceededError{Limit: "value length", Max: l.MaxValueLength, Key: p.key})
+			}
+			if d.codec.nfcKeys {
+				p.key = norm.NFC.String(p.key)
+			}
+			if d.codec.nfcValues {
+				p.value = norm.NFC.String(p.value)
+			}
+			if err := fn(p.key, p.value); err != nil {
+				return err
+			}
+		}
+		if last || err != nil {
+			return nil
+		}
+	}
+}
+
+// pairTooLarge skips the rest of the record holding raw, the start of a pair
+// exceeding the size set by SetMaxPairSize, and returns the error reporting
+// it. The key of the pair is reported if it was read in full.
+func (d *Decoder) pairTooLarge(raw []byte, last bool) error {
+	err := &LimitExceededError{Limit: "pair size", Max: d.maxPair}
+	if rawKey, _, ok := bytes.Cut(raw, []byte("=")); ok {
+		if key, kerr := url.QueryUnescape(string(rawKey)); kerr == nil {
+			err.Key = key
+		}
+	}
+	d.skipRecord(last)
+	return fmt.Errorf("form: %w", err)
+}
+
+// skipRecord discards the rest of the current record, unless last reports that
+// the pair last read ended it.
+func (d *Decoder) skipRecord(last bool) {
+	if last {
+		return
+	}
+	for {
+		if _, err := d.r.ReadSlice('\n'); err != bufio.ErrBufferFull {
+			return
+		}
+	}
+}
+
+// readPair returns the text of the next pair of the current record, and
+// whether it is the last pair of the record. A pair longer than the codec
+// permits a record to be, or than the size set by SetMaxPairSize, is returned
+// as soon as it exceeds the limit.
+func (d *Decoder) readPair() ([]byte, bool, error) {
+	max := d.codec.limits.MaxBytes
+	if d.maxPair > 0 && (max <= 0 || d.maxPair < max) {
+		max = d.maxPair
+	}
+	var pair []byte
+	for max <= 0 || len(pair) <= max {
+		if d.r.Buffered() == 0 {
+			if _, err := d.r.Peek(1); err != nil {
+				return pair, true, err
+			}
+		}
+		buf, _ := d.r.Peek(d.r.Buffered())
+		if i := bytes.IndexAny(buf, "&\n"); i >= 0 {
+			pair = append(pair, buf[:i]...)
+			d.r.Discard(i + 1)
+			return pair, buf[i] == '\n', nil
+		}
+		pair = append(pair, buf...)
+		d.r.Discard(len(buf))
+	}
+	return pair, false, nil
+}
+
+// More reports whether there is another record to decode.
+func (d *Decoder) More() bool {
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return false
+		}
+		if !isSpace(b[0]) {
+			return true
+		}
+		d.r.ReadByte()
+	}
+}
+
+// readRecord returns the next line of the input that is not blank.
+func (d *Decoder) readRecord() ([]byte, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, readError(err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			d.read = true
+			return line, nil
+		}
+		if err != nil {
+			return nil, io.EOF
+		}
+	}
+}
+
+// readLine returns the next line of the input, including its newline. A line
+// longer than the codec permits is returned with one byte more than the limit,
+// the remainder being discarded.
+func (d *Decoder) readLine() ([]byte, error) {
+	max := d.codec.limits.MaxBytes
+	var line []byte
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		if max <= 0 || len(line) <= max {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if max > 0 && len(line) > max {
+			line = line[:max+1]
+		}
+		if err == io.EOF && len(line) > 0 {
+			// The last record need not end with a newline.
+			return line, nil
+		}
+		return line, err
+	}
+}
+
+// readError describes err, an error reading the input. A body larger than
+// permitted by [net/http.MaxBytesReader] is reported as a [LimitExceededError]
+// with the limit of the reader, so that oversized input is reported alike
+// however it is limited.
+func readError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("form: %w", &LimitExceededError{Limit: "byte size", Max: int(maxErr.Limit), Err: err})
+	}
+	return fmt.Errorf("form: failed to read body: %w", err)
+}
+
+// limitReader reads from r, returning [ErrTooLarge] once more than n bytes
+// have been read.
+type limitReader struct {
+	r    io.Reader
+	n    int64
+	over bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.over {
+		return 0, ErrTooLarge
+	}
+	// Read one byte more than remains, to learn whether the input exceeds the
+	// limit.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		return n, err
+	}
+	n, l.n, l.over = int(l.n), 0, true
+	return n, ErrTooLarge
+}
+
+// isSpace reports whether b is a space or line break separating records.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
 }
 
 // Encoder writes form-urlencoded data to an [io.Writer].
 type Encoder struct {
-	w io.Writer
+	w     io.Writer
+	codec *Codec
+
+	// rawHTML is set when the HTML characters < and > are written without
+	// escaping.
+	rawHTML bool
+
+	// separator is written between the output of successive calls to Encode,
+	// and written is set once output has been written.
+	separator string
+	written   bool
+
+	// terminator, if set, ends the output of every call to Encode.
+	terminator string
 }
 
 // NewEncoder creates a new [Encoder] that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w: w}
+	return defaultCodec.NewEncoder(w)
+}
+
+// SetSeparator sets the separator written between the output of successive
+// calls to Encode. The default is "&", so that values encoded one after
+// another form a single body, as though their fields belonged to one value.
+func (e *Encoder) SetSeparator(sep string) {
+	e.separator = sep
+}
+
+// SetTerminator sets a suffix, such as "\n", written after the output of every
+// call to Encode, so that each value is a record of its own, as a [Decoder]
+// reads them. When a terminator is set no separator is written, and a value
+// encoding to nothing is written as an empty record.
+func (e *Encoder) SetTerminator(suffix string) {
+	e.terminator = suffix
+}
+
+// Flush flushes any buffered output of the underlying [io.Writer] to its
+// destination, so that values encoded so far are sent to a client while more
+// are produced. Writers with a Flush method, such as [bufio.Writer] and
+// [net/http.ResponseWriter] values implementing [net/http.Flusher], are
+// flushed; for other writers Flush does nothing.
+func (e *Encoder) Flush() error {
+	switch f := e.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// htmlUnescaper reverses the escaping of the HTML characters < and >, which
+// need not be escaped in form data.
+var htmlUnescaper = strings.NewReplacer("%3C", "<", "%3E", ">")
+
+// SetEscapeHTML specifies whether the HTML characters < and > should be
+// percent-encoded. The default behavior is to escape them, as browsers do. The
+// & character is always escaped as it separates pairs in form data.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.rawHTML = !on
 }
 
 // Encode encodes v as form-urlencoded data and writes it to the underlying
-// [io.Writer].
+// [io.Writer]. If earlier calls wrote output, the separator set by
+// [Encoder.SetSeparator] is written first. A value encoding to nothing writes
+// nothing, unless a terminator is set by [Encoder.SetTerminator].
+//
+// The output of each call is written in a single Write, so the underlying
+// writer only ever receives complete pairs and a reader of a streamed response
+// never sees a pair cut in two.
 func (e *Encoder) Encode(v interface{}) error {
-	data, err := Marshal(v)
+	return e.encode(e.codec, v)
+}
+
+// encode encodes v using c and writes it to the underlying writer.
+func (e *Encoder) encode(c *Codec, v interface{}) error {
+	if e.rawHTML {
+		escape := c.escape
+		raw := *c
+		raw.escape = func(s string) string {
+			return htmlUnescaper.Replace(escape(s))
+		}
+		raw.escapedKeys = nil
+		c = &raw
+	}
+
+	data, err := c.marshal(v)
 	if err != nil {
 		return err
 	}
+	switch {
+	case e.terminator != "":
+		data = append(data, e.terminator...)
+	case len(data) == 0:
+		return nil
+	case e.written:
+		data = append([]byte(e.separator), data...)
+	}
 
-	_, err = e.w.Write(data)
-	return err
+	if _, err := e.w.Write(data); err != nil {
+		return err
+	}
+	e.written = true
+	return nil
 }
@@ -1,7 +1,13 @@
 package formenc_test
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -90,3 +96,526 @@ func TestEncoder(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoder_UseNumber(t *testing.T) {
+	t.Parallel()
+
+	input := "count=42&ratio=-1.5e3&name=john&code=007&tags[]=1"
+
+	var got map[string]interface{}
+	decoder := formenc.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"count": json.Number("42"),
+		"ratio": json.Number("-1.5e3"),
+		"name":  "john",
+		"code":  "007",
+		"tags":  []interface{}{json.Number("1")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_DisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithIgnoreUnknownKeys())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Person
+	if err := codec.NewDecoder(strings.NewReader("name=john&unknown=x")).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoder := codec.NewDecoder(strings.NewReader("name=john&unknown=x"))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&got); err == nil {
+		t.Error("expected error for unknown field, got nil")
+	}
+}
+
+func TestEncoder_SetEscapeHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		escapeHTML bool
+		want       string
+	}{
+		"escaped": {
+			escapeHTML: true,
+			want:       "name=%3Cb%3Ejohn+%26+co%3C%2Fb%3E",
+		},
+		"unescaped": {
+			escapeHTML: false,
+			want:       "name=<b>john+%26+co<%2Fb>",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var b bytes.Buffer
+			encoder := formenc.NewEncoder(&b)
+			encoder.SetEscapeHTML(tt.escapeHTML)
+			if err := encoder.Encode(map[string]string{"name": "<b>john & co</b>"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+
+			var got map[string]string
+			if err := formenc.DecodeString(b.String(), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(map[string]string{"name": "<b>john & co</b>"}, got); diff != "" {
+				t.Errorf("round trip (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_SetKeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	decoder := formenc.NewDecoder(strings.NewReader("x-name=john&x-age=30"))
+	decoder.SetKeyNormalizer(func(key string) string {
+		return strings.TrimPrefix(key, "x-")
+	})
+
+	var got Person
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Person{Name: "john", Age: 30}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_Records(t *testing.T) {
+	t.Parallel()
+
+	input := "name=alice&age=30\n\nname=bob\r\nname=%%%\nname=carol"
+	decoder := formenc.NewDecoder(strings.NewReader(input))
+
+	var got []Person
+	var errs int
+	for decoder.More() {
+		var p Person
+		if err := decoder.Decode(&p); err != nil {
+			errs++
+			continue
+		}
+		got = append(got, p)
+	}
+
+	want := []Person{{Name: "alice", Age: 30}, {Name: "bob"}, {Name: "carol"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 invalid record, got %d", errs)
+	}
+
+	var p Person
+	if err := decoder.Decode(&p); err != io.EOF {
+		t.Errorf("expected io.EOF after the last record, got: %v", err)
+	}
+}
+
+func TestDecoder_EmptyInput(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"", "\n\n"} {
+		var p Person
+		err := formenc.NewDecoder(strings.NewReader(input)).Decode(&p)
+		if err == nil || err == io.EOF {
+			t.Errorf("expected empty input error for %q, got: %v", input, err)
+		}
+	}
+}
+
+func TestDecoder_RecordLimit(t *testing.T) {
+	t.Parallel()
+
+	codec, err := formenc.NewCodec(formenc.WithLimits(formenc.Limits{MaxBytes: 16}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	input := "name=" + strings.Repeat("a", 8192) + "\nname=bob\n"
+	decoder := codec.NewDecoder(strings.NewReader(input))
+
+	var p Person
+	var limitErr *formenc.LimitExceededError
+	if err := decoder.Decode(&p); !errors.As(err, &limitErr) {
+		t.Fatalf("expected LimitExceededError, got: %v", err)
+	}
+	if err := decoder.Decode(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "bob" {
+		t.Errorf("expected the next record to decode, got: %+v", p)
+	}
+}
+
+func TestEncoder_Append(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		separator string
+		want      string
+	}{
+		"default separator": {
+			want: "name=alice&age=30&name=bob",
+		},
+		"custom separator": {
+			separator: "\n",
+			want:      "name=alice\nage=30&name=bob",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var b bytes.Buffer
+			encoder := formenc.NewEncoder(&b)
+			if tt.separator != "" {
+				encoder.SetSeparator(tt.separator)
+			}
+			for _, v := range []interface{}{
+				map[string]string{"name": "alice"},
+				map[string]string{},
+				Person{Name: "bob", Age: 30},
+			} {
+				if err := encoder.Encode(v); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewDecoderLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		limit   int64
+		want    []Person
+		wantErr error
+	}{
+		"within limit": {
+			input: "name=alice\nname=bob",
+			limit: 19,
+			want:  []Person{{Name: "alice"}, {Name: "bob"}},
+		},
+		"exceeds limit": {
+			input:   "name=alice\nname=bob",
+			limit:   12,
+			want:    []Person{{Name: "alice"}},
+			wantErr: formenc.ErrTooLarge,
+		},
+		"single record exceeds limit": {
+			input:   "name=" + strings.Repeat("a", 8192),
+			limit:   1024,
+			wantErr: formenc.ErrTooLarge,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			decoder := formenc.NewDecoderLimit(strings.NewReader(tt.input), tt.limit)
+
+			var got []Person
+			var err error
+			for decoder.More() {
+				var p Person
+				if err = decoder.Decode(&p); err != nil {
+					break
+				}
+				got = append(got, p)
+			}
+			if tt.wantErr == nil && err == nil {
+				err = decoder.Decode(&Person{})
+				if err == io.EOF {
+					err = nil
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_MaxBytesReader(t *testing.T) {
+	t.Parallel()
+
+	body := "name=" + strings.Repeat("a", 64)
+	tests := map[string]func(*formenc.Decoder) error{
+		"decode": func(d *formenc.Decoder) error {
+			return d.Decode(&Person{})
+		},
+		"decode func": func(d *formenc.Decoder) error {
+			return d.DecodeFunc(func(_, _ string) error { return nil })
+		},
+	}
+	for name, decode := range tests {
+		decode := decode
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := http.MaxBytesReader(nil, io.NopCloser(strings.NewReader(body)), 16)
+			err := decode(formenc.NewDecoder(r))
+
+			var limitErr *formenc.LimitExceededError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected LimitExceededError, got: %v", err)
+			}
+			if limitErr.Limit != "byte size" || limitErr.Max != 16 {
+				t.Errorf("expected byte size limit of 16, got: %v", limitErr)
+			}
+			var maxErr *http.MaxBytesError
+			if !errors.As(err, &maxErr) {
+				t.Errorf("expected error to wrap MaxBytesError, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_Buffered(t *testing.T) {
+	t.Parallel()
+
+	r := strings.NewReader("name=alice\nsignature=" + strings.Repeat("f", 8192))
+	decoder := formenc.NewDecoder(r)
+
+	var p Person
+	if err := decoder.Decode(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "alice" {
+		t.Errorf("expected name alice, got %q", p.Name)
+	}
+
+	rest, err := io.ReadAll(io.MultiReader(decoder.Buffered(), r))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "signature=" + strings.Repeat("f", 8192); string(rest) != want {
+		t.Errorf("expected the remaining input, got %d bytes", len(rest))
+	}
+}
+
+func TestEncoder_SetTerminator(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	encoder := formenc.NewEncoder(&b)
+	encoder.SetTerminator("\n")
+	for _, p := range []Person{{Name: "alice", Age: 30}, {Name: "bob"}} {
+		if err := encoder.Encode(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if diff := cmp.Diff("age=30&name=alice\nname=bob\n", b.String()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var got []Person
+	decoder := formenc.NewDecoder(&b)
+	for decoder.More() {
+		var p Person
+		if err := decoder.Decode(&p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, p)
+	}
+	if diff := cmp.Diff([]Person{{Name: "alice", Age: 30}, {Name: "bob"}}, got); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_DecodeFunc(t *testing.T) {
+	t.Parallel()
+
+	type kv struct{ Key, Value string }
+
+	input := "user[name]=alice&tags[]=a+b&empty=\n\nname=bob%21"
+	decoder := formenc.NewDecoder(strings.NewReader(input))
+
+	var records [][]kv
+	for {
+		var record []kv
+		err := decoder.DecodeFunc(func(key, value string) error {
+			record = append(record, kv{key, value})
+			return nil
+		})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	want := [][]kv{
+		{{"user[name]", "alice"}, {"tags[]", "a b"}, {"empty", ""}},
+		{{"name", "bob!"}},
+	}
+	if diff := cmp.Diff(want, records); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_DecodeFunc_Errors(t *testing.T) {
+	t.Parallel()
+
+	stop := errors.New("stop")
+	decoder := formenc.NewDecoder(strings.NewReader("a=1&b=2&c=3\nd=4"))
+	var keys []string
+	err := decoder.DecodeFunc(func(key, value string) error {
+		keys = append(keys, key)
+		if key == "b" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected callback error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, keys); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithLimits(formenc.Limits{MaxBytes: 64}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoder = codec.NewDecoder(strings.NewReader("a=" + strings.Repeat("x", 8192) + "&b=1\nname=bob"))
+	var limitErr *formenc.LimitExceededError
+	err = decoder.DecodeFunc(func(key, value string) error { return nil })
+	if !errors.As(err, &limitErr) {
+		t.Errorf("expected LimitExceededError, got: %v", err)
+	}
+
+	// The rest of the oversized record is skipped.
+	keys = nil
+	err = decoder.DecodeFunc(func(key, value string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"name"}, keys); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	decoder = formenc.NewDecoder(strings.NewReader("a=%%%"))
+	if err := decoder.DecodeFunc(func(key, value string) error { return nil }); err == nil {
+		t.Error("expected syntax error, got nil")
+	}
+}
+
+func TestDecoder_SetMaxPairSize(t *testing.T) {
+	t.Parallel()
+
+	// A record far larger than any pair is decoded pair by pair.
+	record := strings.Repeat("metric=cpu&value=0.5&", 50000) + "metric=end"
+	input := record + "\nlabel=" + strings.Repeat("x", 8192) + "&tail=1\nname=bob"
+
+	decoder := formenc.NewDecoder(strings.NewReader(input))
+	decoder.SetMaxPairSize(64)
+
+	var count int
+	err := decoder.DecodeFunc(func(key, value string) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 100001 {
+		t.Errorf("expected 100001 pairs, got %d", count)
+	}
+
+	err = decoder.DecodeFunc(func(key, value string) error { return nil })
+	var limitErr *formenc.LimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected LimitExceededError, got: %v", err)
+	}
+	want := formenc.LimitExceededError{Limit: "pair size", Max: 64, Key: "label"}
+	if diff := cmp.Diff(want, *limitErr); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// The rest of the oversized record is skipped.
+	var keys []string
+	err = decoder.DecodeFunc(func(key, value string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"name"}, keys); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_Flush(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	encoder := formenc.NewEncoder(rec)
+	if err := encoder.Encode(Person{Name: "alice"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rec.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	encoder = formenc.NewEncoder(w)
+	if err := encoder.Encode(Person{Name: "bob"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected output to be buffered, got %q", b.String())
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("name=bob", b.String()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if err := formenc.NewEncoder(io.Discard).Flush(); err != nil {
+		t.Errorf("unexpected error flushing a plain writer: %v", err)
+	}
+}
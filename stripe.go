@@ -0,0 +1,64 @@
+package formenc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// WithStripeCompat configures a [Codec] to follow the form conventions of the
+// Stripe API, so request bodies for Stripe-style APIs can be built and parsed
+// directly.
+//
+// Nested structs and maps are written with bracketed keys, as in
+// "card[exp_month]=12" and "metadata[order_id]=6735", and array elements with
+// explicit indices, as in "items[0][price]=price_1" or "expand[0]=customer".
+// Booleans are written and read only as "true" or "false", and values of type
+// [time.Time] as Unix timestamps in seconds.
+func WithStripeCompat() Option {
+	return func(c *Codec) error {
+		if err := c.setSyntax("WithStripeCompat", true, true); err != nil {
+			return err
+		}
+		c.parser = bracketParser{}
+		c.renderer = bracketRenderer{indices: true}
+		c.positionalKeys = true
+
+		opts := []Option{
+			WithDecodeFunc(time.Time{}, stripeParseTime),
+			WithEncodeFunc(time.Time{}, stripeFormatTime),
+			WithDecodeFunc(false, stripeParseBool),
+		}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func stripeParseTime(s string) (interface{}, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid Unix timestamp")
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+func stripeFormatTime(v interface{}) (string, error) {
+	return strconv.FormatInt(v.(time.Time).Unix(), 10), nil
+}
+
+func stripeParseBool(s string) (interface{}, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return nil, errors.New("boolean must be true or false")
+}
@@ -0,0 +1,122 @@
+package formenc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+type StripeCard struct {
+	Number   string `form:"number"`
+	ExpMonth int    `form:"exp_month"`
+	ExpYear  int    `form:"exp_year"`
+}
+
+type StripeLineItem struct {
+	Price    string `form:"price"`
+	Quantity int64  `form:"quantity"`
+}
+
+type StripeParams struct {
+	Customer string            `form:"customer,omitempty"`
+	Card     *StripeCard       `form:"card,omitempty"`
+	Items    []StripeLineItem  `form:"items,omitempty"`
+	Metadata map[string]string `form:"metadata,omitempty"`
+	Expand   []string          `form:"expand,omitempty"`
+	Livemode bool              `form:"livemode"`
+	TrialEnd time.Time         `form:"trial_end"`
+}
+
+func TestCodec_StripeCompat(t *testing.T) {
+	t.Parallel()
+
+	params := StripeParams{
+		Customer: "cus_123",
+		Card:     &StripeCard{Number: "4242424242424242", ExpMonth: 12, ExpYear: 2030},
+		Items: []StripeLineItem{
+			{Price: "price_1", Quantity: 2},
+			{Price: "price_2", Quantity: 1},
+		},
+		Metadata: map[string]string{"order_id": "6735"},
+		Expand:   []string{"customer", "invoice.subscription"},
+		Livemode: false,
+		TrialEnd: time.Date(2025, 2, 8, 10, 0, 0, 0, time.UTC),
+	}
+
+	codec, err := formenc.NewCodec(formenc.WithStripeCompat())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := codec.Marshal(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "card%5Bexp_month%5D=12&card%5Bexp_year%5D=2030&card%5Bnumber%5D=4242424242424242" +
+		"&customer=cus_123&expand%5B0%5D=customer&expand%5B1%5D=invoice.subscription" +
+		"&items%5B0%5D%5Bprice%5D=price_1&items%5B0%5D%5Bquantity%5D=2" +
+		"&items%5B1%5D%5Bprice%5D=price_2&items%5B1%5D%5Bquantity%5D=1" +
+		"&livemode=false&metadata%5Border_id%5D=6735&trial_end=1739008800"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	var decoded StripeParams
+	if err := codec.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(params, decoded); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestCodec_StripeCompat_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		want    StripeParams
+		wantErr bool
+	}{
+		"unindexed expansion": {
+			input: "expand[]=customer&expand[]=invoice",
+			want:  StripeParams{Expand: []string{"customer", "invoice"}},
+		},
+		"boolean": {
+			input: "livemode=true",
+			want:  StripeParams{Livemode: true},
+		},
+		"numeric boolean": {
+			input:   "livemode=1",
+			wantErr: true,
+		},
+		"invalid timestamp": {
+			input:   "trial_end=2025-02-08",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(formenc.WithStripeCompat())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got StripeParams
+			err = codec.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,51 +2,236 @@ package formenc
 
 import (
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
+// defaultTagName is the struct tag key read when no other is configured.
+const defaultTagName = "form"
+
 // cache of struct tags to avoid repeated parsing of the same struct type across
-// multiple calls to tags. The key is the [reflect.Type] of the struct, and the
-// value is a slice of *tag, one for each field on the struct.
+// multiple calls to tags. The key is a tagCacheKey identifying the struct type
+// and tag name, and the value is a slice of *tag, one for each field on the
+// struct.
 //
 // This cache is safe for concurrent use.
-var structTagCache sync.Map
+var structTagCache tagCache
+
+// tagCache maps struct types to the tags of their fields. Readers never lock;
+// the map is replaced as types are added, which happens rarely once a program
+// has seen each of its types. See [Codec.Prepare].
+type tagCache struct {
+	mu   sync.Mutex
+	tags atomic.Pointer[map[tagCacheKey][]*tag]
+}
+
+// load returns the tags cached for key, if any.
+func (tc *tagCache) load(key tagCacheKey) ([]*tag, bool) {
+	if m := tc.tags.Load(); m != nil {
+		tags, ok := (*m)[key]
+		return tags, ok
+	}
+	return nil, false
+}
+
+// store caches tags for key, returning the tags cached instead if another
+// caller stored them first.
+func (tc *tagCache) store(key tagCacheKey, tags []*tag) []*tag {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	old := tc.tags.Load()
+	if old != nil {
+		if cached, ok := (*old)[key]; ok {
+			return cached
+		}
+	}
+	m := make(map[tagCacheKey][]*tag, 1)
+	if old != nil {
+		m = make(map[tagCacheKey][]*tag, len(*old)+1)
+		for k, v := range *old {
+			m[k] = v
+		}
+	}
+	m[key] = tags
+	tc.tags.Store(&m)
+	return tags
+}
+
+// FieldNamer is implemented by structs that name their own fields in form
+// data, such as generated types that cannot be given struct tags.
+// FormFieldName receives the Go name of each field without a name in its tag
+// and returns the name used in form data, or "-" to ignore the field. It is
+// called on the zero value of the struct, and its results are cached.
+type FieldNamer interface {
+	FormFieldName(field string) string
+}
+
+var fieldNamerType = reflect.TypeOf((*FieldNamer)(nil)).Elem()
+
+type tagCacheKey struct {
+	Type reflect.Type
+	Name string
+}
 
 type tag struct {
-	Name   string
-	Omit   bool
-	Ignore bool
+	Name       string
+	Omit       bool
+	OmitZero   bool
+	Groups     []string
+	Deprecated []string
+	ReadOnly   bool
+	Secret     bool
+	WriteOnly  bool
+	Ignore     bool
+	Style      ParameterStyle
+	Explode    *bool
+	Required   bool
+	Default    string
+	Enum       []string
+	ErrMsg     string
+	KVPairs    string
+	KVSep      string
+
+	// Err describes a flag that could not be parsed, reported whenever the
+	// field is encoded or decoded.
+	Err *TagError
+
+	// Path holds the parts of a dotted name, such as "address.city", which
+	// is encoded and decoded as the nested key "address[city]".
+	Path []string
+
+	// segs holds the path segments of the name, computed once when the tag
+	// is cached so that encoding does not build them for every value.
+	segs []pathSegment
+}
+
+// segments returns the path segments of the field's name, of which there are
+// several for a dotted name. The segments are shared and must not be modified.
+func (t *tag) segments() []pathSegment {
+	if t.segs != nil {
+		return t.segs
+	}
+	if t.Path == nil {
+		return []pathSegment{{Key: t.Name}}
+	}
+	segs := make([]pathSegment, len(t.Path))
+	for i, name := range t.Path {
+		segs[i] = pathSegment{Key: name}
+	}
+	return segs
 }
 
-func tags(fv reflect.Value) []*tag {
-	tt := reflect.Indirect(fv).Type()
+// matchesPath reports whether the field has a dotted name spelled by key
+// followed by the leading segments of path.
+func (t *tag) matchesPath(key string, path []pathSegment) bool {
+	if t.Ignore || len(t.Path) == 0 || t.Path[0] != key || len(path) < len(t.Path)-1 {
+		return false
+	}
+	for i, name := range t.Path[1:] {
+		if path[i].Index || path[i].Key != name {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Codec) tags(fv reflect.Value) []*tag {
+	return c.typeTags(reflect.Indirect(fv).Type())
+}
+
+// typeTags returns the tags of the fields of the struct type tt, or none if tt
+// is not a struct.
+func (c *Codec) typeTags(tt reflect.Type) []*tag {
 	if tt.Kind() != reflect.Struct {
 		return []*tag{}
 	}
 
 	// Check the cache first.
-	if cached, ok := structTagCache.Load(tt); ok {
-		return cached.([]*tag)
+	key := tagCacheKey{Type: tt, Name: c.tagName}
+	if cached, ok := structTagCache.load(key); ok {
+		return cached
 	}
 
 	// Create a slice of tags to store the tags for each field on the struct. The
 	// length of the slice is equal to the number of fields on the struct.
 	tags := make([]*tag, tt.NumField())
 
+	// Structs may name fields without a name of their own.
+	var namer FieldNamer
+	if reflect.PointerTo(tt).Implements(fieldNamerType) {
+		namer = reflect.New(tt).Interface().(FieldNamer)
+	}
+
 	// Look for a Field on the struct that matches the key name.
 	for i := 0; i < tt.NumField(); i++ {
 		f := tt.Field(i)
-		tag := parseTag(f.Tag.Get("form"))
+		tag := parseTag(f.Tag.Get(c.tagName))
 		if !tag.Ignore && tag.Name == "" {
 			tag.Name = f.Name
+			if namer != nil {
+				if name := namer.FormFieldName(f.Name); name == "-" {
+					tag.Ignore, tag.Name = true, ""
+				} else if name != "" {
+					tag.Name = name
+				}
+			}
+		}
+		if tag.Err != nil {
+			tag.Err.Type, tag.Err.Field = tt, f.Name
+		}
+		if strings.Contains(tag.Name, ".") {
+			tag.Path = strings.Split(tag.Name, ".")
+		}
+		if tag.Name != "" {
+			tag.segs = tag.segments()
 		}
 		tags[i] = tag
 	}
 
 	// Store the tags in the cache.
-	structTagCache.Store(tt, tags)
-	return tags
+	return structTagCache.store(key, tags)
+}
+
+// Prepare parses the struct tags of types, and of the types they contain, as
+// [Unmarshal] and [Marshal] would on first seeing them. Services may call it
+// at startup so that the first requests they serve do not pay this cost.
+func Prepare(types ...reflect.Type) {
+	defaultCodec.Prepare(types...)
+}
+
+// Prepare parses the struct tags of types, and of the types they contain, as
+// c would on first seeing them.
+func (c *Codec) Prepare(types ...reflect.Type) {
+	seen := map[reflect.Type]bool{}
+	for _, t := range types {
+		c.prepare(t, seen)
+	}
+}
+
+// prepare caches the tags of t and the types it contains. Types already
+// prepared are recorded in seen, so that recursive types terminate.
+func (c *Codec) prepare(t reflect.Type, seen map[reflect.Type]bool) {
+	if t == nil || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		c.prepare(t.Elem(), seen)
+	case reflect.Map:
+		c.prepare(t.Key(), seen)
+		c.prepare(t.Elem(), seen)
+	case reflect.Struct:
+		tags := c.typeTags(t)
+		for i := 0; i < t.NumField(); i++ {
+			if !tags[i].Ignore {
+				c.prepare(t.Field(i).Type, seen)
+			}
+		}
+	}
 }
 
 func parseTag(str string) *tag {
@@ -76,12 +261,48 @@ func parseTag(str string) *tag {
 
 	// The remaining parts of the tag are flags that modify the behaviour of the
 	// field.
+	// Flags taking an argument are written as flag=argument.
 	for _, p := range parts[1:] {
-		switch strings.TrimSpace(p) {
+		flag, arg, _ := strings.Cut(strings.TrimSpace(p), "=")
+		switch flag {
 		case "omitempty":
 			t.Omit = true
+		case "omitzero":
+			t.OmitZero = true
 		case "ignore":
 			t.Ignore = true
+		case "style":
+			t.Style = ParameterStyle(arg)
+		case "explode":
+			if explode, err := strconv.ParseBool(arg); err == nil {
+				t.Explode = &explode
+			}
+		case "required":
+			t.Required = true
+		case "default":
+			t.Default = arg
+		case "kv":
+			var err error
+			if t.KVPairs, t.KVSep, err = kvSeparators(arg); err != nil && t.Err == nil {
+				t.Err = &TagError{Flag: strings.TrimSpace(p), Err: err}
+			}
+		case "secret":
+			t.Secret = true
+		case "readonly":
+			t.ReadOnly = true
+		case "writeonly":
+			t.WriteOnly = true
+		case "deprecated":
+			t.Deprecated = strings.Split(arg, "|")
+		case "groups":
+			t.Groups = strings.Split(arg, ";")
+		case "enum":
+			t.Enum = strings.Split(arg, "|")
+		case "errmsg":
+			// The message extends to the end of the tag, so that it may
+			// contain commas.
+			_, t.ErrMsg, _ = strings.Cut(str, "errmsg=")
+			return t
 		}
 	}
 
@@ -0,0 +1,97 @@
+package formenc
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnmarshalValues stores values, such as the Form of an [http.Request], in the
+// value pointed to by v, as [Unmarshal] does. See [Codec.UnmarshalValues].
+func UnmarshalValues(values url.Values, v interface{}) error {
+	return defaultCodec.UnmarshalValues(values, v)
+}
+
+// UnmarshalValues stores values in the value pointed to by v, as
+// [Codec.Unmarshal] does with the form data they were parsed from. Since
+// url.Values has no order of its own, keys are decoded in sorted order, and
+// the values of each key in the order they were added, so that a key repeated
+// without brackets, as in "tags=go&tags=web", fills a slice field in the
+// order it was submitted. Empty values leave v untouched.
+func (c *Codec) UnmarshalValues(values url.Values, v interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Spaces are escaped as "%20" rather than '+', which some options decode
+	// as a plus sign.
+	var b strings.Builder
+	for _, k := range keys {
+		key := strings.ReplaceAll(url.QueryEscape(k), "+", "%20")
+		for _, value := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(key)
+			b.WriteByte('=')
+			b.WriteString(strings.ReplaceAll(url.QueryEscape(value), "+", "%20"))
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return c.unmarshal([]byte(b.String()), v)
+}
+
+// ValuesToMap nests values into maps and slices by their bracketed keys, as
+// [Unmarshal] does when decoding into a map[string]interface{}, so that
+// "user[name]" becomes the "name" entry of the map held by "user". Values are
+// taken in order of their sorted keys, so where a key repeats without "[]"
+// its last value is kept.
+func ValuesToMap(values url.Values) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var entries []entry
+	for _, k := range keys {
+		path, err := parseKey(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values[k] {
+			entries = append(entries, entry{key: k, path: path, value: v})
+		}
+	}
+
+	m := make(map[string]interface{})
+	if err := defaultCodec.unmarshalEntries(entries, reflect.ValueOf(&m).Elem()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MapToValues flattens nested maps and slices into values with bracketed
+// keys, as [Marshal] does, so that the "name" entry of the map held by "user"
+// becomes "user[name]".
+func MapToValues(m map[string]interface{}) (url.Values, error) {
+	pairs, _, err := defaultCodec.encodePairs(m)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(url.Values, len(pairs))
+	for _, p := range pairs {
+		values.Add(p.key, p.value)
+	}
+	return values, nil
+}
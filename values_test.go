@@ -0,0 +1,165 @@
+package formenc_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestValuesToMap(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   url.Values
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		"flat": {
+			input: url.Values{"name": {"alice"}},
+			want:  map[string]interface{}{"name": "alice"},
+		},
+		"nested": {
+			input: url.Values{
+				"user[name]":          {"diana"},
+				"user[permissions][]": {"read", "write"},
+				"user[address][city]": {"Leeds"},
+			},
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":        "diana",
+					"permissions": []interface{}{"read", "write"},
+					"address":     map[string]interface{}{"city": "Leeds"},
+				},
+			},
+		},
+		"repeated key": {
+			input: url.Values{"colour": {"red", "blue"}},
+			want:  map[string]interface{}{"colour": "blue"},
+		},
+		"invalid key": {
+			input:   url.Values{"user[name": {"x"}},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formenc.ValuesToMap(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMapToValues(t *testing.T) {
+	t.Parallel()
+
+	m := map[string]interface{}{
+		"name": "alice",
+		"user": map[string]interface{}{
+			"age":         25,
+			"permissions": []string{"read", "write"},
+		},
+	}
+
+	got, err := formenc.MapToValues(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := url.Values{
+		"name":                {"alice"},
+		"user[age]":           {"25"},
+		"user[permissions][]": {"read", "write"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// The values nest back into the equivalent map of strings.
+	back, err := formenc.ValuesToMap(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantBack := map[string]interface{}{
+		"name": "alice",
+		"user": map[string]interface{}{
+			"age":         "25",
+			"permissions": []interface{}{"read", "write"},
+		},
+	}
+	if diff := cmp.Diff(wantBack, back); diff != "" {
+		t.Errorf("round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	t.Parallel()
+
+	type Search struct {
+		Query string   `form:"q"`
+		Tags  []string `form:"tags"`
+		Sizes []int    `form:"sizes"`
+	}
+
+	tests := map[string]struct {
+		opts  []formenc.Option
+		input url.Values
+		want  Search
+	}{
+		"repeated keys": {
+			input: url.Values{"tags": {"go", "web", "api"}, "sizes": {"3", "1", "2"}},
+			want:  Search{Tags: []string{"go", "web", "api"}, Sizes: []int{3, 1, 2}},
+		},
+		"bracketed and plain keys": {
+			input: url.Values{"tags": {"go"}, "tags[]": {"web"}},
+			want:  Search{Tags: []string{"go", "web"}},
+		},
+		"escaped characters": {
+			input: url.Values{"q": {"a+b c&d=e"}},
+			want:  Search{Query: "a+b c&d=e"},
+		},
+		"literal plus": {
+			opts:  []formenc.Option{formenc.WithLiteralPlusValues()},
+			input: url.Values{"q": {"+44 1234"}},
+			want:  Search{Query: "+44 1234"},
+		},
+		"empty": {
+			input: url.Values{"tags": {}},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := formenc.NewCodec(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got Search
+			if err := codec.UnmarshalValues(tt.input, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package formenc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Walk calls fn with the path and value of each pair [Marshal] would encode
+// v as, without building the encoded output. See [Codec.Walk].
+func Walk(v interface{}, fn func(path KeyPath, value string) error) error {
+	return defaultCodec.Walk(v, fn)
+}
+
+// Walk calls fn with the path and value of each pair c would encode v as,
+// without building the encoded output, so that values can be signed, audited
+// or extracted as they are encoded. Pairs are visited in the order they are
+// produced, which is the order of struct fields and sorted map keys, rather
+// than sorted by key as [Codec.Marshal] writes them. Pairs that are only key
+// syntax, such as the key members of map entries written by
+// [WithAWSQueryCompat], are not visited. The elements of slices and arrays
+// are addressed by their position, even where the key written omits it.
+//
+// The path passed to fn is only valid for the duration of the call. If fn
+// returns an error, Walk stops and returns it.
+//
+// Values implementing [Appender] are encoded and the pairs written parsed
+// again, so that they are visited as they are for any other value.
+func (c *Codec) Walk(v interface{}, fn func(path KeyPath, value string) error) error {
+	if a, ok := v.(Appender); ok {
+		return c.walkAppender(a, fn)
+	}
+
+	e := &encodeState{fn: fn}
+	_, err := c.encodeInto(e, v)
+	if e.err != nil {
+		return e.err
+	}
+	return err
+}
+
+// walkAppender calls fn with the path and value of each pair written by a.
+func (c *Codec) walkAppender(a Appender, fn func(path KeyPath, value string) error) error {
+	rv := reflect.ValueOf(a)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	data, err := a.AppendForm(nil)
+	if err != nil {
+		return fmt.Errorf("form: %w", err)
+	}
+	entries, err := c.parse(string(data), rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := fn(keyPath(e.path), e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
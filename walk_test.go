@@ -0,0 +1,96 @@
+package formenc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tomasbasham/formenc"
+)
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	type Order struct {
+		ID     int               `form:"id"`
+		Tags   []string          `form:"tags"`
+		Labels map[string]string `form:"labels"`
+		Ship   Address           `form:"ship,omitempty"`
+	}
+
+	tests := map[string]struct {
+		value interface{}
+		want  []string
+	}{
+		"struct": {
+			value: Order{
+				ID:     7,
+				Tags:   []string{"a", "b"},
+				Labels: map[string]string{"z": "1", "env": "prod"},
+				Ship:   Address{City: "Leeds"},
+			},
+			want: []string{
+				"id=7",
+				"tags[0]=a",
+				"tags[1]=b",
+				"labels[env]=prod",
+				"labels[z]=1",
+				"ship[street]=",
+				"ship[city]=Leeds",
+				"ship[state]=",
+				"ship[zip]=",
+			},
+		},
+		"top-level slice": {
+			value: []Address{{City: "Paris"}},
+			want:  []string{"0[street]=", "0[city]=Paris", "0[state]=", "0[zip]="},
+		},
+		"appender": {
+			value: &Coordinate{X: 1, Y: 2},
+			want:  []string{"x=1", "y=2"},
+		},
+		"nil": {
+			value: (*Order)(nil),
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			err := formenc.Walk(tt.value, func(path formenc.KeyPath, value string) error {
+				got = append(got, path.String()+"="+value)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWalk_Errors(t *testing.T) {
+	t.Parallel()
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := formenc.Walk(map[string]string{"a": "1", "b": "2"}, func(formenc.KeyPath, string) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected callback error, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected walk to stop after the first error, got %d calls", calls)
+	}
+
+	if err := formenc.Walk(42, func(formenc.KeyPath, string) error { return nil }); err == nil {
+		t.Error("expected error for scalar value, got nil")
+	}
+}